@@ -0,0 +1,110 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultAuthWindow is the default accepted time difference of signed requests
+const DefaultAuthWindow = 300
+
+// AuthConfig is the request authentication configuration, nil means authentication disabled.
+//
+// Clients send the api key in header X-Api-Key (gRPC metadata x-api-key).
+// Apps with secret must sign requests additionally:
+//
+//	X-Timestamp: unix timestamp in seconds
+//	X-Signature: hex(hmac_sha256(secret, timestamp + "\n" + body))
+//
+// body is the raw http request body, or the serialized request message of gRPC exactly as it is sent.
+type AuthConfig struct {
+	Apps []*AppAuth `json:"apps"`
+	// accepted time difference of signed requests in seconds, default 300
+	Window int `json:"window"`
+
+	keys map[string]*AppAuth
+}
+
+// AppAuth is the credential of an app
+type AppAuth struct {
+	Key string `json:"key"`
+	// HMAC secret, empty means the api key is enough
+	Secret string `json:"secret"`
+	// app types that the key can submit
+	AppTypes []string `json:"app_types"`
+}
+
+func (c *AuthConfig) Validate() error {
+	if len(c.Apps) == 0 {
+		return errors.New("apps is required")
+	}
+	if c.Window < 0 {
+		return errors.New("window must not be negative")
+	}
+
+	keys := make(map[string]*AppAuth, len(c.Apps))
+	for _, app := range c.Apps {
+		if app == nil || app.Key == "" {
+			return errors.New("apps contains empty key")
+		}
+		if _, ok := keys[app.Key]; ok {
+			return errors.Errorf("duplicated key %s", app.Key)
+		}
+		if len(app.AppTypes) == 0 {
+			return errors.Errorf("app_types of key %s is required", app.Key)
+		}
+		keys[app.Key] = app
+	}
+	c.keys = keys
+
+	return nil
+}
+
+// GetApp returns credential of the api key, nil if not found
+func (c *AuthConfig) GetApp(key string) *AppAuth {
+	if c.keys == nil {
+		for _, app := range c.Apps {
+			if app != nil && app.Key == key {
+				return app
+			}
+		}
+		return nil
+	}
+
+	return c.keys[key]
+}
+
+// Signed reports whether any app must sign requests, it's safe to call on nil config
+func (c *AuthConfig) Signed() bool {
+	if c == nil {
+		return false
+	}
+	for _, app := range c.Apps {
+		if app != nil && app.Secret != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetWindow returns the accepted time difference of signed requests
+func (c *AuthConfig) GetWindow() time.Duration {
+	if c.Window == 0 {
+		return DefaultAuthWindow * time.Second
+	}
+
+	return time.Duration(c.Window) * time.Second
+}
+
+// Allow reports whether the app can submit events of appType
+func (a *AppAuth) Allow(appType string) bool {
+	for _, t := range a.AppTypes {
+		if t == appType {
+			return true
+		}
+	}
+
+	return false
+}
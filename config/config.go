@@ -3,24 +3,173 @@ package config
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/kafkaclient"
 )
 
 type Config struct {
-	Version     string
-	VersionDate string
-	HTTPAddr    string         `json:"http_addr"`
-	DebugAddr   string         `json:"debug_addr"`
-	GRPCAddr    string         `json:"grpc_addr"`
-	TopicRouter *TopicRouter   `json:"topic_router,omitempty"`
-	Storage     *StorageConfig `json:"storage,omitempty"`
+	Version     string           `json:"-"`
+	VersionDate string           `json:"-"`
+	HTTPAddr    string           `json:"http_addr"`
+	DebugAddr   string           `json:"debug_addr"`
+	GRPCAddr    string           `json:"grpc_addr"`
+	HTTP        *HTTPConfig      `json:"http,omitempty"`
+	Auth        *AuthConfig      `json:"auth,omitempty"`
+	RateLimit   *RateLimitConfig `json:"rate_limit,omitempty"`
+	Enrich      *EnrichConfig    `json:"enrich,omitempty"`
+	Privacy     *PrivacyConfig   `json:"privacy,omitempty"`
+	Dedup       *DedupConfig     `json:"dedup,omitempty"`
+	EventTime   *EventTimeConfig `json:"event_time,omitempty"`
+	TopicRouter *TopicRouter     `json:"topic_router,omitempty"`
+	Storage     *StorageConfig   `json:"storage,omitempty"`
 }
 
 func (c *Config) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
 	fmt.Fprintf(w, "Version:%s\nDate:%s", c.Version, c.VersionDate)
 }
 
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	if c.HTTPAddr == "" || c.GRPCAddr == "" || c.DebugAddr == "" {
+		return errors.New("http_addr, grpc_addr and debug_addr are required")
+	}
+	if c.HTTP != nil {
+		if err := c.HTTP.Validate(); err != nil {
+			return errors.Wrap(err, "http")
+		}
+	}
+	if c.Auth != nil {
+		if err := c.Auth.Validate(); err != nil {
+			return errors.Wrap(err, "auth")
+		}
+	}
+	if c.RateLimit != nil {
+		if err := c.RateLimit.Validate(); err != nil {
+			return errors.Wrap(err, "rate_limit")
+		}
+	}
+	if c.Enrich != nil {
+		if err := c.Enrich.Validate(); err != nil {
+			return errors.Wrap(err, "enrich")
+		}
+	}
+	if c.Privacy != nil {
+		if err := c.Privacy.Validate(); err != nil {
+			return errors.Wrap(err, "privacy")
+		}
+	}
+	if c.Dedup != nil {
+		if err := c.Dedup.Validate(); err != nil {
+			return errors.Wrap(err, "dedup")
+		}
+	}
+	if c.EventTime != nil {
+		if err := c.EventTime.Validate(); err != nil {
+			return errors.Wrap(err, "event_time")
+		}
+	}
+	if c.TopicRouter == nil {
+		return errors.New("topic_router is missing")
+	}
+	if err := c.TopicRouter.Validate(); err != nil {
+		return errors.Wrap(err, "topic_router")
+	}
+	if c.Storage == nil {
+		return errors.New("storage is missing")
+	}
+	if err := c.Storage.Validate(); err != nil {
+		return errors.Wrap(err, "storage")
+	}
+
+	return nil
+}
+
+// DefaultMaxBodySize is the default limit of decompressed http request body
+const DefaultMaxBodySize = 10 << 20
+
+// HTTPConfig is the configuration of http transport, changes require restart
+type HTTPConfig struct {
+	// max size of request body after decompression in bytes, default 10MB
+	MaxBodySize int64 `json:"max_body_size"`
+	// nil means CORS disabled
+	CORS *CORSConfig `json:"cors,omitempty"`
+}
+
+func (c *HTTPConfig) Validate() error {
+	if c.MaxBodySize < 0 {
+		return errors.New("max_body_size must not be negative")
+	}
+	if c.CORS != nil {
+		if err := c.CORS.Validate(); err != nil {
+			return errors.Wrap(err, "cors")
+		}
+	}
+
+	return nil
+}
+
+// GetMaxBodySize returns the max request body size, it's safe to call on nil config
+func (c *HTTPConfig) GetMaxBodySize() int64 {
+	if c == nil || c.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+
+	return c.MaxBodySize
+}
+
+// GetCORS returns CORS configuration, it's safe to call on nil config
+func (c *HTTPConfig) GetCORS() *CORSConfig {
+	if c == nil {
+		return nil
+	}
+
+	return c.CORS
+}
+
+// CORSConfig is the cross-origin resource sharing configuration of http transport
+type CORSConfig struct {
+	// e.g. https://www.example.com, https://*.example.com (subdomains only), * (any origin, without credentials)
+	AllowedOrigins []string `json:"allowed_origins"`
+	// request headers allowed besides Content-Type and Content-Encoding
+	AllowedHeaders   []string `json:"allowed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	// seconds that preflight result can be cached, 0 means not set
+	MaxAge int `json:"max_age"`
+}
+
+func (c *CORSConfig) Validate() error {
+	if len(c.AllowedOrigins) == 0 {
+		return errors.New("allowed_origins is required")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			// any site could make credentialed requests
+			if c.AllowCredentials {
+				return errors.New("allowed origin * is not allowed with allow_credentials")
+			}
+			continue
+		}
+		// wildcard is only allowed as the leftmost label of host
+		i := strings.Index(origin, "://")
+		if i <= 0 {
+			return errors.Errorf("invalid origin %s", origin)
+		}
+		if host := strings.TrimPrefix(origin[i+3:], "*."); host == "" || strings.Contains(host, "*") {
+			return errors.Errorf("invalid origin %s", origin)
+		}
+	}
+	if c.MaxAge < 0 {
+		return errors.New("max_age must not be negative")
+	}
+
+	return nil
+}
+
 type TopicRouter struct {
 	DefaultTopic string `json:"default_topic"`
 	// router defination and priority:
@@ -38,15 +187,218 @@ type TopicRouter struct {
 	//   case app_type = myapp, env != test, event = pv then myapp_event_log_pv
 	//   case app_type = myapp, env != test, event = mv then myapp_event_log_other
 	RouteMap map[string]string `json:"route_map"`
+	// rules are checked in order before route_map, the first matched rule wins
+	Rules []*RouteRule `json:"rules,omitempty"`
+	// message key by topic, see Destination.Key
+	KeyMap map[string]string `json:"key_map,omitempty"`
+	// message key of the topics that are not configured, default event_id
+	DefaultKey string `json:"default_key,omitempty"`
+}
+
+func (r *TopicRouter) Validate() error {
+	for topic, key := range r.KeyMap {
+		if err := ValidateKey(key); err != nil {
+			return errors.Wrapf(err, "key_map %s", topic)
+		}
+	}
+	if r.DefaultKey != "" {
+		if err := ValidateKey(r.DefaultKey); err != nil {
+			return errors.Wrap(err, "default_key")
+		}
+	}
+	for i, rule := range r.Rules {
+		if rule == nil {
+			return errors.Errorf("rules[%d] is empty", i)
+		}
+		if err := rule.Validate(); err != nil {
+			return errors.Wrapf(err, "rules[%d]", i)
+		}
+	}
+	for key := range r.RouteMap {
+		if key == "" {
+			return errors.New("route_map contains empty key")
+		}
+		// route keys are matched in lower case
+		if key != strings.ToLower(key) {
+			return errors.Errorf("route_map key %s must be lower case", key)
+		}
+	}
+
+	return nil
 }
 
 type StorageConfig struct {
 	DataType string       `json:"data_type"`       //json|protobuf, default protobuf
 	Types    string       `json:"types,omitempty"` //kafka|stdout, multiple values are comma separated, default stdout
 	Kafka    *KafkaConfig `json:"kafka,omitempty"`
+	Spool    *SpoolConfig `json:"spool,omitempty"`
+	Delivery *Delivery    `json:"delivery,omitempty"`
+	// invalid and unroutable events are written to dead letter topic
+	DeadLetter *DeadLetterConfig `json:"dead_letter,omitempty"`
 }
 
+// DeadLetterConfig is the destination of rejected events, records are deadletter.Letter in json
+type DeadLetterConfig struct {
+	// empty means disabled
+	Topic string `json:"topic"`
+}
+
+// GetTopic returns dead letter topic, it's safe to call on nil config
+func (c *DeadLetterConfig) GetTopic() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Topic
+}
+
+func (c *StorageConfig) Validate() error {
+	switch c.DataType {
+	case "", "json", "protobuf":
+	default:
+		return errors.Errorf("unknow data_type %s", c.DataType)
+	}
+
+	for _, storageType := range strings.Split(c.Types, ",") {
+		switch strings.ToLower(strings.TrimSpace(storageType)) {
+		case "", "stdout":
+		case "kafka":
+			if c.Kafka == nil {
+				return errors.New("kafka configuration is missing")
+			}
+			if err := c.Kafka.Validate(); err != nil {
+				return errors.Wrap(err, "kafka")
+			}
+		default:
+			return errors.Errorf("unknow storage %s", storageType)
+		}
+	}
+
+	if c.Spool != nil {
+		if err := c.Spool.Validate(); err != nil {
+			return errors.Wrap(err, "spool")
+		}
+	}
+
+	if c.Delivery != nil {
+		if err := c.Delivery.Validate(); err != nil {
+			return errors.Wrap(err, "delivery")
+		}
+	}
+
+	return nil
+}
+
+// Kafka partitioners
+const (
+	// sarama default, FNV-1a hash of key
+	PartitionerHash = "hash"
+	// same as the default partitioner of java producer
+	PartitionerMurmur2 = "murmur2"
+	// key is the partition number
+	PartitionerManual     = "manual"
+	PartitionerRandom     = "random"
+	PartitionerRoundRobin = "roundrobin"
+)
+
 type KafkaConfig struct {
-	Addrs          string         `json:"addrs"`
+	Addrs string `json:"addrs"`
+	// client id, tls, sasl and producer options, they have priority over producer_config
+	kafkaclient.Config
+	// hash | murmur2 | manual | random | roundrobin, default hash.
+	// messages without key are distributed round-robin
+	Partitioner    string         `json:"partitioner"`
 	ProducerConfig *sarama.Config `json:"producer_config"`
 }
+
+func (c *KafkaConfig) Validate() error {
+	if strings.TrimSpace(strings.Replace(c.Addrs, ",", "", -1)) == "" {
+		return errors.New("addrs is missing")
+	}
+	switch c.Partitioner {
+	case "", PartitionerHash, PartitionerMurmur2, PartitionerManual, PartitionerRandom, PartitionerRoundRobin:
+	default:
+		return errors.Errorf("unknown partitioner %s", c.Partitioner)
+	}
+	// options are checked along with producer_config, e.g. zstd compression requires version 2.1.0
+	producerConfig := sarama.NewConfig()
+	if c.ProducerConfig != nil {
+		*producerConfig = *c.ProducerConfig
+	}
+	if err := c.Config.Apply(producerConfig); err != nil {
+		return err
+	}
+	if err := producerConfig.Validate(); err != nil {
+		return errors.Wrap(err, "producer_config")
+	}
+
+	return nil
+}
+
+// SpoolConfig defines local disk buffer of kafka storage.
+// Messages that can't be delivered are appended to segment files in Dir,
+// and replayed in order when kafka recovers.
+type SpoolConfig struct {
+	Dir           string `json:"dir"`            // empty means spool is disabled
+	SegmentSize   int64  `json:"segment_size"`   // max bytes of segment file, default 64MB
+	MaxSize       int64  `json:"max_size"`       // max bytes of all segment files, oldest segments are dropped. default 1GB
+	MaxAge        int    `json:"max_age"`        // seconds, older segments are dropped. default 7 days
+	RetryInterval int    `json:"retry_interval"` // seconds to wait before replay since last failure. default 10
+}
+
+func (c *SpoolConfig) Validate() error {
+	if c.SegmentSize < 0 || c.MaxSize < 0 || c.MaxAge < 0 || c.RetryInterval < 0 {
+		return errors.New("negative value")
+	}
+	if c.SegmentSize > 0 && c.MaxSize > 0 && c.SegmentSize > c.MaxSize {
+		return errors.New("segment_size is greater than max_size")
+	}
+
+	return nil
+}
+
+// Delivery defines when requests wait for the storage ack.
+// In sync mode, requests fail with retryable error if the storage fails or times out.
+type Delivery struct {
+	Mode    string   `json:"mode"`    // async|sync, default async
+	Timeout int      `json:"timeout"` // milliseconds to wait for the storage ack, default 3000
+	Topics  []string `json:"topics"`  // topics that use sync mode even if mode is async
+}
+
+func (d *Delivery) Validate() error {
+	switch d.Mode {
+	case "", "async", "sync":
+	default:
+		return errors.Errorf("unknow mode %s", d.Mode)
+	}
+	if d.Timeout < 0 {
+		return errors.New("negative timeout")
+	}
+
+	return nil
+}
+
+// IsSync reports whether messages of topic wait for the storage ack
+func (d *Delivery) IsSync(topic string) bool {
+	if d == nil {
+		return false
+	}
+	if d.Mode == "sync" {
+		return true
+	}
+	for _, t := range d.Topics {
+		if t == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetTimeout returns timeout of sync mode
+func (d *Delivery) GetTimeout() time.Duration {
+	if d == nil || d.Timeout <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(d.Timeout) * time.Millisecond
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default dedup parameters
+const (
+	DefaultDedupWindow  = 600
+	DefaultDedupMaxKeys = 1000000
+)
+
+// DedupConfig drops events whose event_id is submitted again in the window, nil means disabled.
+// Keys are kept in memory of each instance, so duplicates that reach different instances are not dropped.
+type DedupConfig struct {
+	// seconds, default 600
+	Window int `json:"window"`
+	// max number of event ids kept in memory, the window is shortened when it's exceeded. default 1000000
+	MaxKeys int `json:"max_keys"`
+}
+
+func (c *DedupConfig) Validate() error {
+	if c.Window < 0 {
+		return errors.New("window must not be negative")
+	}
+	if c.MaxKeys < 0 {
+		return errors.New("max_keys must not be negative")
+	}
+
+	return nil
+}
+
+// GetWindow returns dedup window
+func (c *DedupConfig) GetWindow() time.Duration {
+	if c.Window == 0 {
+		return DefaultDedupWindow * time.Second
+	}
+
+	return time.Duration(c.Window) * time.Second
+}
+
+// GetMaxKeys returns max number of keys kept in memory
+func (c *DedupConfig) GetMaxKeys() int {
+	if c.MaxKeys == 0 {
+		return DefaultDedupMaxKeys
+	}
+
+	return c.MaxKeys
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// EnrichConfig is the ordered enrichment stages that fill event logs.
+// nil means the default stages: udid, common, geoip, useragent, resolution
+type EnrichConfig struct {
+	Stages []*EnrichStage `json:"stages"`
+}
+
+// EnrichStage is a registered enricher, see eventlog.RegisterEnricher
+type EnrichStage struct {
+	Name string `json:"name"`
+	// arguments passed to enricher factory
+	Args string `json:"args"`
+	// app types that the stage applies to, empty means all
+	AppTypes []string `json:"app_types"`
+	// app types that the stage doesn't apply to
+	ExcludeAppTypes []string `json:"exclude_app_types"`
+}
+
+func (c *EnrichConfig) Validate() error {
+	for i, stage := range c.Stages {
+		if stage == nil || stage.Name == "" {
+			return errors.Errorf("stages[%d] name is required", i)
+		}
+		if len(stage.AppTypes) > 0 && len(stage.ExcludeAppTypes) > 0 {
+			return errors.Errorf("stage %s: app_types and exclude_app_types are exclusive", stage.Name)
+		}
+	}
+
+	return nil
+}
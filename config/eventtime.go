@@ -0,0 +1,66 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Actions of implausible event time
+const (
+	EventTimeFlag   = "flag"
+	EventTimeReject = "reject"
+)
+
+// Default event time limits
+const (
+	DefaultEventTimeMaxFuture = 3600
+	DefaultEventTimeMaxAge    = 30
+)
+
+// EventTimeConfig checks the corrected event_time against logged_time, nil means no checks.
+// Implausible event time is flagged in event_time_flag (future / expired), or rejected.
+type EventTimeConfig struct {
+	// seconds that event time can be later than logged time, default 3600
+	MaxFuture int `json:"max_future"`
+	// days that event time can be earlier than logged time, default 30
+	MaxAge int `json:"max_age"`
+	// flag | reject, default flag
+	Action string `json:"action"`
+}
+
+func (c *EventTimeConfig) Validate() error {
+	if c.MaxFuture < 0 || c.MaxAge < 0 {
+		return errors.New("max_future and max_age must not be negative")
+	}
+	switch c.Action {
+	case "", EventTimeFlag, EventTimeReject:
+	default:
+		return errors.Errorf("unknown action %s", c.Action)
+	}
+
+	return nil
+}
+
+// GetMaxFuture returns duration that event time can be later than logged time
+func (c *EventTimeConfig) GetMaxFuture() time.Duration {
+	if c.MaxFuture == 0 {
+		return DefaultEventTimeMaxFuture * time.Second
+	}
+
+	return time.Duration(c.MaxFuture) * time.Second
+}
+
+// GetMaxAge returns duration that event time can be earlier than logged time
+func (c *EventTimeConfig) GetMaxAge() time.Duration {
+	if c.MaxAge == 0 {
+		return DefaultEventTimeMaxAge * 24 * time.Hour
+	}
+
+	return time.Duration(c.MaxAge) * 24 * time.Hour
+}
+
+// IsReject reports whether implausible event time is rejected
+func (c *EventTimeConfig) IsReject() bool {
+	return c.Action == EventTimeReject
+}
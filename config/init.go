@@ -4,67 +4,295 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/techxmind/logserver/kafkaclient"
 )
 
 var (
 	DefaultConfig *Config
+
+	// configuration file, json or yaml format
+	_configFile string
+
+	// command line flags that are explicitly set, see Init
+	_flagOverrides map[string]string
+
+	// flag name => config field setter, used to apply flags on config loaded from file
+	_flagFields = make(map[string]func(*Config, string))
 )
 
 func init() {
-	DefaultConfig = &Config{
+	DefaultConfig = newConfig()
+
+	flag.StringVar(&_configFile, "config", "", "Configuration file, json or yaml format. Flags and env vars have priority over it")
+
+	stringFlag("debug.addr", "Debug and metrics listen address", func(c *Config) *string {
+		return &c.DebugAddr
+	})
+	stringFlag("http.addr", "HTTP listen address", func(c *Config) *string {
+		return &c.HTTPAddr
+	})
+	stringFlag("grpc.addr", "gRPC (HTTP) listen address", func(c *Config) *string {
+		return &c.GRPCAddr
+	})
+	stringFlag("storage.data_type", "protobuf|json. Data type that store in storage", func(c *Config) *string {
+		return &c.Storage.DataType
+	})
+	stringFlag("storage.kafka.addrs", "Kafka broker addresses, multiple values are comma separated", func(c *Config) *string {
+		return &c.Storage.Kafka.Addrs
+	})
+	stringFlag("storage.kafka.partitioner", "hash|murmur2|manual|random|roundrobin. murmur2 is compatible with java producer", func(c *Config) *string {
+		return &c.Storage.Kafka.Partitioner
+	})
+	stringFlag("storage.kafka.version", "Kafka version, e.g. 2.1.0", func(c *Config) *string {
+		return &c.Storage.Kafka.Version
+	})
+	stringFlag("storage.kafka.client_id", "Kafka client id", func(c *Config) *string {
+		return &c.Storage.Kafka.ClientID
+	})
+	stringFlag("storage.kafka.compression", "none|gzip|snappy|lz4|zstd. Kafka message compression codec", func(c *Config) *string {
+		return &c.Storage.Kafka.Compression
+	})
+	stringFlag("storage.kafka.required_acks", "none|leader|all. Kafka acks required by producer", func(c *Config) *string {
+		return &c.Storage.Kafka.RequiredAcks
+	})
+	boolFlag("storage.kafka.idempotent", "Enable idempotent kafka producer, it requires required_acks all", func(c *Config) *bool {
+		return &c.Storage.Kafka.Idempotent
+	})
+	intFlag("storage.kafka.linger", "Milliseconds that kafka messages are buffered before they are sent in batch", func(c *Config) *int {
+		return &c.Storage.Kafka.Linger
+	})
+	intFlag("storage.kafka.flush_bytes", "Buffered bytes that trigger a kafka flush", func(c *Config) *int {
+		return &c.Storage.Kafka.FlushBytes
+	})
+	intFlag("storage.kafka.max_message_bytes", "Max bytes of a kafka message", func(c *Config) *int {
+		return &c.Storage.Kafka.MaxMessageBytes
+	})
+	boolFlag("storage.kafka.tls.enable", "Connect to kafka brokers with TLS", func(c *Config) *bool {
+		return &c.Storage.Kafka.TLS.Enable
+	})
+	stringFlag("storage.kafka.tls.ca_file", "PEM encoded CA certificates of kafka brokers, empty means system CAs", func(c *Config) *string {
+		return &c.Storage.Kafka.TLS.CAFile
+	})
+	stringFlag("storage.kafka.tls.cert_file", "PEM encoded client certificate of kafka TLS", func(c *Config) *string {
+		return &c.Storage.Kafka.TLS.CertFile
+	})
+	stringFlag("storage.kafka.tls.key_file", "PEM encoded client key of kafka TLS", func(c *Config) *string {
+		return &c.Storage.Kafka.TLS.KeyFile
+	})
+	boolFlag("storage.kafka.tls.insecure_skip_verify", "Skip verifying kafka broker certificates", func(c *Config) *bool {
+		return &c.Storage.Kafka.TLS.InsecureSkipVerify
+	})
+	stringFlag("storage.kafka.sasl.mechanism", "PLAIN|SCRAM-SHA-256|SCRAM-SHA-512. Kafka SASL mechanism, empty means disabled", func(c *Config) *string {
+		return &c.Storage.Kafka.SASL.Mechanism
+	})
+	stringFlag("storage.kafka.sasl.username", "Kafka SASL username", func(c *Config) *string {
+		return &c.Storage.Kafka.SASL.Username
+	})
+	stringFlag("storage.kafka.sasl.password", "Kafka SASL password, prefer env STORAGE_KAFKA_SASL_PASSWORD", func(c *Config) *string {
+		return &c.Storage.Kafka.SASL.Password
+	})
+	stringFlag("storage.types", "stdout|kafka. Multiple values are comma separated", func(c *Config) *string {
+		return &c.Storage.Types
+	})
+	stringFlag("storage.delivery.mode", "async|sync. In sync mode requests wait for the storage ack and fail with retryable error", func(c *Config) *string {
+		return &c.Storage.Delivery.Mode
+	})
+	stringFlag("storage.spool.dir", "Local directory that buffers kafka messages when brokers are unreachable, empty means disabled", func(c *Config) *string {
+		return &c.Storage.Spool.Dir
+	})
+	stringFlag("storage.dead_letter.topic", "Topic that invalid and unroutable events are written to, empty means disabled", func(c *Config) *string {
+		return &c.Storage.DeadLetter.Topic
+	})
+
+	// Use environment variables, if set. Flags have priority over Env vars.
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		_configFile = file
+	}
+	applyEnv(DefaultConfig)
+}
+
+func newConfig() *Config {
+	return &Config{
+		DebugAddr: ":5060",
+		HTTPAddr:  ":5050",
+		GRPCAddr:  ":5040",
 		TopicRouter: &TopicRouter{
 			DefaultTopic: "event-log",
 		},
 		Storage: &StorageConfig{
 			DataType: "protobuf",
 			Types:    "stdout",
-			Kafka:    &KafkaConfig{},
+			Kafka: &KafkaConfig{
+				Config: kafkaclient.Config{
+					TLS:  &kafkaclient.TLSConfig{},
+					SASL: &kafkaclient.SASLConfig{},
+				},
+			},
+			Spool:      &SpoolConfig{},
+			Delivery:   &Delivery{},
+			DeadLetter: &DeadLetterConfig{},
 		},
 	}
+}
+
+// stringFlag binds flag to the field of DefaultConfig, field's current value is used as default value
+func stringFlag(name, usage string, field func(*Config) *string) {
+	p := field(DefaultConfig)
+	flag.StringVar(p, name, *p, usage)
+	_flagFields[name] = func(c *Config, value string) {
+		allocParents(name, c)
+		*field(c) = value
+	}
+}
 
-	flag.StringVar(&DefaultConfig.DebugAddr, "debug.addr", ":5060", "Debug and metrics listen address")
-	flag.StringVar(&DefaultConfig.HTTPAddr, "http.addr", ":5050", "HTTP listen address")
-	flag.StringVar(&DefaultConfig.GRPCAddr, "grpc.addr", ":5040", "gRPC (HTTP) listen address")
-
-	flag.StringVar(
-		&DefaultConfig.Storage.DataType,
-		"storage.data_type",
-		"",
-		"protobuf|json. Data type that store in storage",
-	)
-	flag.StringVar(
-		&DefaultConfig.Storage.Kafka.Addrs,
-		"storage.kafka.addrs",
-		"",
-		"Kafka broker addresses, multiple values are comma separated",
-	)
-	flag.StringVar(
-		&DefaultConfig.Storage.Types,
-		"storage.types",
-		"",
-		"stdout|kafka. Multiple values are comma separated",
-	)
+// intFlag is stringFlag of int field
+func intFlag(name, usage string, field func(*Config) *int) {
+	p := field(DefaultConfig)
+	flag.IntVar(p, name, *p, usage)
+	_flagFields[name] = func(c *Config, value string) {
+		allocParents(name, c)
+		// value is validated by flag.Parse
+		*field(c), _ = strconv.Atoi(value)
+	}
+}
 
-	// Use environment variables, if set. Flags have priority over Env vars.
+// boolFlag is stringFlag of bool field
+func boolFlag(name, usage string, field func(*Config) *bool) {
+	p := field(DefaultConfig)
+	flag.BoolVar(p, name, *p, usage)
+	_flagFields[name] = func(c *Config, value string) {
+		allocParents(name, c)
+		*field(c), _ = strconv.ParseBool(value)
+	}
+}
+
+// allocParents allocates the nested storage configurations that the field of flag name is in,
+// they're nil if they're null in configuration file. Env vars use the names of their flags.
+func allocParents(name string, c *Config) {
+	if !strings.HasPrefix(name, "storage.") {
+		return
+	}
+	if c.Storage == nil {
+		c.Storage = &StorageConfig{}
+	}
+	s := c.Storage
+	switch {
+	case strings.HasPrefix(name, "storage.kafka."):
+		if s.Kafka == nil {
+			s.Kafka = &KafkaConfig{}
+		}
+		if s.Kafka.TLS == nil {
+			s.Kafka.TLS = &kafkaclient.TLSConfig{}
+		}
+		if s.Kafka.SASL == nil {
+			s.Kafka.SASL = &kafkaclient.SASLConfig{}
+		}
+	case strings.HasPrefix(name, "storage.spool.") && s.Spool == nil:
+		s.Spool = &SpoolConfig{}
+	case strings.HasPrefix(name, "storage.delivery.") && s.Delivery == nil:
+		s.Delivery = &Delivery{}
+	case strings.HasPrefix(name, "storage.dead_letter.") && s.DeadLetter == nil:
+		s.DeadLetter = &DeadLetterConfig{}
+	}
+}
+
+func applyEnv(cfg *Config) {
 	if addr := os.Getenv("DEBUG_ADDR"); addr != "" {
-		DefaultConfig.DebugAddr = addr
+		cfg.DebugAddr = addr
 	}
 	if port := os.Getenv("PORT"); port != "" {
-		DefaultConfig.HTTPAddr = fmt.Sprintf(":%s", port)
+		cfg.HTTPAddr = fmt.Sprintf(":%s", port)
 	}
 	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
-		DefaultConfig.HTTPAddr = addr
+		cfg.HTTPAddr = addr
 	}
 	if addr := os.Getenv("GRPC_ADDR"); addr != "" {
-		DefaultConfig.GRPCAddr = addr
+		cfg.GRPCAddr = addr
 	}
 	if dataType := os.Getenv("STORAGE_DATA_TYPE"); dataType != "" {
-		DefaultConfig.Storage.DataType = dataType
+		allocParents("storage.data_type", cfg)
+		cfg.Storage.DataType = dataType
 	}
 	if types := os.Getenv("STORAGE_TYPES"); types != "" {
-		DefaultConfig.Storage.Types = types
+		allocParents("storage.types", cfg)
+		cfg.Storage.Types = types
 	}
 	if addrs := os.Getenv("STORAGE_KAFKA_ADDRS"); addrs != "" {
-		DefaultConfig.Storage.Kafka.Addrs = addrs
+		allocParents("storage.kafka.addrs", cfg)
+		cfg.Storage.Kafka.Addrs = addrs
+	}
+	if partitioner := os.Getenv("STORAGE_KAFKA_PARTITIONER"); partitioner != "" {
+		allocParents("storage.kafka.partitioner", cfg)
+		cfg.Storage.Kafka.Partitioner = partitioner
+	}
+	if version := os.Getenv("STORAGE_KAFKA_VERSION"); version != "" {
+		allocParents("storage.kafka.version", cfg)
+		cfg.Storage.Kafka.Version = version
+	}
+	if clientID := os.Getenv("STORAGE_KAFKA_CLIENT_ID"); clientID != "" {
+		allocParents("storage.kafka.client_id", cfg)
+		cfg.Storage.Kafka.ClientID = clientID
+	}
+	if mechanism := os.Getenv("STORAGE_KAFKA_SASL_MECHANISM"); mechanism != "" {
+		allocParents("storage.kafka.sasl.mechanism", cfg)
+		cfg.Storage.Kafka.SASL.Mechanism = mechanism
+	}
+	if username := os.Getenv("STORAGE_KAFKA_SASL_USERNAME"); username != "" {
+		allocParents("storage.kafka.sasl.username", cfg)
+		cfg.Storage.Kafka.SASL.Username = username
+	}
+	if password := os.Getenv("STORAGE_KAFKA_SASL_PASSWORD"); password != "" {
+		allocParents("storage.kafka.sasl.password", cfg)
+		cfg.Storage.Kafka.SASL.Password = password
+	}
+	if mode := os.Getenv("STORAGE_DELIVERY_MODE"); mode != "" {
+		allocParents("storage.delivery.mode", cfg)
+		cfg.Storage.Delivery.Mode = mode
+	}
+	if dir := os.Getenv("STORAGE_SPOOL_DIR"); dir != "" {
+		allocParents("storage.spool.dir", cfg)
+		cfg.Storage.Spool.Dir = dir
+	}
+	if topic := os.Getenv("STORAGE_DEAD_LETTER_TOPIC"); topic != "" {
+		allocParents("storage.dead_letter.topic", cfg)
+		cfg.Storage.DeadLetter.Topic = topic
+	}
+}
+
+func applyFlags(cfg *Config) {
+	for name, value := range _flagOverrides {
+		if set, ok := _flagFields[name]; ok {
+			set(cfg, value)
+		}
+	}
+}
+
+// Init loads configuration file specified by -config flag (or CONFIG_FILE env var) into DefaultConfig.
+// It must be called after flag.Parse
+func Init() error {
+	_flagOverrides = make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		_flagOverrides[f.Name] = f.Value.String()
+	})
+
+	if _configFile == "" {
+		return nil
 	}
+
+	cfg, err := Load(_configFile)
+	if err != nil {
+		return err
+	}
+
+	*DefaultConfig = *cfg
+
+	return nil
+}
+
+// File returns configuration file name, empty if not specified
+func File() string {
+	return _configFile
 }
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads configuration file, then overrides it with env vars and command line flags.
+// Priority: flags > env vars > file > default values
+func Load(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "read config file")
+	}
+
+	cfg := newConfig()
+	// sarama config has many required fields, use its default values as base
+	cfg.Storage.Kafka.ProducerConfig = sarama.NewConfig()
+
+	if err := Decode(data, filepath.Ext(filename), cfg); err != nil {
+		return nil, errors.Wrapf(err, "decode config file %s", filename)
+	}
+
+	applyEnv(cfg)
+	applyFlags(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid config file %s", filename)
+	}
+
+	return cfg, nil
+}
+
+// Decode decodes json or yaml(ext = .yaml|.yml) data into cfg.
+// Fields that are not set in data keep their values.
+func Decode(data []byte, ext string, cfg *Config) error {
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		if v == nil {
+			return nil
+		}
+		// convert to json to share the json tags of Config
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = bs
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(cfg)
+}
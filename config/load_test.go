@@ -0,0 +1,220 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "logserver-config")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	filename := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	return filename
+}
+
+func TestLoadJSON(t *testing.T) {
+	ast := assert.New(t)
+
+	filename := writeTestFile(t, "config.json", `{
+		"http_addr": ":8080",
+		"topic_router": {
+			"default_topic": "default",
+			"route_map": {"myapp": "myapp_event"}
+		},
+		"storage": {
+			"data_type": "json",
+			"types": "stdout,kafka",
+			"kafka": {
+				"addrs": "127.0.0.1:9092",
+				"client_id": "logserver",
+				"compression": "gzip",
+				"sasl": {"mechanism": "SCRAM-SHA-256", "username": "user", "password": "pass"},
+				"producer_config": {"Producer": {"RequiredAcks": -1}}
+			}
+		}
+	}`)
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+
+	ast.Equal(":8080", cfg.HTTPAddr)
+	ast.Equal(":5040", cfg.GRPCAddr)
+	ast.Equal("default", cfg.TopicRouter.DefaultTopic)
+	ast.Equal("myapp_event", cfg.TopicRouter.RouteMap["myapp"])
+	ast.Equal("json", cfg.Storage.DataType)
+	ast.Equal("127.0.0.1:9092", cfg.Storage.Kafka.Addrs)
+	ast.EqualValues(-1, cfg.Storage.Kafka.ProducerConfig.Producer.RequiredAcks)
+	// default values of sarama config are kept
+	ast.True(cfg.Storage.Kafka.ProducerConfig.Producer.Return.Errors)
+	ast.Equal("logserver", cfg.Storage.Kafka.ClientID)
+	ast.Equal("gzip", cfg.Storage.Kafka.Compression)
+	ast.Equal("SCRAM-SHA-256", cfg.Storage.Kafka.SASL.Mechanism)
+	// tls is not enabled by default
+	ast.False(cfg.Storage.Kafka.TLS.Enable)
+}
+
+func TestLoadYAML(t *testing.T) {
+	ast := assert.New(t)
+
+	filename := writeTestFile(t, "config.yaml", `
+grpc_addr: ":9090"
+topic_router:
+  default_topic: default
+  route_map:
+    myapp.pv: myapp_event_pv
+storage:
+  types: stdout
+`)
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+
+	ast.Equal(":9090", cfg.GRPCAddr)
+	ast.Equal("myapp_event_pv", cfg.TopicRouter.RouteMap["myapp.pv"])
+	ast.Equal("protobuf", cfg.Storage.DataType)
+}
+
+func TestLoadOverrides(t *testing.T) {
+	ast := assert.New(t)
+
+	filename := writeTestFile(t, "config.yaml", `
+http_addr: ":8080"
+grpc_addr: ":9090"
+storage:
+  data_type: json
+`)
+
+	os.Setenv("HTTP_ADDR", ":8081")
+	defer os.Unsetenv("HTTP_ADDR")
+	os.Setenv("STORAGE_DATA_TYPE", "protobuf")
+	defer os.Unsetenv("STORAGE_DATA_TYPE")
+	_flagOverrides = map[string]string{
+		"storage.data_type":           "json",
+		"grpc.addr":                   ":9091",
+		"storage.kafka.linger":        "10",
+		"storage.kafka.idempotent":    "true",
+		"storage.kafka.sasl.username": "user",
+	}
+	defer func() { _flagOverrides = nil }()
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+
+	ast.Equal(":8081", cfg.HTTPAddr)
+	ast.Equal(":9091", cfg.GRPCAddr)
+	ast.Equal("json", cfg.Storage.DataType)
+	ast.Equal(10, cfg.Storage.Kafka.Linger)
+	ast.True(cfg.Storage.Kafka.Idempotent)
+	ast.Equal("user", cfg.Storage.Kafka.SASL.Username)
+}
+
+func TestLoadOverridesNull(t *testing.T) {
+	ast := assert.New(t)
+
+	filename := writeTestFile(t, "config.yaml", `
+storage:
+  kafka:
+    sasl:
+  delivery:
+  spool:
+  dead_letter:
+`)
+
+	for name, value := range map[string]string{
+		"STORAGE_KAFKA_SASL_USERNAME": "user",
+		"STORAGE_DELIVERY_MODE":       "sync",
+		"STORAGE_SPOOL_DIR":           "/tmp/spool",
+		"STORAGE_DEAD_LETTER_TOPIC":   "dead-letter",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	_flagOverrides = map[string]string{
+		"storage.kafka.tls.ca_file": "ca.pem",
+	}
+	defer func() { _flagOverrides = nil }()
+
+	cfg, err := Load(filename)
+	require.NoError(t, err)
+
+	ast.Equal("user", cfg.Storage.Kafka.SASL.Username)
+	ast.Equal("ca.pem", cfg.Storage.Kafka.TLS.CAFile)
+	ast.Equal("sync", cfg.Storage.Delivery.Mode)
+	ast.Equal("/tmp/spool", cfg.Storage.Spool.Dir)
+	ast.Equal("dead-letter", cfg.Storage.DeadLetter.Topic)
+
+	// storage is null
+	filename = writeTestFile(t, "config.yaml", `
+storage:
+`)
+	_, err = Load(filename)
+	require.NoError(t, err)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown_field.yaml", "unknown: 1"},
+		{"data_type.yaml", "storage:\n  data_type: xml"},
+		{"storage.yaml", "storage:\n  types: mysql"},
+		{"kafka.yaml", "storage:\n  types: kafka"},
+		{"route.yaml", "topic_router:\n  route_map:\n    MyApp: topic"},
+		{"syntax.json", "{"},
+		{"body_size.yaml", "http:\n  max_body_size: -1"},
+		{"cors.yaml", "http:\n  cors:\n    max_age: 10"},
+		{"auth.yaml", "auth:\n  apps:\n    - key: k1"},
+		{"rate_limit.yaml", "rate_limit:\n  routes:\n    default:\n      ip: {rate: 0}"},
+		{"cors_origin.yaml", "http:\n  cors:\n    allowed_origins: [https://a.*.com]"},
+		{"cors_credentials.yaml", "http:\n  cors:\n    allowed_origins: [\"*\"]\n    allow_credentials: true"},
+		{"enrich.yaml", "enrich:\n  stages:\n    - args: x"},
+		{"privacy_action.yaml", "privacy:\n  policies:\n    default:\n      fields: {imei: truncate}"},
+		{"privacy_salt.yaml", "privacy:\n  policies:\n    default:\n      fields: {imei: hash}"},
+		{"privacy_precision.yaml", "privacy:\n  policies:\n    default:\n      coordinate_precision: -1"},
+		{"dedup.yaml", "dedup:\n  window: -1"},
+		{"event_time.yaml", "event_time:\n  action: drop"},
+		{"route_rule.yaml", "topic_router:\n  rules:\n    - topic: \"{unknown}_events\""},
+		{"key_map.yaml", "topic_router:\n  key_map:\n    t: unknown"},
+		{"sasl.yaml", "storage:\n  types: kafka\n  kafka:\n    addrs: 127.0.0.1:9092\n    sasl: {mechanism: GSSAPI}"},
+		{"compression.yaml", "storage:\n  types: kafka\n  kafka:\n    addrs: 127.0.0.1:9092\n    compression: zstd"},
+		{"partitioner.yaml", "storage:\n  types: kafka\n  kafka:\n    addrs: 127.0.0.1:9092\n    partitioner: crc32"},
+	}
+
+	for _, test := range tests {
+		_, err := Load(writeTestFile(t, test.name, test.content))
+		assert.Error(t, err, test.name)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	filename := writeTestFile(t, "config.yaml", "http_addr: \":8080\"")
+
+	reloaded := make(chan *Config, 1)
+	stop := Watch(filename, 10*time.Millisecond, func(cfg *Config) {
+		reloaded <- cfg
+	})
+	defer stop()
+
+	// make sure modification time changes
+	modTime := time.Now().Add(time.Second)
+	require.NoError(t, ioutil.WriteFile(filename, []byte("http_addr: \":8081\""), 0644))
+	require.NoError(t, os.Chtimes(filename, modTime, modTime))
+
+	select {
+	case cfg := <-reloaded:
+		assert.Equal(t, ":8081", cfg.HTTPAddr)
+	case <-time.After(time.Second):
+		t.Error("config is not reloaded")
+	}
+}
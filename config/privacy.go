@@ -0,0 +1,157 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Privacy actions of a field
+const (
+	PrivacyKeep     = "keep"
+	PrivacyDrop     = "drop"
+	PrivacyHash     = "hash"
+	PrivacyTruncate = "truncate"
+)
+
+// Default privacy policy parameters
+const (
+	DefaultIPv4Prefix          = 24
+	DefaultIPv6Prefix          = 48
+	DefaultCoordinatePrecision = 2
+)
+
+// PrivacyFields is the fields that privacy policies apply to
+var PrivacyFields = []string{"ip", "imei", "mac", "idfa", "oaid", "android_id", "lat", "lon"}
+
+// PrivacyConfig is the per app_type privacy policies applied before event logs are stored,
+// nil means event logs are stored as they are.
+type PrivacyConfig struct {
+	// policies by app_type, "default" applies to the app types that are not listed
+	Policies map[string]*PrivacyPolicy `json:"policies"`
+	// secret that hash keys are derived from, required by hash action
+	Salt string `json:"salt"`
+	// hash key is rotated every salt_rotation seconds, 0 means never rotated
+	SaltRotation int `json:"salt_rotation"`
+}
+
+// PrivacyPolicy is the actions of fields, fields not listed are kept.
+//
+//	keep: stored as it is
+//	drop: cleared
+//	hash: hex(hmac_sha256(key, value)), key is derived from salt and rotation period
+//	truncate: ip is masked to prefix, lat/lon are rounded to precision. only ip, lat and lon
+type PrivacyPolicy struct {
+	Fields map[string]string `json:"fields"`
+	// prefix length of truncated ipv4, default 24 if it's not set
+	IPv4Prefix *int `json:"ipv4_prefix"`
+	// prefix length of truncated ipv6, default 48 if it's not set
+	IPv6Prefix *int `json:"ipv6_prefix"`
+	// decimal places of truncated lat/lon, default 2 if it's not set
+	CoordinatePrecision *int `json:"coordinate_precision"`
+}
+
+func (c *PrivacyConfig) Validate() error {
+	if c.SaltRotation < 0 {
+		return errors.New("salt_rotation must not be negative")
+	}
+	for appType, policy := range c.Policies {
+		if policy == nil {
+			return errors.Errorf("policy %s is empty", appType)
+		}
+		if err := policy.Validate(); err != nil {
+			return errors.Wrapf(err, "policy %s", appType)
+		}
+		if c.Salt == "" && policy.Uses(PrivacyHash) {
+			return errors.Errorf("policy %s: salt is required by hash action", appType)
+		}
+	}
+
+	return nil
+}
+
+// GetPolicy returns policy of the app type, nil if there's none
+func (c *PrivacyConfig) GetPolicy(appType string) *PrivacyPolicy {
+	if c == nil {
+		return nil
+	}
+	if policy, ok := c.Policies[appType]; ok {
+		return policy
+	}
+
+	return c.Policies["default"]
+}
+
+func (p *PrivacyPolicy) Validate() error {
+	for field, action := range p.Fields {
+		if !isPrivacyField(field) {
+			return errors.Errorf("unknown field %s", field)
+		}
+		switch action {
+		case PrivacyKeep, PrivacyDrop, PrivacyHash:
+		case PrivacyTruncate:
+			if field != "ip" && field != "lat" && field != "lon" {
+				return errors.Errorf("field %s can't be truncated", field)
+			}
+		default:
+			return errors.Errorf("field %s: unknown action %s", field, action)
+		}
+	}
+	if v := p.GetIPv4Prefix(); v < 0 || v > 32 {
+		return errors.New("ipv4_prefix must be in [0, 32]")
+	}
+	if v := p.GetIPv6Prefix(); v < 0 || v > 128 {
+		return errors.New("ipv6_prefix must be in [0, 128]")
+	}
+	if v := p.GetCoordinatePrecision(); v < 0 || v > 8 {
+		return errors.New("coordinate_precision must be in [0, 8]")
+	}
+
+	return nil
+}
+
+// Uses reports whether any field of the policy takes the action
+func (p *PrivacyPolicy) Uses(action string) bool {
+	for _, a := range p.Fields {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetIPv4Prefix returns prefix length of truncated ipv4, 0 masks the whole address
+func (p *PrivacyPolicy) GetIPv4Prefix() int {
+	if p.IPv4Prefix == nil {
+		return DefaultIPv4Prefix
+	}
+
+	return *p.IPv4Prefix
+}
+
+// GetIPv6Prefix returns prefix length of truncated ipv6
+func (p *PrivacyPolicy) GetIPv6Prefix() int {
+	if p.IPv6Prefix == nil {
+		return DefaultIPv6Prefix
+	}
+
+	return *p.IPv6Prefix
+}
+
+// GetCoordinatePrecision returns decimal places of truncated lat/lon, 0 rounds them to integers
+func (p *PrivacyPolicy) GetCoordinatePrecision() int {
+	if p.CoordinatePrecision == nil {
+		return DefaultCoordinatePrecision
+	}
+
+	return *p.CoordinatePrecision
+}
+
+func isPrivacyField(field string) bool {
+	for _, f := range PrivacyFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// RateLimitConfig is the token bucket rate limit configuration of submit requests,
+// nil means no limit. Tokens are events, a batch request takes one token per event.
+type RateLimitConfig struct {
+	// limits by endpoint name: SubmitSingle, SubmitMultiple.
+	// "default" applies to the endpoints that are not listed.
+	Routes map[string]*RouteLimit `json:"routes"`
+	// max number of buckets kept in memory, idle buckets are dropped beyond it. default 100000
+	MaxBuckets int `json:"max_buckets"`
+}
+
+// RouteLimit is the limits of a route
+type RouteLimit struct {
+	// limit of all events
+	Global *Limit `json:"global,omitempty"`
+	// limit of each app_type
+	AppType *Limit `json:"app_type,omitempty"`
+	// limit of specific app_types, overrides AppType
+	AppTypes map[string]*Limit `json:"app_types,omitempty"`
+	// limit of each client ip, it is the peer address of gRPC requests
+	IP *Limit `json:"ip,omitempty"`
+}
+
+// Limit is a token bucket
+type Limit struct {
+	// events per second
+	Rate float64 `json:"rate"`
+	// bucket size, default is rate
+	Burst float64 `json:"burst"`
+}
+
+// DefaultMaxBuckets is the default max number of rate limit buckets
+const DefaultMaxBuckets = 100000
+
+func (c *RateLimitConfig) Validate() error {
+	if c.MaxBuckets < 0 {
+		return errors.New("max_buckets must not be negative")
+	}
+	for name, route := range c.Routes {
+		if route == nil {
+			return errors.Errorf("route %s is empty", name)
+		}
+		if err := route.Validate(); err != nil {
+			return errors.Wrapf(err, "route %s", name)
+		}
+	}
+
+	return nil
+}
+
+// GetRoute returns limits of the endpoint, nil if not limited
+func (c *RateLimitConfig) GetRoute(name string) *RouteLimit {
+	if route, ok := c.Routes[name]; ok {
+		return route
+	}
+
+	return c.Routes["default"]
+}
+
+// GetMaxBuckets returns max number of buckets kept in memory
+func (c *RateLimitConfig) GetMaxBuckets() int {
+	if c.MaxBuckets == 0 {
+		return DefaultMaxBuckets
+	}
+
+	return c.MaxBuckets
+}
+
+func (r *RouteLimit) Validate() error {
+	limits := map[string]*Limit{
+		"global":   r.Global,
+		"app_type": r.AppType,
+		"ip":       r.IP,
+	}
+	for appType, limit := range r.AppTypes {
+		limits["app_types."+appType] = limit
+	}
+	for name, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if limit.Rate <= 0 {
+			return errors.Errorf("%s: rate must be positive", name)
+		}
+		if limit.Burst < 0 {
+			return errors.Errorf("%s: burst must not be negative", name)
+		}
+	}
+
+	return nil
+}
+
+// GetAppType returns limit of the app type
+func (r *RouteLimit) GetAppType(appType string) *Limit {
+	if limit, ok := r.AppTypes[appType]; ok {
+		return limit
+	}
+
+	return r.AppType
+}
+
+// GetBurst returns the bucket size
+func (l *Limit) GetBurst() float64 {
+	if l.Burst == 0 {
+		return l.Rate
+	}
+
+	return l.Burst
+}
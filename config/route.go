@@ -0,0 +1,220 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// Operators of route conditions
+const (
+	OpEq     = "eq"
+	OpPrefix = "prefix"
+	OpRegex  = "regex"
+	OpIn     = "in"
+	OpGt     = "gt"
+	OpGte    = "gte"
+	OpLt     = "lt"
+	OpLte    = "lte"
+)
+
+// ExtendInfoPrefix is the field name prefix of extend_info keys, e.g. extend_info.source
+const ExtendInfoPrefix = "extend_info."
+
+// event log fields indexed by json name, e.g. event_id
+var _eventLogFields = func() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	typ := reflect.TypeOf(pb.EventLog{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || field.Type.Kind() == reflect.Map {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}()
+
+var _rxTemplateField = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// RouteRule routes events matching all conditions to topic and destinations.
+// Topic is a template, {field} is replaced with the field value of the event,
+// e.g. {app_type}_{platform}_events. Destination is skipped if any field of its template is empty,
+// and the rule doesn't match if all destinations are skipped.
+type RouteRule struct {
+	Match []*RouteCondition `json:"match"`
+	// shorthand of a destination with default options
+	Topic string `json:"topic,omitempty"`
+	// event is written to all destinations
+	Destinations []*Destination `json:"destinations,omitempty"`
+	// following rules are checked after the rule matches, so that event is written to their destinations too
+	Continue bool `json:"continue,omitempty"`
+}
+
+// Destination is a topic that events are written to
+type Destination struct {
+	// topic template, same as RouteRule.Topic
+	Topic string `json:"topic"`
+	// json | protobuf, default storage.data_type
+	DataType string `json:"data_type,omitempty"`
+	// fields written to the topic, empty means all. json names of EventLog fields,
+	// extend_info for the whole map or extend_info.{key}
+	Fields []string `json:"fields,omitempty"`
+	// message key: field name e.g. udid, template e.g. {app_type}:{mid}, or none for no key.
+	// default is key_map of the topic router
+	Key string `json:"key,omitempty"`
+}
+
+// KeyNone means messages have no key, they are distributed round-robin
+const KeyNone = "none"
+
+// RouteCondition is a condition on EventLog field
+type RouteCondition struct {
+	// json name of EventLog field e.g. app_type, or extend_info.{key}
+	Field string `json:"field"`
+	// eq | prefix | regex | in | gt | gte | lt | lte, default eq
+	Op string `json:"op"`
+	// operand of eq, prefix, regex and numeric compare
+	Value string `json:"value"`
+	// operand of in
+	Values []string `json:"values"`
+}
+
+func (r *RouteRule) Validate() error {
+	if r.Topic == "" && len(r.Destinations) == 0 {
+		return errors.New("topic or destinations is required")
+	}
+	if r.Topic != "" {
+		if err := validateTemplate(r.Topic); err != nil {
+			return err
+		}
+	}
+	for i, dest := range r.Destinations {
+		if dest == nil {
+			return errors.Errorf("destinations[%d] is empty", i)
+		}
+		if err := dest.Validate(); err != nil {
+			return errors.Wrapf(err, "destinations[%d]", i)
+		}
+	}
+	for i, cond := range r.Match {
+		if cond == nil {
+			return errors.Errorf("match[%d] is empty", i)
+		}
+		if err := cond.Validate(); err != nil {
+			return errors.Wrapf(err, "match[%d]", i)
+		}
+	}
+
+	return nil
+}
+
+func (d *Destination) Validate() error {
+	if d.Topic == "" {
+		return errors.New("topic is required")
+	}
+	if err := validateTemplate(d.Topic); err != nil {
+		return err
+	}
+	if d.DataType != "" && d.DataType != "json" && d.DataType != "protobuf" {
+		return errors.Errorf("data_type must be one of json, protobuf")
+	}
+	for _, field := range d.Fields {
+		if field != "extend_info" && !IsEventLogField(field) {
+			return errors.Errorf("fields: unknown field %s", field)
+		}
+	}
+	if d.Key != "" {
+		if err := ValidateKey(d.Key); err != nil {
+			return errors.Wrap(err, "key")
+		}
+	}
+
+	return nil
+}
+
+// ValidateKey checks message key spec: field name, template or none
+func ValidateKey(key string) error {
+	if key == KeyNone {
+		return nil
+	}
+	if strings.ContainsAny(key, "{}") {
+		return validateTemplate(key)
+	}
+	if !IsEventLogField(key) {
+		return errors.Errorf("unknown field %s", key)
+	}
+
+	return nil
+}
+
+func validateTemplate(tpl string) error {
+	for _, m := range _rxTemplateField.FindAllStringSubmatch(tpl, -1) {
+		if !IsEventLogField(m[1]) {
+			return errors.Errorf("%s: unknown field %s", tpl, m[1])
+		}
+	}
+	if strings.ContainsAny(_rxTemplateField.ReplaceAllString(tpl, ""), "{}") {
+		return errors.Errorf("%s: unbalanced braces", tpl)
+	}
+
+	return nil
+}
+
+func (c *RouteCondition) Validate() error {
+	if !IsEventLogField(c.Field) {
+		return errors.Errorf("unknown field %s", c.Field)
+	}
+
+	switch c.GetOp() {
+	case OpEq, OpPrefix:
+	case OpRegex:
+		if _, err := regexp.Compile(c.Value); err != nil {
+			return errors.Wrap(err, "regex")
+		}
+	case OpIn:
+		if len(c.Values) == 0 {
+			return errors.New("values of in is required")
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return errors.Errorf("%s requires number, got %s", c.Op, c.Value)
+		}
+	default:
+		return errors.Errorf("unknown op %s", c.Op)
+	}
+
+	return nil
+}
+
+// GetOp returns operator of the condition
+func (c *RouteCondition) GetOp() string {
+	if c.Op == "" {
+		return OpEq
+	}
+
+	return c.Op
+}
+
+// EventLogField returns EventLog field by json name, map fields are excluded
+func EventLogField(name string) (reflect.StructField, bool) {
+	field, ok := _eventLogFields[name]
+
+	return field, ok
+}
+
+// IsEventLogField reports whether name is EventLog field or extend_info key
+func IsEventLogField(name string) bool {
+	if strings.HasPrefix(name, ExtendInfoPrefix) {
+		return len(name) > len(ExtendInfoPrefix)
+	}
+	_, ok := _eventLogFields[name]
+
+	return ok
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/techxmind/logserver/logger"
+)
+
+// Watch reloads configuration file when the process receives SIGHUP or the file is modified,
+// then calls fn with the new configuration. Invalid configuration is logged and discarded.
+// The file modification time is checked every interval, zero interval disables the check.
+func Watch(filename string, interval time.Duration, fn func(*Config)) (stop func()) {
+	var (
+		sig     = make(chan os.Signal, 1)
+		quit    = make(chan struct{})
+		modTime = fileModTime(filename)
+	)
+
+	signal.Notify(sig, syscall.SIGHUP)
+
+	reload := func(reason string) {
+		cfg, err := Load(filename)
+		if err != nil {
+			logger.Error("Reload config", "file", filename, "reason", reason, "err", err)
+			return
+		}
+		logger.Info("Reload config", "file", filename, "reason", reason)
+		fn(cfg)
+	}
+
+	go func() {
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		defer signal.Stop(sig)
+
+		for {
+			select {
+			case <-sig:
+				modTime = fileModTime(filename)
+				reload("SIGHUP")
+			case <-tick:
+				if t := fileModTime(filename); !t.IsZero() && !t.Equal(modTime) {
+					modTime = t
+					reload("modified")
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
+
+func fileModTime(filename string) time.Time {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -13,6 +13,7 @@ import (
 var (
 	_marshalers  = make(map[string]func(string) (Marshaler, error))
 	_sinkTargets = make(map[string]func(string) (io.Writer, error))
+	_sinks       = make(map[string]func(*SinkConfig) (Sink, error))
 )
 
 func init() {
@@ -64,6 +65,48 @@ func init() {
 			return rollingfile.New(filename, options...)
 		}
 	})
+
+	// args: dir, files are named by marshaler, e.g. .json
+	RegisterSink("partitioned_file", func(cfg *SinkConfig) (Sink, error) {
+		marshaler, err := GetMarshaler(cfg.Marshaler, cfg.MarshalerArgs)
+		if err != nil {
+			return nil, err
+		}
+		return NewPartitionedSink(
+			cfg.TargetArgs,
+			"."+cfg.Marshaler,
+			marshaler,
+			cfg.Partition,
+			WithInputBufferSize(cfg.InputBufferSize),
+			WithOutputBufferSize(cfg.OutputBufferSize),
+		)
+	})
+
+	// args: dir
+	RegisterSink("parquet", func(cfg *SinkConfig) (Sink, error) {
+		return NewParquetSink(cfg.TargetArgs, cfg.Parquet, WithInputBufferSize(cfg.InputBufferSize))
+	})
+
+	// args: bucket, objects are named by marshaler, e.g. .json
+	RegisterSink("s3", func(cfg *SinkConfig) (Sink, error) {
+		marshaler, err := GetMarshaler(cfg.Marshaler, cfg.MarshalerArgs)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Sink(
+			cfg.TargetArgs,
+			"."+cfg.Marshaler,
+			marshaler,
+			cfg.S3,
+			WithInputBufferSize(cfg.InputBufferSize),
+			WithOutputBufferSize(cfg.OutputBufferSize),
+		)
+	})
+
+	// args: table, e.g. events or db.events
+	RegisterSink("clickhouse", func(cfg *SinkConfig) (Sink, error) {
+		return NewClickHouseSink(cfg.TargetArgs, cfg.ClickHouse, WithInputBufferSize(cfg.InputBufferSize))
+	})
 }
 
 func RegisterMarshaler(marshaler string, factory func(string) (Marshaler, error)) {
@@ -91,3 +134,33 @@ func GetSinkTarget(target, args string) (io.Writer, error) {
 
 	return factory(args)
 }
+
+// RegisterSink registers the target that implements Sink directly, e.g. columnar files, object storage and databases.
+// Targets that write marshaled messages, e.g. partitioned_file and s3, create the marshaler from SinkConfig themselves.
+func RegisterSink(target string, factory func(*SinkConfig) (Sink, error)) {
+	_sinks[target] = factory
+}
+
+// GetSink returns Sink of the configuration,
+// it's the registered Sink of the target, or default Sink that writes marshaled messages to the target.
+func GetSink(cfg *SinkConfig) (Sink, error) {
+	if factory, ok := _sinks[cfg.Target]; ok {
+		return factory(cfg)
+	}
+
+	marshaler, err := GetMarshaler(cfg.Marshaler, cfg.MarshalerArgs)
+	if err != nil {
+		return nil, err
+	}
+	target, err := GetSinkTarget(cfg.Target, cfg.TargetArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSink(
+		target,
+		marshaler,
+		WithInputBufferSize(cfg.InputBufferSize),
+		WithOutputBufferSize(cfg.OutputBufferSize),
+	), nil
+}
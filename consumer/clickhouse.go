@@ -0,0 +1,350 @@
+package consumer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/logger"
+)
+
+const (
+	defaultClickHouseAddr          = "http://127.0.0.1:8123"
+	defaultClickHouseBatchRows     = 10000
+	defaultClickHouseBatchInterval = 5
+	defaultClickHouseRetries       = 3
+	defaultClickHouseTimeout       = 30
+	defaultClickHouseBackoff       = time.Second
+	maxClickHouseBackoff           = 30 * time.Second
+)
+
+// ClickHouseConfig defines the inserts of clickhouse sink
+type ClickHouseConfig struct {
+	// http interface, default http://127.0.0.1:8123
+	Addr     string `json:"addr"`
+	Database string `json:"database"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// column=field, comma separated. Fields are resolved as csv headers, e.g. event_id, EventTime, extend_info.custom.
+	// Column is the field if it's omitted, e.g. "event_id,dt=logged_date,custom=extend_info.custom".
+	// Empty means all fields of EventLog in protobuf names
+	Columns string `json:"columns"`
+	// batch is inserted when it reaches the rows, default 10000
+	BatchRows int `json:"batch_rows"`
+	// seconds, batch is inserted in the interval after its first row. default 5
+	BatchInterval int `json:"batch_interval"`
+	// retries of failed insert with exponential backoff, 0 means no retries.
+	// default 3 if the config is nil
+	Retries int `json:"retries"`
+	// seconds of insert request, default 30
+	Timeout int `json:"timeout"`
+}
+
+func (c *ClickHouseConfig) Validate() error {
+	if _, _, err := parseClickHouseColumns(c.Columns); err != nil {
+		return err
+	}
+	if c.BatchRows < 0 || c.BatchInterval < 0 || c.Retries < 0 || c.Timeout < 0 {
+		return errors.New("negative value")
+	}
+
+	return nil
+}
+
+// parseClickHouseColumns returns column names and the fields
+func parseClickHouseColumns(def string) (columns []string, fields []string, err error) {
+	items := splitValues(def)
+	if len(items) == 0 {
+		items = eventLogFieldNames()
+	}
+
+	for _, item := range items {
+		column, field := item, item
+		if pos := strings.IndexByte(item, '='); pos >= 0 {
+			column, field = strings.TrimSpace(item[:pos]), strings.TrimSpace(item[pos+1:])
+		}
+		if column == "" {
+			return nil, nil, errors.Errorf("invalid clickhouse column:%s", item)
+		}
+		if _, err := newStandardName(field); err != nil {
+			return nil, nil, err
+		}
+		columns = append(columns, column)
+		fields = append(fields, field)
+	}
+
+	return columns, fields, nil
+}
+
+// eventLogFieldNames returns protobuf names of EventLog fields
+func eventLogFieldNames() []string {
+	var (
+		eventType = reflect.TypeOf(pb.EventLog{})
+		names     = make([]string, 0, eventType.NumField())
+	)
+	for i := 0; i < eventType.NumField(); i++ {
+		if name := protobufName(eventType.Field(i).Tag.Get("protobuf")); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+type clickHouseSink struct {
+	table    string
+	cfg      ClickHouseConfig
+	client   *http.Client
+	endpoint string
+	m        Marshaler
+
+	batchRows     int
+	batchInterval time.Duration
+	retries       int
+	backoff       time.Duration
+
+	input  chan *SinkMessage
+	errors chan error
+	ack    chan SinkAck
+	quit   chan struct{}
+	// closed when run returns
+	done chan struct{}
+
+	// current batch in csv
+	batch   *bytes.Buffer
+	rows    int
+	firstAt time.Time
+	lastAck SinkAck
+}
+
+// NewClickHouseSink returns Sink that inserts events to table by http interface of clickhouse.
+// Events are inserted in batches sized by rows and time, failed batches are retried with backoff.
+// Offsets of the events in a batch are acked after the batch is inserted.
+func NewClickHouseSink(table string, cfg *ClickHouseConfig, optList ...Option) (Sink, error) {
+	if table == "" {
+		return nil, errors.New("SinkTarget[clickhouse] args[table] is missing")
+	}
+
+	s := &clickHouseSink{
+		table:   table,
+		backoff: defaultClickHouseBackoff,
+		errors:  make(chan error, 1),
+		ack:     make(chan SinkAck, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+		batch:   &bytes.Buffer{},
+	}
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, errors.Wrap(err, "SinkTarget[clickhouse]")
+		}
+		s.cfg = *cfg
+	} else {
+		s.cfg.Retries = defaultClickHouseRetries
+	}
+
+	columns, fields, err := parseClickHouseColumns(s.cfg.Columns)
+	if err != nil {
+		return nil, errors.Wrap(err, "SinkTarget[clickhouse]")
+	}
+	if s.m, err = NewCSVMarshaler(fields); err != nil {
+		return nil, errors.Wrap(err, "SinkTarget[clickhouse]")
+	}
+
+	if s.cfg.Addr == "" {
+		s.cfg.Addr = defaultClickHouseAddr
+	}
+	s.endpoint = clickHouseEndpoint(s.cfg.Addr, s.cfg.Database, table, columns)
+	s.client = &http.Client{
+		Timeout: time.Duration(positive(s.cfg.Timeout, defaultClickHouseTimeout)) * time.Second,
+	}
+	s.batchRows = positive(s.cfg.BatchRows, defaultClickHouseBatchRows)
+	s.batchInterval = time.Duration(positive(s.cfg.BatchInterval, defaultClickHouseBatchInterval)) * time.Second
+	s.retries = s.cfg.Retries
+
+	opts := &options{
+		inputBufferSize: 100,
+	}
+	for _, opt := range optList {
+		opt.apply(opts)
+	}
+	s.input = make(chan *SinkMessage, opts.inputBufferSize)
+
+	go s.run()
+
+	return s, nil
+}
+
+// clickHouseEndpoint returns url of the insert query
+func clickHouseEndpoint(addr, database, table string, columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, "`"+strings.Replace(column, "`", "\\`", -1)+"`")
+	}
+
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("INSERT INTO %s (%s) FORMAT CSV", table, strings.Join(quoted, ",")))
+	if database != "" {
+		query.Set("database", database)
+	}
+
+	return strings.TrimRight(addr, "/") + "/?" + query.Encode()
+}
+
+func (s *clickHouseSink) run() {
+	ticker := time.NewTicker(s.checkInterval())
+	defer func() {
+		ticker.Stop()
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case msg := <-s.input:
+			if err := s.write(msg); err != nil {
+				s.errors <- err
+				return
+			}
+		case <-ticker.C:
+			if s.rows > 0 && time.Since(s.firstAt) >= s.batchInterval {
+				if err := s.commit(); err != nil {
+					s.errors <- err
+					return
+				}
+			}
+		case <-s.quit:
+			if err := s.commit(); err != nil {
+				logger.Error("ClickHouse sink close", "err", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *clickHouseSink) checkInterval() time.Duration {
+	if interval := s.batchInterval / 10; interval < time.Second {
+		return interval
+	}
+
+	return time.Second
+}
+
+func (s *clickHouseSink) write(msg *SinkMessage) error {
+	p, err := s.m.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if s.rows == 0 {
+		s.firstAt = time.Now()
+	}
+	s.batch.Write(p)
+	s.rows++
+
+	if s.lastAck != nil {
+		s.lastAck = s.lastAck.Chain(msg.Ack)
+	} else {
+		s.lastAck = msg.Ack
+	}
+
+	if s.rows >= s.batchRows {
+		return s.commit()
+	}
+
+	return nil
+}
+
+// commit inserts current batch, then acks the messages in it.
+// The same batch is retried, so it's deduplicated by replicated tables if the failed insert is committed.
+func (s *clickHouseSink) commit() error {
+	if s.rows == 0 {
+		return nil
+	}
+
+	var (
+		err     error
+		backoff = s.backoff
+	)
+	for i := 0; ; i++ {
+		if err = s.insert(s.batch.Bytes()); err == nil {
+			break
+		}
+		if i >= s.retries {
+			return errors.Wrapf(err, "clickhouse insert %d rows", s.rows)
+		}
+		logger.Warn("ClickHouse insert retry", "err", err, "retry", i+1, "backoff", backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxClickHouseBackoff {
+			backoff = maxClickHouseBackoff
+		}
+	}
+	logger.Debug("ClickHouse insert", "rows", s.rows)
+
+	s.batch.Reset()
+	s.rows = 0
+	s.ack <- s.lastAck
+	s.lastAck = nil
+
+	return nil
+}
+
+func (s *clickHouseSink) insert(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if s.cfg.User != "" {
+		req.Header.Set("X-ClickHouse-User", s.cfg.User)
+	}
+	if s.cfg.Password != "" {
+		req.Header.Set("X-ClickHouse-Key", s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return nil
+}
+
+func (s *clickHouseSink) Ack() <-chan SinkAck {
+	return s.ack
+}
+
+func (s *clickHouseSink) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *clickHouseSink) Input() chan<- *SinkMessage {
+	return s.input
+}
+
+// Close inserts current batch, it returns immediately if the sink stopped on error
+func (s *clickHouseSink) Close() error {
+	select {
+	case s.quit <- struct{}{}:
+		<-s.done
+	case <-s.done:
+	}
+
+	logger.Debug("ClickHouse sink close")
+
+	return nil
+}
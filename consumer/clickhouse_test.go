@@ -0,0 +1,215 @@
+package consumer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// testClickHouse records inserts of http interface
+type testClickHouse struct {
+	sync.Mutex
+	// requests that fail with 500
+	failures int
+	queries  []string
+	bodies   []string
+	users    []string
+}
+
+func (c *testClickHouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "Code: 252. DB::Exception: Too many parts")
+		return
+	}
+	c.queries = append(c.queries, r.URL.Query().Get("query"))
+	c.bodies = append(c.bodies, string(body))
+	c.users = append(c.users, r.Header.Get("X-ClickHouse-User"))
+}
+
+func TestClickHouseColumns(t *testing.T) {
+	ast := assert.New(t)
+
+	columns, fields, err := parseClickHouseColumns("event_id, EventTime, dt=logged_date, custom=extend_info.custom")
+	ast.NoError(err)
+	ast.Equal([]string{"event_id", "EventTime", "dt", "custom"}, columns)
+	ast.Equal([]string{"event_id", "EventTime", "logged_date", "extend_info.custom"}, fields)
+
+	columns, fields, err = parseClickHouseColumns("")
+	ast.NoError(err)
+	ast.Equal(columns, fields)
+	ast.Contains(columns, "event_id")
+	ast.Contains(columns, "extend_info")
+
+	_, _, err = parseClickHouseColumns("id=not_exists")
+	ast.Error(err)
+	_, _, err = parseClickHouseColumns("=event_id")
+	ast.Error(err)
+
+	ast.Equal(
+		"http://127.0.0.1:8123/?database=logs&query=INSERT+INTO+events+%28%60event_id%60%2C%60dt%60%29+FORMAT+CSV",
+		clickHouseEndpoint("http://127.0.0.1:8123/", "logs", "events", []string{"event_id", "dt"}),
+	)
+}
+
+func TestClickHouseSink(t *testing.T) {
+	ast := assert.New(t)
+
+	ch := &testClickHouse{failures: 1}
+	server := httptest.NewServer(ch)
+	defer server.Close()
+
+	sink, err := NewClickHouseSink("events", &ClickHouseConfig{
+		Addr:      server.URL,
+		User:      "logserver",
+		Columns:   "event_id,custom=extend_info.custom",
+		BatchRows: 2,
+		Retries:   1,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		sink.Input() <- &SinkMessage{
+			Ack:   &testAck{id: fmt.Sprint(i)},
+			Event: &pb.EventLog{EventId: fmt.Sprintf("event-%d", i), ExtendInfo: map[string]string{"custom": "a,b"}},
+		}
+	}
+
+	// batch is committed by rows after the failed insert is retried
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("0", ack.(*testAck).id)
+		ast.Equal([]string{"1"}, ack.(*testAck).ids)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ack")
+	}
+
+	// rest rows are committed on close
+	go sink.Close()
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("2", ack.(*testAck).id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no ack")
+	}
+
+	ch.Lock()
+	defer ch.Unlock()
+	require.Len(t, ch.bodies, 2)
+	ast.Equal("INSERT INTO events (`event_id`,`custom`) FORMAT CSV", ch.queries[0])
+	ast.Equal("event-0,\"a,b\"\nevent-1,\"a,b\"\n", ch.bodies[0])
+	ast.Equal("event-2,\"a,b\"\n", ch.bodies[1])
+	ast.Equal("logserver", ch.users[0])
+}
+
+func TestClickHouseSinkInterval(t *testing.T) {
+	ast := assert.New(t)
+
+	ch := &testClickHouse{}
+	server := httptest.NewServer(ch)
+	defer server.Close()
+
+	sink, err := NewClickHouseSink("events", &ClickHouseConfig{Addr: server.URL, BatchInterval: 1})
+	require.NoError(t, err)
+
+	sink.Input() <- &SinkMessage{
+		Ack:   &testAck{id: "0"},
+		Event: &pb.EventLog{EventId: "event-0"},
+	}
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("0", ack.(*testAck).id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no ack")
+	}
+	sink.Close()
+
+	ch.Lock()
+	defer ch.Unlock()
+	require.Len(t, ch.bodies, 1)
+	ast.True(strings.HasPrefix(ch.bodies[0], "event-0,"))
+}
+
+func TestClickHouseSinkFailure(t *testing.T) {
+	ast := assert.New(t)
+
+	ch := &testClickHouse{failures: 100}
+	server := httptest.NewServer(ch)
+	defer server.Close()
+
+	s := &clickHouseSink{
+		endpoint:  server.URL,
+		client:    http.DefaultClient,
+		m:         MarshalerFunc(JSONMarshaler),
+		batchRows: 10,
+		retries:   2,
+		backoff:   time.Millisecond,
+		ack:       make(chan SinkAck, 1),
+		batch:     &bytes.Buffer{},
+	}
+	require.NoError(t, s.write(&SinkMessage{Ack: &testAck{id: "0"}, Event: &pb.EventLog{}}))
+
+	// messages are not acked if insert fails after retries
+	err := s.commit()
+	if ast.Error(err) {
+		ast.Contains(err.Error(), "Too many parts")
+	}
+	ast.Equal(97, ch.failures)
+	ast.Equal(1, s.rows)
+	select {
+	case <-s.ack:
+		t.Fatal("unexpected ack")
+	default:
+	}
+}
+
+func TestClickHouseSinkCloseAfterError(t *testing.T) {
+	ast := assert.New(t)
+
+	ch := &testClickHouse{failures: 100}
+	server := httptest.NewServer(ch)
+	defer server.Close()
+
+	// no retries
+	sink, err := NewClickHouseSink("events", &ClickHouseConfig{Addr: server.URL, BatchRows: 1})
+	require.NoError(t, err)
+
+	sink.Input() <- &SinkMessage{Ack: &testAck{id: "0"}, Event: &pb.EventLog{EventId: "event-0"}}
+	select {
+	case err := <-sink.Errors():
+		ast.Error(err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no error")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		sink.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close blocks after error")
+	}
+
+	ch.Lock()
+	defer ch.Unlock()
+	ast.Equal(99, ch.failures)
+}
@@ -30,6 +30,8 @@ func main() {
 		return
 	}
 
+	consumer.ApplyEnv(consumer.DefaultConfig)
+
 	ctx := context.Background()
 	consumer, err := consumer.New(ctx, consumer.DefaultConfig)
 	if err != nil {
@@ -2,9 +2,11 @@ package consumer
 
 import (
 	"flag"
+	"os"
 	"strings"
 
 	"github.com/Shopify/sarama"
+	"github.com/techxmind/logserver/kafkaclient"
 	"github.com/techxmind/logserver/logger"
 )
 
@@ -13,9 +15,20 @@ var DefaultConfig *Config
 func init() {
 	DefaultConfig = &Config{
 		GroupID: "default",
+		Format:  FormatAuto,
 		Sink: &SinkConfig{
-			Marshaler: "json",
-			Target:    "stdout",
+			Marshaler:  "json",
+			Target:     "stdout",
+			Parquet:    &ParquetConfig{},
+			Partition:  &PartitionConfig{},
+			S3:         &S3Config{},
+			ClickHouse: &ClickHouseConfig{},
+		},
+		DeadLetter: &DeadLetterConfig{},
+		Dedup:      &DedupConfig{},
+		Kafka: &kafkaclient.Config{
+			TLS:  &kafkaclient.TLSConfig{},
+			SASL: &kafkaclient.SASLConfig{},
 		},
 	}
 
@@ -43,12 +56,78 @@ func init() {
 		"event-log",
 		"Topics to consume, multiple values are comma separated",
 	)
+	flag.StringVar(
+		&DefaultConfig.Format,
+		"format",
+		FormatAuto,
+		"Message payload format, auto|protobuf|json|jsonpb. auto detects it by content-type header or payload",
+	)
+	flag.StringVar(
+		&DefaultConfig.TopicFormats,
+		"topic_formats",
+		"",
+		"Message payload format by topic, e.g. event-log=json,event-log-pb=protobuf. Topics not listed use format",
+	)
 	flag.StringVar(
 		&DefaultConfig.Offset,
 		"offset",
 		"newest",
 		"initial offset: newest | oldest",
 	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.ClientID,
+		"kafka.client_id",
+		"",
+		"Kafka client id",
+	)
+	flag.BoolVar(
+		&DefaultConfig.Kafka.TLS.Enable,
+		"kafka.tls.enable",
+		false,
+		"Connect to kafka brokers with TLS",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.TLS.CAFile,
+		"kafka.tls.ca_file",
+		"",
+		"PEM encoded CA certificates of kafka brokers, empty means system CAs",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.TLS.CertFile,
+		"kafka.tls.cert_file",
+		"",
+		"PEM encoded client certificate of kafka TLS",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.TLS.KeyFile,
+		"kafka.tls.key_file",
+		"",
+		"PEM encoded client key of kafka TLS",
+	)
+	flag.BoolVar(
+		&DefaultConfig.Kafka.TLS.InsecureSkipVerify,
+		"kafka.tls.insecure_skip_verify",
+		false,
+		"Skip verifying kafka broker certificates",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.SASL.Mechanism,
+		"kafka.sasl.mechanism",
+		"",
+		"Kafka SASL mechanism, PLAIN|SCRAM-SHA-256|SCRAM-SHA-512. Empty means disabled",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.SASL.Username,
+		"kafka.sasl.username",
+		"",
+		"Kafka SASL username",
+	)
+	flag.StringVar(
+		&DefaultConfig.Kafka.SASL.Password,
+		"kafka.sasl.password",
+		"",
+		"Kafka SASL password, default env KAFKA_SASL_PASSWORD",
+	)
 	flag.StringVar(
 		&DefaultConfig.Sink.Marshaler,
 		"sink.marshaler",
@@ -65,13 +144,211 @@ func init() {
 		&DefaultConfig.Sink.Target,
 		"sink.target",
 		"stdout",
-		"Output target, stdout|file",
+		"Output target, stdout|file|partitioned_file|parquet|s3|clickhouse",
 	)
 	flag.StringVar(
 		&DefaultConfig.Sink.TargetArgs,
 		"sink.target_args",
 		"",
-		"Output target args, file target requires filename specified. RollingFile format: filename:maxsize[:maxage:suffix]",
+		"Output target args, file target requires filename specified. RollingFile format: filename:maxsize[:maxage:suffix]. partitioned_file and parquet targets require dir specified. s3 target requires bucket specified. clickhouse target requires table specified",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.Partition.TimeField,
+		"sink.partition.time_field",
+		"event_time",
+		"Time that partitions files, event_time|logged_time",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.Partition.By,
+		"sink.partition.by",
+		"",
+		"Partition keys before dt, topic|app_type|event. Multiple values are comma separated",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.Partition.Granularity,
+		"sink.partition.granularity",
+		"hour",
+		"Time partition granularity, hour|day",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.Partition.Timezone,
+		"sink.partition.timezone",
+		"UTC",
+		"IANA time zone of dt and hour partitions, e.g. Asia/Shanghai",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.Partition.MaxDelay,
+		"sink.partition.max_delay",
+		defaultPartitionMaxDelay,
+		"Events whose event_time differs from logged_time more than the seconds are partitioned by logged_time",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.Partition.IdleTimeout,
+		"sink.partition.idle_timeout",
+		defaultPartitionIdleTimeout,
+		"Partition files that are not written in the seconds are closed",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.Partition.FlushInterval,
+		"sink.partition.flush_interval",
+		defaultPartitionFlushInterval,
+		"Seconds that partition files are flushed and offsets are marked",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.Partition.MaxOpenFiles,
+		"sink.partition.max_open_files",
+		defaultPartitionMaxOpenFiles,
+		"Max number of open partition files, least recently written file is closed when it's reached",
+	)
+	flag.Int64Var(
+		&DefaultConfig.Sink.Parquet.RowGroupSize,
+		"sink.parquet.row_group_size",
+		defaultParquetRowGroupSize,
+		"Bytes of parquet row group",
+	)
+	flag.Int64Var(
+		&DefaultConfig.Sink.Parquet.FileSize,
+		"sink.parquet.file_size",
+		defaultParquetFileSize,
+		"Parquet file is finished when it reaches the size in bytes",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.Parquet.FileAge,
+		"sink.parquet.file_age",
+		defaultParquetFileAge,
+		"Parquet file is finished after it's opened for the seconds",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.Parquet.Compression,
+		"sink.parquet.compression",
+		"snappy",
+		"Parquet compression codec, snappy|gzip|zstd|lz4|none",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.Endpoint,
+		"sink.s3.endpoint",
+		"",
+		"S3 compatible endpoint, e.g. http://127.0.0.1:9000. Empty means aws s3",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.Region,
+		"sink.s3.region",
+		defaultS3Region,
+		"S3 region",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.AccessKey,
+		"sink.s3.access_key",
+		"",
+		"S3 access key, empty means aws default credential chain, e.g. env AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.SecretKey,
+		"sink.s3.secret_key",
+		"",
+		"S3 secret key of the access key",
+	)
+	flag.BoolVar(
+		&DefaultConfig.Sink.S3.PathStyle,
+		"sink.s3.path_style",
+		false,
+		"Put bucket in path instead of host, it's required by minio in most cases",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.Key,
+		"sink.s3.key",
+		defaultS3Key,
+		"S3 object key template, placeholders: {topic} {date} {hour} {host} {ts}. Extension of marshaler is appended",
+	)
+	flag.BoolVar(
+		&DefaultConfig.Sink.S3.Gzip,
+		"sink.s3.gzip",
+		false,
+		"Gzip S3 objects",
+	)
+	flag.Int64Var(
+		&DefaultConfig.Sink.S3.PartSize,
+		"sink.s3.part_size",
+		5<<20,
+		"Bytes of S3 multipart upload part, min 5MB",
+	)
+	flag.Int64Var(
+		&DefaultConfig.Sink.S3.FileSize,
+		"sink.s3.file_size",
+		defaultS3FileSize,
+		"S3 file is uploaded when it reaches the size in bytes before compression",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.S3.FileAge,
+		"sink.s3.file_age",
+		defaultS3FileAge,
+		"S3 file is uploaded after it's created for the seconds",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.S3.Retries,
+		"sink.s3.retries",
+		defaultS3Retries,
+		"Max retries of failed S3 requests, 0 means no retries",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.S3.TempDir,
+		"sink.s3.temp_dir",
+		"",
+		"Dir of local files before they are uploaded, default os temp dir",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.ClickHouse.Addr,
+		"sink.clickhouse.addr",
+		defaultClickHouseAddr,
+		"ClickHouse http interface address",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.ClickHouse.Database,
+		"sink.clickhouse.database",
+		"",
+		"ClickHouse database, empty means default database of the user",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.ClickHouse.User,
+		"sink.clickhouse.user",
+		"",
+		"ClickHouse user",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.ClickHouse.Password,
+		"sink.clickhouse.password",
+		"",
+		"ClickHouse password, default env CLICKHOUSE_PASSWORD",
+	)
+	flag.StringVar(
+		&DefaultConfig.Sink.ClickHouse.Columns,
+		"sink.clickhouse.columns",
+		"",
+		"ClickHouse columns, column=field comma separated, e.g. event_id,dt=logged_date,custom=extend_info.custom. Fields are resolved as csv headers. Empty means all fields of EventLog",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.ClickHouse.BatchRows,
+		"sink.clickhouse.batch_rows",
+		defaultClickHouseBatchRows,
+		"ClickHouse batch is inserted when it reaches the rows",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.ClickHouse.BatchInterval,
+		"sink.clickhouse.batch_interval",
+		defaultClickHouseBatchInterval,
+		"ClickHouse batch is inserted in the seconds after its first row",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.ClickHouse.Retries,
+		"sink.clickhouse.retries",
+		defaultClickHouseRetries,
+		"Retries of failed ClickHouse insert with exponential backoff, 0 means no retries",
+	)
+	flag.IntVar(
+		&DefaultConfig.Sink.ClickHouse.Timeout,
+		"sink.clickhouse.timeout",
+		defaultClickHouseTimeout,
+		"Seconds of ClickHouse insert request",
 	)
 	flag.IntVar(
 		&DefaultConfig.Sink.InputBufferSize,
@@ -85,6 +362,36 @@ func init() {
 		4096,
 		"Sink output buffer size, 0 means no output buffer",
 	)
+	flag.StringVar(
+		&DefaultConfig.DeadLetter.Target,
+		"dead_letter.target",
+		"",
+		"Target that messages failed to unmarshal are written to, stdout|file. Empty means disabled",
+	)
+	flag.StringVar(
+		&DefaultConfig.DeadLetter.TargetArgs,
+		"dead_letter.target_args",
+		"",
+		"Dead letter target args, same as sink.target_args",
+	)
+	flag.IntVar(
+		&DefaultConfig.Dedup.Window,
+		"dedup.window",
+		0,
+		"Drop messages whose event_id is consumed again in the window, in seconds. 0 means disabled",
+	)
+	flag.IntVar(
+		&DefaultConfig.Dedup.MaxKeys,
+		"dedup.max_keys",
+		1000000,
+		"Max number of event ids kept in memory for dedup",
+	)
+	flag.StringVar(
+		&DefaultConfig.HeaderFilter,
+		"filter.headers",
+		"",
+		"Consume only messages with matched record headers, e.g. app-type=myapp|myapp2,event=pv. Requires kafka_version >= 0.11",
+	)
 }
 
 type Config struct {
@@ -94,6 +401,36 @@ type Config struct {
 	Topics       string      `json:"topics"`
 	Offset       string      `json:"offset"`
 	Sink         *SinkConfig `json:"sink"`
+
+	// auto | protobuf | json | jsonpb, default auto
+	Format string `json:"format"`
+	// format by topic, see ParseTopicFormats
+	TopicFormats string `json:"topic_formats"`
+
+	// client id, tls and sasl options
+	Kafka *kafkaclient.Config `json:"kafka"`
+
+	DeadLetter *DeadLetterConfig `json:"dead_letter"`
+	Dedup      *DedupConfig      `json:"dedup"`
+
+	// record header filter, see ParseHeaderFilter
+	HeaderFilter string `json:"header_filter"`
+}
+
+// DeadLetterConfig is the destination of rejected messages, records are deadletter.Letter in json lines
+type DeadLetterConfig struct {
+	// registered sink target, empty means disabled
+	Target     string `json:"target"`
+	TargetArgs string `json:"target_args"`
+}
+
+// DedupConfig drops messages whose event_id is consumed again in the window,
+// e.g. retried by clients or redelivered after rebalance
+type DedupConfig struct {
+	// seconds, 0 means disabled
+	Window int `json:"window"`
+	// max number of event ids kept in memory
+	MaxKeys int `json:"max_keys"`
 }
 
 type SinkConfig struct {
@@ -103,6 +440,26 @@ type SinkConfig struct {
 	TargetArgs       string `json:"target_args"`
 	OutputBufferSize int    `json:"output_buffer_size"`
 	InputBufferSize  int    `json:"input_buffer_size"`
+
+	// options of partitioned_file target
+	Partition *PartitionConfig `json:"partition"`
+	// options of parquet target
+	Parquet *ParquetConfig `json:"parquet"`
+	// options of s3 target
+	S3 *S3Config `json:"s3"`
+	// options of clickhouse target
+	ClickHouse *ClickHouseConfig `json:"clickhouse"`
+}
+
+// ApplyEnv sets the secrets that are not specified by flags from env vars.
+// Env vars are not flag defaults, so that secrets are never printed in usage. It must be called after flag.Parse
+func ApplyEnv(cfg *Config) {
+	if cfg.Kafka != nil && cfg.Kafka.SASL != nil && cfg.Kafka.SASL.Password == "" {
+		cfg.Kafka.SASL.Password = os.Getenv("KAFKA_SASL_PASSWORD")
+	}
+	if cfg.Sink != nil && cfg.Sink.ClickHouse != nil && cfg.Sink.ClickHouse.Password == "" {
+		cfg.Sink.ClickHouse.Password = os.Getenv("CLICKHOUSE_PASSWORD")
+	}
 }
 
 func (cfg *Config) GetKafkaVersion() sarama.KafkaVersion {
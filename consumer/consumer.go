@@ -4,11 +4,14 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/pkg/errors"
 	_ "go.uber.org/atomic"
 
+	"github.com/techxmind/logserver/deadletter"
+	"github.com/techxmind/logserver/dedup"
 	pb "github.com/techxmind/logserver/interface-defs"
 	"github.com/techxmind/logserver/logger"
 )
@@ -20,6 +23,18 @@ type Consumer struct {
 	wg     sync.WaitGroup
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// nil if dead letter is disabled
+	deadLetter *DeadLetterWriter
+	// nil if dedup is disabled
+	dedup *dedup.Set
+	// nil if all messages are consumed
+	filter HeaderFilter
+	// offsets of the messages that are not sent to sink
+	skipped skippedOffsets
+	// payload format of topics that are not in formats, empty means auto
+	format  string
+	formats map[string]string
 }
 
 func New(pctx context.Context, cfg *Config) (*Consumer, error) {
@@ -39,27 +54,50 @@ func New(pctx context.Context, cfg *Config) (*Consumer, error) {
 		sinkCfg = DefaultConfig.Sink
 	}
 
-	marshaler, err := GetMarshaler(sinkCfg.Marshaler, sinkCfg.MarshalerArgs)
+	sink, err := GetSink(sinkCfg)
 	if err != nil {
 		return nil, err
 	}
-	sinkTarget, err := GetSinkTarget(sinkCfg.Target, sinkCfg.TargetArgs)
+
+	var deadLetter *DeadLetterWriter
+	if cfg.DeadLetter != nil && cfg.DeadLetter.Target != "" {
+		w, err := GetSinkTarget(cfg.DeadLetter.Target, cfg.DeadLetter.TargetArgs)
+		if err != nil {
+			return nil, errors.Wrap(err, "dead letter")
+		}
+		deadLetter = NewDeadLetterWriter(w)
+	}
+
+	filter, err := ParseHeaderFilter(cfg.HeaderFilter)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Format != "" && !validFormat(cfg.Format) {
+		return nil, errors.Errorf("unknown format %s", cfg.Format)
+	}
+	formats, err := ParseTopicFormats(cfg.TopicFormats)
 	if err != nil {
 		return nil, err
 	}
-	sink := NewSink(
-		sinkTarget,
-		marshaler,
-		WithInputBufferSize(sinkCfg.InputBufferSize),
-		WithOutputBufferSize(sinkCfg.OutputBufferSize),
-	)
 
 	ctx, cancel := context.WithCancel(pctx)
 	consumer := &Consumer{
-		ctx:    ctx,
-		topics: topics,
-		cancel: cancel,
-		sink:   sink,
+		ctx:        ctx,
+		topics:     topics,
+		cancel:     cancel,
+		sink:       sink,
+		deadLetter: deadLetter,
+		filter:     filter,
+		format:     cfg.Format,
+		formats:    formats,
+	}
+
+	if cfg.Dedup != nil && cfg.Dedup.Window > 0 {
+		maxKeys := cfg.Dedup.MaxKeys
+		if maxKeys <= 0 {
+			maxKeys = DefaultConfig.Dedup.MaxKeys
+		}
+		consumer.dedup = dedup.New(time.Duration(cfg.Dedup.Window)*time.Second, maxKeys)
 	}
 
 	consumerConfig := sarama.NewConfig()
@@ -69,6 +107,11 @@ func New(pctx context.Context, cfg *Config) (*Consumer, error) {
 	if cfg.Offset == "oldest" {
 		consumerConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
 	}
+	if cfg.Kafka != nil {
+		if err := cfg.Kafka.Apply(consumerConfig); err != nil {
+			return nil, errors.Wrap(err, "kafka")
+		}
+	}
 
 	group, err := sarama.NewConsumerGroup(addrs, cfg.GroupID, consumerConfig)
 	if err != nil {
@@ -88,6 +131,9 @@ func New(pctx context.Context, cfg *Config) (*Consumer, error) {
 		"kafka.addrs", strings.Join(addrs, ","),
 		"kafka.version", consumerConfig.Version,
 		"topics", strings.Join(topics, ","),
+		"filter.headers", cfg.HeaderFilter,
+		"format", cfg.Format,
+		"topic_formats", cfg.TopicFormats,
 	)
 
 	return consumer, nil
@@ -120,7 +166,7 @@ OUTLOOP:
 		case iack := <-c.sink.Ack():
 			logger.Debug("sink.Ack")
 			if ack, ok := iack.(*Ack); ok {
-				ack.MarkOffset()
+				c.markOffset(ack)
 			}
 
 		case <-c.ctx.Done():
@@ -172,6 +218,18 @@ func (ack *Ack) MarkOffset() {
 	}
 }
 
+// markOffset marks offsets of the acked messages and the skipped messages following them
+func (c *Consumer) markOffset(ack *Ack) {
+	ack.MarkOffset()
+
+	c.skipped.ack(ack.Topic, ack.Partition, ack.Offset)
+	for topic, partitions := range ack.chained {
+		for partition, offset := range partitions {
+			c.skipped.ack(topic, partition, offset)
+		}
+	}
+}
+
 // ConsumeClaim implements sarama.ConsumerGroupHandler
 //
 func (c *Consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
@@ -185,12 +243,24 @@ OUTLOOP:
 			if msg == nil {
 				break OUTLOOP
 			}
+			if c.filter != nil && !c.filter.Match(msg.Headers) {
+				c.skipped.skip(sess, msg)
+				continue
+			}
 			event := &pb.EventLog{}
-			err := event.XXX_Unmarshal(msg.Value)
+			err := decodeEvent(c.topicFormat(msg.Topic), msg, event)
 			if err != nil {
 				logger.Error("Message unmarshal", "err", err)
+				c.writeDeadLetter(deadletter.ReasonUnmarshal, msg, err)
+				c.skipped.skip(sess, msg)
+				continue
+			}
+			if c.dedup != nil && event.EventId != "" && c.dedup.Seen(event.EventId) {
+				logger.Debug("Duplicated message", "event_id", event.EventId, "topic", msg.Topic, "offset", msg.Offset)
+				c.skipped.skip(sess, msg)
 				continue
 			}
+			c.skipped.send(msg)
 			c.sink.Input() <- &SinkMessage{
 				Topic: msg.Topic,
 				Event: event,
@@ -211,10 +281,20 @@ OUTLOOP:
 	return nil
 }
 
+// topicFormat returns payload format of topic
+func (c *Consumer) topicFormat(topic string) string {
+	if format, ok := c.formats[topic]; ok {
+		return format
+	}
+
+	return c.format
+}
+
 // Setup implements sarama.ConsumerGroupHandler
 //
 func (c *Consumer) Setup(s sarama.ConsumerGroupSession) error {
 	logger.Info("Setup", "memberID", s.MemberID())
+	c.skipped.reset()
 	return nil
 }
 
@@ -231,4 +311,7 @@ func (c *Consumer) Close() {
 	c.cancel()
 	c.wg.Wait()
 	c.group.Close()
+	if c.deadLetter != nil {
+		c.deadLetter.Close()
+	}
 }
@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/techxmind/logserver/deadletter"
+	"github.com/techxmind/logserver/logger"
+)
+
+// DeadLetterWriter writes rejected kafka messages as json lines of deadletter.Letter
+type DeadLetterWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewDeadLetterWriter(w io.Writer) *DeadLetterWriter {
+	return &DeadLetterWriter{
+		w: w,
+	}
+}
+
+// Write writes message rejected for reason
+// It's thread-safe
+func (d *DeadLetterWriter) Write(reason string, msg *sarama.ConsumerMessage, err error) error {
+	letter := deadletter.New(reason, msg.Value, err)
+	letter.Topic = msg.Topic
+	letter.Partition = msg.Partition
+	letter.Offset = msg.Offset
+
+	data, err := letter.Marshal()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err = d.w.Write(data)
+
+	return err
+}
+
+// Close closes underlying writer if it's closable
+func (d *DeadLetterWriter) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if closer, ok := d.w.(io.Closer); ok && d.w != os.Stdout {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (c *Consumer) writeDeadLetter(reason string, msg *sarama.ConsumerMessage, err error) {
+	if c.deadLetter == nil {
+		return
+	}
+
+	if werr := c.deadLetter.Write(reason, msg, err); werr != nil {
+		logger.Error("write dead letter", "err", werr)
+	}
+}
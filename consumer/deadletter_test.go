@@ -0,0 +1,216 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/techxmind/logserver/deadletter"
+	"github.com/techxmind/logserver/dedup"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+type testSession struct {
+	sarama.ConsumerGroupSession
+	// marked offsets in order
+	offsets []int64
+}
+
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.offsets = append(s.offsets, offset)
+}
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func TestDeadLetter(t *testing.T) {
+	ast := assert.New(t)
+
+	var (
+		output     = &bytes.Buffer{}
+		deadLetter = &bytes.Buffer{}
+		sink       = NewSink(output, MarshalerFunc(JSONMarshaler), WithOutputBufferSize(0))
+		claim      = &testClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Consumer{
+		ctx:        ctx,
+		cancel:     cancel,
+		sink:       sink,
+		deadLetter: NewDeadLetterWriter(deadLetter),
+	}
+
+	event, _ := (&pb.EventLog{EventId: "event-1"}).Marshal()
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Partition: 1, Offset: 10, Value: []byte{0xff, 0xff}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Partition: 1, Offset: 11, Value: event}
+	close(claim.messages)
+
+	ast.NoError(c.ConsumeClaim(&testSession{}, claim))
+	<-sink.Ack()
+	sink.Close()
+
+	ast.Contains(output.String(), "event-1")
+
+	lines := strings.Split(strings.TrimSpace(deadLetter.String()), "\n")
+	if ast.Len(lines, 1) {
+		var letter deadletter.Letter
+		ast.NoError(json.Unmarshal([]byte(lines[0]), &letter))
+		ast.Equal(deadletter.ReasonUnmarshal, letter.Reason)
+		ast.Equal("event-log", letter.Topic)
+		ast.Equal(int32(1), letter.Partition)
+		ast.Equal(int64(10), letter.Offset)
+		ast.Equal([]byte{0xff, 0xff}, letter.Data)
+		ast.NotEmpty(letter.Error)
+	}
+}
+
+func TestConsumerDedup(t *testing.T) {
+	ast := assert.New(t)
+
+	var (
+		output = &bytes.Buffer{}
+		sink   = NewSink(output, MarshalerFunc(JSONMarshaler), WithOutputBufferSize(0))
+		claim  = &testClaim{messages: make(chan *sarama.ConsumerMessage, 4)}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Consumer{
+		ctx:    ctx,
+		cancel: cancel,
+		sink:   sink,
+		dedup:  dedup.New(time.Minute, 1000),
+	}
+
+	event1, _ := (&pb.EventLog{EventId: "event-1"}).Marshal()
+	event2, _ := (&pb.EventLog{EventId: "event-2"}).Marshal()
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 10, Value: event1}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 11, Value: event1}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 12, Value: event2}
+	// trailing duplicate
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 13, Value: event2}
+	close(claim.messages)
+
+	sess := &testSession{}
+	ast.NoError(c.ConsumeClaim(sess, claim))
+	c.markOffset((<-sink.Ack()).(*Ack))
+	c.markOffset((<-sink.Ack()).(*Ack))
+	sink.Close()
+
+	ast.Equal(1, strings.Count(output.String(), "event-1"))
+	ast.Equal(1, strings.Count(output.String(), "event-2"))
+	// offset of the trailing duplicate is marked after the messages before it are acked
+	ast.Equal([]int64{10, 12, 13}, sess.offsets)
+}
+
+func TestHeaderFilter(t *testing.T) {
+	ast := assert.New(t)
+
+	_, err := ParseHeaderFilter("app-type")
+	ast.Error(err)
+
+	filter, err := ParseHeaderFilter("app-type=myapp|myapp2, event=pv")
+	ast.NoError(err)
+
+	var (
+		output = &bytes.Buffer{}
+		sink   = NewSink(output, MarshalerFunc(JSONMarshaler), WithOutputBufferSize(0))
+		claim  = &testClaim{messages: make(chan *sarama.ConsumerMessage, 4)}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Consumer{
+		ctx:    ctx,
+		cancel: cancel,
+		sink:   sink,
+		filter: filter,
+	}
+
+	header := func(key, value string) *sarama.RecordHeader {
+		return &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+	}
+	event1, _ := (&pb.EventLog{EventId: "event-1"}).Marshal()
+	event2, _ := (&pb.EventLog{EventId: "event-2"}).Marshal()
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 10, Value: event1, Headers: []*sarama.RecordHeader{
+		header("app-type", "myapp2"), header("event", "pv"),
+	}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 11, Value: event2, Headers: []*sarama.RecordHeader{
+		header("app-type", "other"), header("event", "pv"),
+	}}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 12, Value: event2, Headers: []*sarama.RecordHeader{
+		header("app-type", "myapp"),
+	}}
+	// not unmarshaled
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 13, Value: []byte{0xff, 0xff}}
+	close(claim.messages)
+
+	ast.NoError(c.ConsumeClaim(&testSession{}, claim))
+	<-sink.Ack()
+	sink.Close()
+
+	ast.Contains(output.String(), "event-1")
+	ast.NotContains(output.String(), "event-2")
+}
+
+func TestHeaderFilterMarkOffset(t *testing.T) {
+	ast := assert.New(t)
+
+	filter, err := ParseHeaderFilter("app-type=myapp")
+	ast.NoError(err)
+
+	var (
+		output = &bytes.Buffer{}
+		sink   = NewSink(output, MarshalerFunc(JSONMarshaler), WithOutputBufferSize(0))
+		claim  = &testClaim{messages: make(chan *sarama.ConsumerMessage, 3)}
+		sess   = &testSession{}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Consumer{
+		ctx:    ctx,
+		cancel: cancel,
+		sink:   sink,
+		filter: filter,
+	}
+
+	headers := []*sarama.RecordHeader{{Key: []byte("app-type"), Value: []byte("myapp")}}
+	event, _ := (&pb.EventLog{EventId: "event-1"}).Marshal()
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 10, Value: event, Headers: headers}
+	// the last messages are all filtered out
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 11, Value: event}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 12, Value: event}
+	close(claim.messages)
+
+	ast.NoError(c.ConsumeClaim(sess, claim))
+	// skipped offsets are not marked before the message pending in sink
+	ast.Empty(sess.offsets)
+
+	ack := <-sink.Ack()
+	c.markOffset(ack.(*Ack))
+	ast.Equal([]int64{10, 12}, sess.offsets)
+
+	// skipped offsets are marked immediately if no message is pending
+	claim = &testClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 13, Value: event}
+	close(claim.messages)
+	ast.NoError(c.ConsumeClaim(sess, claim))
+	ast.Equal([]int64{10, 12, 13}, sess.offsets)
+
+	sink.Close()
+}
@@ -4,6 +4,9 @@ CREATE TABLE event_log (
   logged_time timestamp NOT NULL,
   logged_date bigint NOT NULL,
   event_time bigint NOT NULL DEFAULT 0,
+  corrected_event_time bigint NOT NULL DEFAULT 0,
+  clock_skew bigint NOT NULL DEFAULT 0,
+  event_time_flag varchar(10) NOT NULL DEFAULT '',
   session_id varchar(32) NOT NULL,
   udid varchar(32) NOT NULL DEFAULT '',
   tkid varchar(32) NOT NULL DEFAULT '',
@@ -28,6 +31,10 @@ CREATE TABLE event_log (
   android_id varchar(64) not null default '',
   idfa varchar(64) not null default '',
   oaid varchar(100) not null default '',
+  browser varchar(20) not null default '',
+  browser_version varchar(20) not null default '',
+  device_type varchar(10) not null default '',
+  engine varchar(10) not null default '',
   carrier int not null default 0,
   network int not null default 0,
   ip varchar(50) not null default '',
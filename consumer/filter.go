@@ -0,0 +1,56 @@
+package consumer
+
+import (
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// HeaderFilter selects messages by kafka record headers without unmarshalling the payload.
+// A message matches if every header of the filter has one of the accepted values.
+type HeaderFilter map[string]map[string]bool
+
+// ParseHeaderFilter parses filter definition, e.g. app-type=myapp|myapp2,event=pv
+// Empty definition returns nil filter which matches all messages.
+func ParseHeaderFilter(def string) (HeaderFilter, error) {
+	var filter HeaderFilter
+
+	for _, item := range splitValues(def) {
+		pos := strings.IndexByte(item, '=')
+		if pos <= 0 {
+			return nil, errors.Errorf("invalid header filter:%s", item)
+		}
+		name := strings.ToLower(strings.TrimSpace(item[:pos]))
+		if filter == nil {
+			filter = make(HeaderFilter)
+		}
+		if filter[name] == nil {
+			filter[name] = make(map[string]bool)
+		}
+		for _, val := range strings.Split(item[pos+1:], "|") {
+			filter[name][strings.TrimSpace(val)] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// Match reports whether message headers are accepted by the filter.
+// Messages without the header are rejected.
+func (f HeaderFilter) Match(headers []*sarama.RecordHeader) bool {
+	for name, vals := range f {
+		matched := false
+		for _, header := range headers {
+			if header != nil && strings.EqualFold(string(header.Key), name) {
+				matched = vals[string(header.Value)]
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,99 @@
+package consumer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/pkg/errors"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/storage"
+)
+
+// Payload formats of kafka messages
+const (
+	// detected by content-type record header, or sniffed from payload if the header is missing
+	FormatAuto     = "auto"
+	FormatProtobuf = "protobuf"
+	// encoding/json, written by logservice with storage.data_type json.
+	// It's faster than jsonpb, but fields in camelCase are ignored
+	FormatJSON = "json"
+	// protobuf json mapping, e.g. camelCase names and int64 in string
+	FormatJSONPB = "jsonpb"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case FormatAuto, FormatProtobuf, FormatJSON, FormatJSONPB:
+		return true
+	}
+
+	return false
+}
+
+// ParseTopicFormats parses payload formats by topic, e.g. event-log=json,event-log-pb=protobuf
+func ParseTopicFormats(def string) (map[string]string, error) {
+	var formats map[string]string
+
+	for _, item := range splitValues(def) {
+		pos := strings.IndexByte(item, '=')
+		if pos <= 0 {
+			return nil, errors.Errorf("invalid topic format:%s", item)
+		}
+		topic, format := strings.TrimSpace(item[:pos]), strings.TrimSpace(item[pos+1:])
+		if !validFormat(format) {
+			return nil, errors.Errorf("unknown format %s of topic %s", format, topic)
+		}
+		if formats == nil {
+			formats = make(map[string]string)
+		}
+		formats[topic] = format
+	}
+
+	return formats, nil
+}
+
+// messageFormat returns the payload format of message, empty format means auto
+func messageFormat(format string, msg *sarama.ConsumerMessage) string {
+	if format != "" && format != FormatAuto {
+		return format
+	}
+
+	for _, header := range msg.Headers {
+		if header == nil || !strings.EqualFold(string(header.Key), storage.HeaderContentType) {
+			continue
+		}
+		switch string(header.Value) {
+		case storage.ContentTypeProtobuf:
+			return FormatProtobuf
+		case storage.ContentTypeJSON:
+			return FormatJSON
+		}
+	}
+
+	// protobuf message of EventLog never starts with '{', that is the tag of field 15 with group wire type.
+	// It's not trimmed, protobuf message may start with '\n' followed by '{' or spaces, e.g. tag and length of event_id.
+	if len(msg.Value) > 0 && msg.Value[0] == '{' {
+		return FormatJSON
+	}
+
+	return FormatProtobuf
+}
+
+// decodeEvent decodes message payload in format into event
+func decodeEvent(format string, msg *sarama.ConsumerMessage, event *pb.EventLog) error {
+	switch detected := messageFormat(format, msg); {
+	case detected == FormatJSON && format == FormatJSON:
+		return json.Unmarshal(msg.Value, event)
+	// detected json may be written by any json marshaler,
+	// jsonpb accepts both field names and int64 in string
+	case detected == FormatJSON, detected == FormatJSONPB:
+		unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+		return unmarshaler.Unmarshal(bytes.NewReader(msg.Value), event)
+	default:
+		return event.XXX_Unmarshal(msg.Value)
+	}
+}
@@ -0,0 +1,105 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	ast := assert.New(t)
+
+	event := &pb.EventLog{
+		EventId:    "event-1",
+		EventTime:  1604300000000,
+		AppType:    "myapp",
+		ExtendInfo: map[string]string{"k": "v"},
+	}
+	protobufData, _ := event.Marshal()
+	jsonData, _ := json.Marshal(event)
+	jsonpbData, _ := (&jsonpb.Marshaler{}).MarshalToString(event)
+
+	contentType := func(v string) []*sarama.RecordHeader {
+		return []*sarama.RecordHeader{{Key: []byte("content-type"), Value: []byte(v)}}
+	}
+
+	tests := []struct {
+		format string
+		msg    *sarama.ConsumerMessage
+	}{
+		{FormatProtobuf, &sarama.ConsumerMessage{Value: protobufData}},
+		{FormatJSON, &sarama.ConsumerMessage{Value: jsonData}},
+		{FormatJSONPB, &sarama.ConsumerMessage{Value: []byte(jsonpbData)}},
+		{"", &sarama.ConsumerMessage{Value: protobufData}},
+		{"", &sarama.ConsumerMessage{Value: jsonData}},
+		{"", &sarama.ConsumerMessage{Value: []byte(jsonpbData)}},
+		{FormatAuto, &sarama.ConsumerMessage{Value: protobufData, Headers: contentType("application/x-protobuf")}},
+		{FormatAuto, &sarama.ConsumerMessage{Value: jsonData, Headers: contentType("application/json")}},
+	}
+
+	for i, test := range tests {
+		decoded := &pb.EventLog{}
+		if ast.NoError(decodeEvent(test.format, test.msg, decoded), "case %d", i) {
+			ast.Equal("event-1", decoded.EventId, "case %d", i)
+			ast.Equal(int64(1604300000000), decoded.EventTime, "case %d", i)
+			ast.Equal("v", decoded.ExtendInfo["k"], "case %d", i)
+		}
+	}
+
+	// length of event_id is '{' or ' ' after the tag '\n'
+	for _, n := range []int{'{', ' '} {
+		data, _ := (&pb.EventLog{EventId: strings.Repeat("e", n)}).Marshal()
+		ast.Equal(FormatProtobuf, messageFormat(FormatAuto, &sarama.ConsumerMessage{Value: data}), "length %d", n)
+	}
+
+	// wrong format
+	ast.Error(decodeEvent(FormatJSONPB, &sarama.ConsumerMessage{Value: protobufData}, &pb.EventLog{}))
+	ast.Error(decodeEvent(FormatProtobuf, &sarama.ConsumerMessage{Value: jsonData}, &pb.EventLog{}))
+
+	formats, err := ParseTopicFormats("event-log=json, event-log-pb=protobuf")
+	ast.NoError(err)
+	ast.Equal(map[string]string{"event-log": "json", "event-log-pb": "protobuf"}, formats)
+	_, err = ParseTopicFormats("event-log=xml")
+	ast.Error(err)
+}
+
+func TestConsumeMixedTopics(t *testing.T) {
+	ast := assert.New(t)
+
+	var (
+		output = &bytes.Buffer{}
+		sink   = NewSink(output, MarshalerFunc(JSONMarshaler), WithOutputBufferSize(0))
+		claim  = &testClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Consumer{
+		ctx:     ctx,
+		cancel:  cancel,
+		sink:    sink,
+		formats: map[string]string{"event-log-json": FormatJSON},
+	}
+
+	event1, _ := (&pb.EventLog{EventId: "event-1"}).Marshal()
+	event2, _ := json.Marshal(&pb.EventLog{EventId: "event-2"})
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log", Offset: 10, Value: event1}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "event-log-json", Offset: 10, Value: event2}
+	close(claim.messages)
+
+	ast.NoError(c.ConsumeClaim(&testSession{}, claim))
+	<-sink.Ack()
+	<-sink.Ack()
+	sink.Close()
+
+	ast.Equal(1, strings.Count(output.String(), "event-1"))
+	ast.Equal(1, strings.Count(output.String(), "event-2"))
+}
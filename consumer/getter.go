@@ -88,11 +88,31 @@ func (s *standardName) GetValue(e *pb.EventLog) (string, error) {
 			return e.AppVersion, nil
 		}
 		return "", errFieldNotExists
+	case "browser":
+		if s.key == "" {
+			return e.Browser, nil
+		}
+		return "", errFieldNotExists
+	case "browserversion":
+		if s.key == "" {
+			return e.BrowserVersion, nil
+		}
+		return "", errFieldNotExists
 	case "carrier":
 		if s.key == "" {
 			return strconv.FormatInt(int64(e.Carrier), 10), nil
 		}
 		return "", errFieldNotExists
+	case "clockskew":
+		if s.key == "" {
+			return strconv.FormatInt(int64(e.ClockSkew), 10), nil
+		}
+		return "", errFieldNotExists
+	case "correctedeventtime":
+		if s.key == "" {
+			return strconv.FormatInt(int64(e.CorrectedEventTime), 10), nil
+		}
+		return "", errFieldNotExists
 	case "devicebrand":
 		if s.key == "" {
 			return e.DeviceBrand, nil
@@ -103,6 +123,11 @@ func (s *standardName) GetValue(e *pb.EventLog) (string, error) {
 			return e.DeviceModel, nil
 		}
 		return "", errFieldNotExists
+	case "devicetype":
+		if s.key == "" {
+			return e.DeviceType, nil
+		}
+		return "", errFieldNotExists
 	case "devicevendor":
 		if s.key == "" {
 			return e.DeviceVendor, nil
@@ -113,6 +138,11 @@ func (s *standardName) GetValue(e *pb.EventLog) (string, error) {
 			return strconv.FormatInt(int64(e.Duration), 10), nil
 		}
 		return "", errFieldNotExists
+	case "engine":
+		if s.key == "" {
+			return e.Engine, nil
+		}
+		return "", errFieldNotExists
 	case "env":
 		if s.key == "" {
 			return e.Env, nil
@@ -133,6 +163,11 @@ func (s *standardName) GetValue(e *pb.EventLog) (string, error) {
 			return strconv.FormatInt(int64(e.EventTime), 10), nil
 		}
 		return "", errFieldNotExists
+	case "eventtimeflag":
+		if s.key == "" {
+			return e.EventTimeFlag, nil
+		}
+		return "", errFieldNotExists
 	case "extendinfo":
 		if s.key != "" {
 			if e.ExtendInfo == nil {
@@ -141,8 +176,8 @@ func (s *standardName) GetValue(e *pb.EventLog) (string, error) {
 			return e.ExtendInfo[s.key], nil
 		}
 		if e.ExtendInfo == nil {
-				return "{}", nil
-			}
+			return "{}", nil
+		}
 		if bs, err := json.Marshal(e.ExtendInfo); err == nil {
 			return string(bs), nil
 		}
@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// skippedOffsets marks offsets of the messages that are not sent to sink, e.g. filtered messages.
+// Offset of a skipped message is marked after the messages sent to sink before it are acked,
+// so that the committed offset never passes the messages pending in sink.
+// Zero value is ready to use.
+type skippedOffsets struct {
+	mu sync.Mutex
+	// topic => partition => offsets
+	partitions map[string]map[int32]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	// offset of the last message sent to sink, -1 if none
+	sent  int64
+	acked int64
+	// offset of the last skipped message that is not marked yet, -1 if none
+	skipped int64
+	session sarama.ConsumerGroupSession
+}
+
+func (o *skippedOffsets) get(topic string, partition int32) *partitionOffsets {
+	if o.partitions == nil {
+		o.partitions = make(map[string]map[int32]*partitionOffsets)
+	}
+	if _, ok := o.partitions[topic]; !ok {
+		o.partitions[topic] = make(map[int32]*partitionOffsets)
+	}
+	p, ok := o.partitions[topic][partition]
+	if !ok {
+		p = &partitionOffsets{sent: -1, acked: -1, skipped: -1}
+		o.partitions[topic][partition] = p
+	}
+
+	return p
+}
+
+// reset forgets offsets of the previous session
+func (o *skippedOffsets) reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.partitions = nil
+}
+
+// send records offset of the message sent to sink
+func (o *skippedOffsets) send(msg *sarama.ConsumerMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.get(msg.Topic, msg.Partition).sent = msg.Offset
+}
+
+// skip marks offset of the skipped message if no message is pending in sink, otherwise it's marked on ack
+func (o *skippedOffsets) skip(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p := o.get(msg.Topic, msg.Partition)
+	if p.acked >= p.sent {
+		sess.MarkOffset(msg.Topic, msg.Partition, msg.Offset, "")
+		return
+	}
+	p.skipped, p.session = msg.Offset, sess
+}
+
+// ack records the offset acked by sink, pending skipped offset is marked if all messages sent are acked
+func (o *skippedOffsets) ack(topic string, partition int32, offset int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p := o.get(topic, partition)
+	if offset > p.acked {
+		p.acked = offset
+	}
+	if p.skipped >= 0 && p.acked >= p.sent {
+		p.session.MarkOffset(topic, partition, p.skipped, "")
+		p.skipped, p.session = -1, nil
+	}
+}
@@ -0,0 +1,421 @@
+package consumer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/logger"
+)
+
+const (
+	defaultParquetRowGroupSize = 64 << 20
+	defaultParquetFileSize     = 256 << 20
+	defaultParquetFileAge      = 600
+)
+
+// ParquetConfig defines parquet files written by parquet sink
+type ParquetConfig struct {
+	// bytes of row group, default 64MB
+	RowGroupSize int64 `json:"row_group_size"`
+	// file is finished when it reaches the size in bytes, default 256MB
+	FileSize int64 `json:"file_size"`
+	// file is finished after it's opened for the seconds, default 600
+	FileAge int `json:"file_age"`
+	// snappy | gzip | zstd | lz4 | none, default snappy
+	Compression string `json:"compression"`
+}
+
+// _parquetRow is the row type of parquet file, it's derived from pb.EventLog.
+// Columns are named by protobuf field names, extend_info is a map column.
+var _parquetRow = newParquetRowType()
+
+// parquetField maps EventLog field to row field
+type parquetField struct {
+	event int
+	row   int
+}
+
+type parquetRowType struct {
+	typ    reflect.Type
+	fields []parquetField
+}
+
+func newParquetRowType() *parquetRowType {
+	var (
+		eventType = reflect.TypeOf(pb.EventLog{})
+		fields    = make([]reflect.StructField, 0, eventType.NumField())
+		rowType   = &parquetRowType{}
+	)
+
+	for i := 0; i < eventType.NumField(); i++ {
+		field := eventType.Field(i)
+		name := protobufName(field.Tag.Get("protobuf"))
+		if name == "" {
+			continue
+		}
+
+		var typ reflect.Type
+		var tag string
+		switch field.Type.Kind() {
+		case reflect.String:
+			typ, tag = reflect.TypeOf(""), "type=BYTE_ARRAY, convertedtype=UTF8"
+		case reflect.Int64:
+			typ, tag = reflect.TypeOf(int64(0)), "type=INT64"
+		// enums are int32
+		case reflect.Int32:
+			typ, tag = reflect.TypeOf(int32(0)), "type=INT32"
+		case reflect.Bool:
+			typ, tag = reflect.TypeOf(false), "type=BOOLEAN"
+		case reflect.Float32:
+			typ, tag = reflect.TypeOf(float32(0)), "type=FLOAT"
+		case reflect.Float64:
+			typ, tag = reflect.TypeOf(float64(0)), "type=DOUBLE"
+		case reflect.Map:
+			if field.Type.Key().Kind() != reflect.String || field.Type.Elem().Kind() != reflect.String {
+				continue
+			}
+			typ = reflect.TypeOf(map[string]string{})
+			tag = "type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"
+		default:
+			continue
+		}
+
+		rowType.fields = append(rowType.fields, parquetField{event: i, row: len(fields)})
+		fields = append(fields, reflect.StructField{
+			Name: field.Name,
+			Type: typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"name=%s, %s"`, name, tag)),
+		})
+	}
+
+	rowType.typ = reflect.StructOf(fields)
+
+	return rowType
+}
+
+// protobufName returns field name in protobuf tag
+func protobufName(tag string) string {
+	for _, item := range strings.Split(tag, ",") {
+		if strings.HasPrefix(item, "name=") {
+			return item[5:]
+		}
+	}
+
+	return ""
+}
+
+// newRow returns pointer of the row converted from event
+func (t *parquetRowType) newRow(event *pb.EventLog) interface{} {
+	var (
+		src = reflect.ValueOf(event).Elem()
+		row = reflect.New(t.typ)
+		dst = row.Elem()
+	)
+
+	for _, field := range t.fields {
+		value := src.Field(field.event)
+		if value.Kind() == reflect.Map {
+			if value.Len() > 0 {
+				dst.Field(field.row).Set(value)
+			}
+			continue
+		}
+		dst.Field(field.row).Set(value.Convert(dst.Field(field.row).Type()))
+	}
+
+	return row.Interface()
+}
+
+func parquetCompression(name string) (parquet.CompressionCodec, error) {
+	switch strings.ToLower(name) {
+	case "", "snappy":
+		return parquet.CompressionCodec_SNAPPY, nil
+	case "gzip":
+		return parquet.CompressionCodec_GZIP, nil
+	case "zstd":
+		return parquet.CompressionCodec_ZSTD, nil
+	case "lz4":
+		return parquet.CompressionCodec_LZ4, nil
+	case "none":
+		return parquet.CompressionCodec_UNCOMPRESSED, nil
+	}
+
+	return parquet.CompressionCodec_UNCOMPRESSED, errors.Errorf("unknown parquet compression %s", name)
+}
+
+// countWriter counts bytes written to file
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// NewParquetSink returns Sink that writes events to parquet files in dir.
+// Files are written with .tmp suffix, and renamed when they are finished by size or age.
+// Offsets of the messages in a file are acked after the file is renamed.
+// Unfinished files left in dir, e.g. by a crash, are removed, their messages are consumed again.
+func NewParquetSink(dir string, cfg *ParquetConfig, optList ...Option) (Sink, error) {
+	if dir == "" {
+		return nil, errors.New("SinkTarget[parquet] args[dir] is missing")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "SinkTarget[parquet]")
+	}
+	if err := removeParquetTemps(dir); err != nil {
+		return nil, errors.Wrap(err, "SinkTarget[parquet]")
+	}
+
+	opts := &options{
+		inputBufferSize: 100,
+	}
+	for _, opt := range optList {
+		opt.apply(opts)
+	}
+
+	s := &parquetSink{
+		dir:          dir,
+		rowGroupSize: defaultParquetRowGroupSize,
+		fileSize:     defaultParquetFileSize,
+		fileAge:      defaultParquetFileAge * time.Second,
+		compression:  parquet.CompressionCodec_SNAPPY,
+		input:        make(chan *SinkMessage, opts.inputBufferSize),
+		errors:       make(chan error, 1),
+		ack:          make(chan SinkAck, 1),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if cfg != nil {
+		if cfg.RowGroupSize > 0 {
+			s.rowGroupSize = cfg.RowGroupSize
+		}
+		if cfg.FileSize > 0 {
+			s.fileSize = cfg.FileSize
+		}
+		if cfg.FileAge > 0 {
+			s.fileAge = time.Duration(cfg.FileAge) * time.Second
+		}
+		var err error
+		if s.compression, err = parquetCompression(cfg.Compression); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// removeParquetTemps removes unfinished files in dir
+func removeParquetTemps(dir string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "events-*.parquet.tmp"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+		logger.Info("Parquet unfinished file removed", "file", name)
+	}
+
+	return nil
+}
+
+type parquetSink struct {
+	dir          string
+	rowGroupSize int64
+	fileSize     int64
+	fileAge      time.Duration
+	compression  parquet.CompressionCodec
+
+	input  chan *SinkMessage
+	errors chan error
+	ack    chan SinkAck
+	quit   chan struct{}
+	// closed when run returns
+	done chan struct{}
+
+	// current file
+	file    *os.File
+	counter *countWriter
+	writer  *writer.ParquetWriter
+	openAt  time.Time
+	lastAck SinkAck
+}
+
+func (s *parquetSink) run() {
+	ticker := time.NewTicker(s.checkInterval())
+	defer func() {
+		ticker.Stop()
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case msg := <-s.input:
+			if err := s.write(msg); err != nil {
+				s.discard()
+				s.errors <- err
+				return
+			}
+		case <-ticker.C:
+			if s.writer != nil && time.Since(s.openAt) >= s.fileAge {
+				if err := s.finish(); err != nil {
+					s.discard()
+					s.errors <- err
+					return
+				}
+			}
+		case <-s.quit:
+			if err := s.finish(); err != nil {
+				logger.Error("Parquet sink close", "err", err)
+				s.discard()
+			}
+			return
+		}
+	}
+}
+
+func (s *parquetSink) checkInterval() time.Duration {
+	if interval := s.fileAge / 10; interval < time.Second {
+		return interval
+	}
+
+	return time.Second
+}
+
+func (s *parquetSink) write(msg *SinkMessage) error {
+	if s.writer == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.writer.Write(_parquetRow.newRow(msg.Event)); err != nil {
+		return errors.Wrap(err, "parquet write")
+	}
+	if s.lastAck != nil {
+		s.lastAck = s.lastAck.Chain(msg.Ack)
+	} else {
+		s.lastAck = msg.Ack
+	}
+
+	// buffered rows are not counted precisely, they are compressed when flushed
+	if s.counter.n+s.writer.ObjsSize >= s.fileSize {
+		return s.finish()
+	}
+
+	return nil
+}
+
+func (s *parquetSink) open() (err error) {
+	name := filepath.Join(s.dir, fmt.Sprintf("events-%d.parquet.tmp", time.Now().UnixNano()))
+	if s.file, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); err != nil {
+		return errors.Wrap(err, "parquet open")
+	}
+
+	s.counter = &countWriter{w: s.file}
+	if s.writer, err = writer.NewParquetWriter(writerfile.NewWriterFile(s.counter), reflect.New(_parquetRow.typ).Interface(), 1); err != nil {
+		s.file.Close()
+		os.Remove(name)
+		return errors.Wrap(err, "parquet writer")
+	}
+	s.writer.RowGroupSize = s.rowGroupSize
+	s.writer.CompressionType = s.compression
+	s.openAt = time.Now()
+
+	logger.Debug("Parquet file open", "file", name)
+
+	return nil
+}
+
+// finish writes footer of current file and renames it, then acks the messages in the file
+func (s *parquetSink) finish() error {
+	if s.writer == nil {
+		return nil
+	}
+
+	var (
+		file = s.file
+		name = file.Name()
+		err  = s.writer.WriteStop()
+	)
+	s.file, s.counter, s.writer = nil, nil, nil
+
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+		return errors.Wrapf(err, "parquet finish %s", name)
+	}
+
+	final := strings.TrimSuffix(name, ".tmp")
+	if err := os.Rename(name, final); err != nil {
+		os.Remove(name)
+		return errors.Wrap(err, "parquet rename")
+	}
+	logger.Debug("Parquet file finished", "file", final)
+
+	if s.lastAck != nil {
+		s.ack <- s.lastAck
+		s.lastAck = nil
+	}
+
+	return nil
+}
+
+// discard removes current file after it fails, the messages in it are not acked
+func (s *parquetSink) discard() {
+	if s.file == nil {
+		return
+	}
+
+	s.file.Close()
+	os.Remove(s.file.Name())
+	s.file, s.counter, s.writer, s.lastAck = nil, nil, nil, nil
+}
+
+func (s *parquetSink) Ack() <-chan SinkAck {
+	return s.ack
+}
+
+func (s *parquetSink) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *parquetSink) Input() chan<- *SinkMessage {
+	return s.input
+}
+
+// Close finishes current file, it returns immediately if the sink stopped on error
+func (s *parquetSink) Close() error {
+	select {
+	case s.quit <- struct{}{}:
+		<-s.done
+	case <-s.done:
+	}
+
+	logger.Debug("Parquet sink close")
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package consumer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func readParquet(t *testing.T, filename string) []interface{} {
+	f, err := local.NewLocalFileReader(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	pr, err := reader.NewParquetReader(f, reflect.New(_parquetRow.typ).Interface(), 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	rows := reflect.New(reflect.SliceOf(_parquetRow.typ))
+	rows.Elem().Set(reflect.MakeSlice(rows.Elem().Type(), int(pr.GetNumRows()), int(pr.GetNumRows())))
+	require.NoError(t, pr.Read(rows.Interface()))
+
+	values := make([]interface{}, 0)
+	for i := 0; i < rows.Elem().Len(); i++ {
+		values = append(values, rows.Elem().Index(i).Interface())
+	}
+
+	return values
+}
+
+func TestParquetSink(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink, err := NewParquetSink(dir, &ParquetConfig{FileSize: 1, Compression: "zstd"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		sink.Input() <- &SinkMessage{
+			Ack: &testAck{id: fmt.Sprint(i)},
+			Event: &pb.EventLog{
+				EventId:    fmt.Sprintf("event-%d", i),
+				EventTime:  1604300000000,
+				Carrier:    pb.EventLog_Carrier(1),
+				ExtendInfo: map[string]string{"k": "v"},
+			},
+		}
+		// file is finished by size for every message
+		select {
+		case ack := <-sink.Ack():
+			ast.Equal(fmt.Sprint(i), ack.(*testAck).id)
+		case <-time.After(time.Second):
+			t.Fatal("no ack")
+		}
+	}
+	sink.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	require.Len(t, files, 2)
+	for _, file := range files {
+		ast.Equal(".parquet", filepath.Ext(file))
+	}
+
+	rows := readParquet(t, files[0])
+	require.Len(t, rows, 1)
+	row := reflect.ValueOf(rows[0])
+	ast.Equal("event-0", row.FieldByName("EventId").String())
+	ast.Equal(int64(1604300000000), row.FieldByName("EventTime").Int())
+	ast.Equal(int64(1), row.FieldByName("Carrier").Int())
+	ast.Equal(map[string]string{"k": "v"}, row.FieldByName("ExtendInfo").Interface())
+}
+
+func TestParquetSinkAge(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink, err := NewParquetSink(dir, &ParquetConfig{FileAge: 1})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		sink.Input() <- &SinkMessage{
+			Ack:   &testAck{id: fmt.Sprint(i)},
+			Event: &pb.EventLog{EventId: fmt.Sprintf("event-%d", i)},
+		}
+	}
+
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal([]string{"1", "2"}, ack.(*testAck).ids)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no ack")
+	}
+	sink.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	require.Len(t, files, 1)
+	ast.Len(readParquet(t, files[0]), 3)
+}
+
+func TestParquetSinkDefaults(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-parquet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// unfinished file of previous run
+	tmp := filepath.Join(dir, "events-1.parquet.tmp")
+	require.NoError(t, ioutil.WriteFile(tmp, []byte("PAR1"), 0644))
+
+	sink, err := NewParquetSink(dir, nil)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ast.Equal(parquet.CompressionCodec_SNAPPY, sink.(*parquetSink).compression)
+	_, err = os.Stat(tmp)
+	ast.True(os.IsNotExist(err))
+}
+
+func TestParquetSinkCloseAfterError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logserver-parquet")
+	require.NoError(t, err)
+
+	sink, err := NewParquetSink(dir, nil)
+	require.NoError(t, err)
+
+	// file can't be opened
+	require.NoError(t, os.RemoveAll(dir))
+	sink.Input() <- &SinkMessage{Ack: &testAck{id: "0"}, Event: &pb.EventLog{EventId: "event-0"}}
+	select {
+	case err := <-sink.Errors():
+		assert.Error(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no error")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		sink.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close blocks after error")
+	}
+}
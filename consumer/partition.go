@@ -0,0 +1,409 @@
+package consumer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/logger"
+)
+
+const (
+	defaultPartitionMaxDelay      = 86400
+	defaultPartitionIdleTimeout   = 300
+	defaultPartitionFlushInterval = 5
+	defaultPartitionMaxOpenFiles  = 64
+)
+
+// PartitionConfig defines the directories of partitioned file target,
+// e.g. dir/topic=event-log/dt=20201102/hour=15/part-host-1604300000000000000.json
+type PartitionConfig struct {
+	// event_time | logged_time, default event_time
+	TimeField string `json:"time_field"`
+	// topic, app_type, event, comma separated. directories are created in order before dt
+	By string `json:"by"`
+	// hour | day, default hour
+	Granularity string `json:"granularity"`
+	// IANA time zone of dt and hour, e.g. Asia/Shanghai. default UTC
+	Timezone string `json:"timezone"`
+	// seconds, events whose event_time differs from logged_time more than it are partitioned by logged_time.
+	// default 86400
+	MaxDelay int `json:"max_delay"`
+	// seconds, files that are not written in it are closed. default 300
+	IdleTimeout int `json:"idle_timeout"`
+	// seconds, open files are flushed and offsets are marked in the interval. default 5
+	FlushInterval int `json:"flush_interval"`
+	// least recently written file is closed when it's reached. default 64
+	MaxOpenFiles int `json:"max_open_files"`
+}
+
+func (c *PartitionConfig) Validate() error {
+	switch c.TimeField {
+	case "", "event_time", "logged_time":
+	default:
+		return errors.Errorf("unknown time_field %s", c.TimeField)
+	}
+	switch c.Granularity {
+	case "", "hour", "day":
+	default:
+		return errors.Errorf("unknown granularity %s", c.Granularity)
+	}
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return errors.Wrap(err, "timezone")
+		}
+	}
+	for _, key := range splitValues(c.By) {
+		switch key {
+		case "topic", "app_type", "event":
+		default:
+			return errors.Errorf("unknown partition key %s", key)
+		}
+	}
+	if c.MaxDelay < 0 || c.IdleTimeout < 0 || c.FlushInterval < 0 || c.MaxOpenFiles < 0 {
+		return errors.New("negative value")
+	}
+
+	return nil
+}
+
+// partitionFile is an open file of partition
+type partitionFile struct {
+	file      *os.File
+	w         *bufio.Writer
+	lastWrite time.Time
+}
+
+type partitionedSink struct {
+	dir  string
+	ext  string
+	m    Marshaler
+	cfg  PartitionConfig
+	host string
+
+	bufferSize    int
+	maxDelay      int64
+	idleTimeout   time.Duration
+	flushInterval time.Duration
+	maxOpenFiles  int
+	// time zone of partitions, nil means UTC
+	location *time.Location
+
+	input  chan *SinkMessage
+	errors chan error
+	ack    chan SinkAck
+	quit   chan struct{}
+	// closed when run returns
+	done chan struct{}
+
+	// partition dir => open file
+	files map[string]*partitionFile
+	// ack of the messages that are not flushed
+	lastAck SinkAck
+}
+
+// NewPartitionedSink returns Sink that writes marshaled messages to the time partitioned directories in dir.
+// Each partition keeps an open file until it's idle, a new file is created if the partition is written again,
+// e.g. by late events. Offsets are marked after all the files are flushed.
+func NewPartitionedSink(dir, ext string, m Marshaler, cfg *PartitionConfig, optList ...Option) (Sink, error) {
+	if dir == "" {
+		return nil, errors.New("SinkTarget[partitioned_file] args[dir] is missing")
+	}
+
+	s := &partitionedSink{
+		dir:    dir,
+		ext:    ext,
+		m:      m,
+		errors: make(chan error, 1),
+		ack:    make(chan SinkAck, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+		files:  make(map[string]*partitionFile),
+	}
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, errors.Wrap(err, "SinkTarget[partitioned_file]")
+		}
+		s.cfg = *cfg
+	}
+
+	opts := &options{
+		inputBufferSize:  100,
+		outputBufferSize: 4096,
+	}
+	for _, opt := range optList {
+		opt.apply(opts)
+	}
+	s.input = make(chan *SinkMessage, opts.inputBufferSize)
+	s.bufferSize = opts.outputBufferSize
+
+	s.maxDelay = int64(positive(s.cfg.MaxDelay, defaultPartitionMaxDelay)) * 1000
+	s.idleTimeout = time.Duration(positive(s.cfg.IdleTimeout, defaultPartitionIdleTimeout)) * time.Second
+	s.flushInterval = time.Duration(positive(s.cfg.FlushInterval, defaultPartitionFlushInterval)) * time.Second
+	s.maxOpenFiles = positive(s.cfg.MaxOpenFiles, defaultPartitionMaxOpenFiles)
+	if s.cfg.Timezone != "" {
+		// it's validated
+		s.location, _ = time.LoadLocation(s.cfg.Timezone)
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		s.host = host
+	} else {
+		s.host = "localhost"
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+func positive(v, dft int) int {
+	if v > 0 {
+		return v
+	}
+	return dft
+}
+
+func (s *partitionedSink) run() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer func() {
+		ticker.Stop()
+		// messages that are not flushed are consumed again
+		for partition := range s.files {
+			s.closeFile(partition)
+		}
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case msg := <-s.input:
+			if err := s.write(msg); err != nil {
+				s.errors <- err
+				return
+			}
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				s.errors <- err
+				return
+			}
+			if err := s.closeIdle(time.Now().Add(-s.idleTimeout)); err != nil {
+				s.errors <- err
+				return
+			}
+		case <-s.quit:
+			if err := s.flush(); err != nil {
+				logger.Error("Partitioned sink close", "err", err)
+			}
+			return
+		}
+	}
+}
+
+// partition returns relative dir of the message
+func (s *partitionedSink) partition(msg *SinkMessage) string {
+	var (
+		event = msg.Event
+		ts    = event.EventTime
+		parts = make([]string, 0, 5)
+	)
+
+	if s.cfg.TimeField == "logged_time" || ts <= 0 || abs(ts-event.LoggedTime) > s.maxDelay {
+		ts = event.LoggedTime
+	}
+	if ts <= 0 {
+		ts = time.Now().UnixNano() / 1e6
+	}
+
+	for _, key := range splitValues(s.cfg.By) {
+		var value string
+		switch key {
+		case "topic":
+			value = msg.Topic
+		case "app_type":
+			value = event.AppType
+		case "event":
+			value = event.Event
+		}
+		parts = append(parts, key+"="+partitionValue(value))
+	}
+
+	t := time.Unix(ts/1000, 0).UTC()
+	if s.location != nil {
+		t = t.In(s.location)
+	}
+	parts = append(parts, "dt="+t.Format("20060102"))
+	if s.cfg.Granularity != "day" {
+		parts = append(parts, "hour="+t.Format("15"))
+	}
+
+	return filepath.Join(parts...)
+}
+
+// partitionValue returns the value that is safe in path
+func partitionValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '=' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, v)
+	if v == "" || v == "." || v == ".." {
+		return "unknown"
+	}
+
+	return v
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func (s *partitionedSink) write(msg *SinkMessage) error {
+	p, err := s.m.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	partition := s.partition(msg)
+	f, ok := s.files[partition]
+	if !ok {
+		if f, err = s.openFile(partition); err != nil {
+			return err
+		}
+	}
+	if _, err := f.w.Write(p); err != nil {
+		return errors.Wrap(err, "partitioned file write")
+	}
+	f.lastWrite = time.Now()
+
+	if s.lastAck != nil {
+		s.lastAck = s.lastAck.Chain(msg.Ack)
+	} else {
+		s.lastAck = msg.Ack
+	}
+
+	return nil
+}
+
+func (s *partitionedSink) openFile(partition string) (*partitionFile, error) {
+	if len(s.files) >= s.maxOpenFiles {
+		var (
+			lru       string
+			lastWrite time.Time
+		)
+		for name, f := range s.files {
+			if lru == "" || f.lastWrite.Before(lastWrite) {
+				lru, lastWrite = name, f.lastWrite
+			}
+		}
+		if err := s.closeFile(lru); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(s.dir, partition)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "partitioned file mkdir")
+	}
+	name := filepath.Join(dir, fmt.Sprintf("part-%s-%d%s", s.host, time.Now().UnixNano(), s.ext))
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "partitioned file open")
+	}
+
+	f := &partitionFile{
+		file: file,
+		w:    bufio.NewWriterSize(file, s.bufferSize),
+	}
+	s.files[partition] = f
+
+	logger.Debug("Partitioned file open", "file", name)
+
+	return f, nil
+}
+
+// closeFile flushes and closes file of the partition.
+// Messages are acked in next flush, because other partitions may hold messages that are not flushed.
+func (s *partitionedSink) closeFile(partition string) error {
+	f := s.files[partition]
+	delete(s.files, partition)
+
+	err := f.w.Flush()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "partitioned file close %s", f.file.Name())
+	}
+
+	logger.Debug("Partitioned file close", "file", f.file.Name())
+
+	return nil
+}
+
+func (s *partitionedSink) closeIdle(before time.Time) error {
+	for partition, f := range s.files {
+		if f.lastWrite.Before(before) {
+			if err := s.closeFile(partition); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flush flushes all open files, then acks the messages
+func (s *partitionedSink) flush() error {
+	if s.lastAck == nil {
+		return nil
+	}
+
+	for _, f := range s.files {
+		if f.w.Buffered() == 0 {
+			continue
+		}
+		if err := f.w.Flush(); err != nil {
+			return errors.Wrapf(err, "partitioned file flush %s", f.file.Name())
+		}
+	}
+
+	s.ack <- s.lastAck
+	s.lastAck = nil
+
+	return nil
+}
+
+func (s *partitionedSink) Ack() <-chan SinkAck {
+	return s.ack
+}
+
+func (s *partitionedSink) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *partitionedSink) Input() chan<- *SinkMessage {
+	return s.input
+}
+
+// Close flushes and closes open files, it returns immediately if the sink stopped on error
+func (s *partitionedSink) Close() error {
+	select {
+	case s.quit <- struct{}{}:
+		<-s.done
+	case <-s.done:
+	}
+
+	logger.Debug("Partitioned sink close")
+
+	return nil
+}
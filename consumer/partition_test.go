@@ -0,0 +1,209 @@
+package consumer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func TestPartitionPath(t *testing.T) {
+	ast := assert.New(t)
+
+	var (
+		eventTime  = int64(1604300000000)
+		loggedTime = eventTime + 3600*1000
+		hour       = func(ts int64) string {
+			return filepath.Join("dt="+time.Unix(ts/1000, 0).UTC().Format("20060102"), "hour="+time.Unix(ts/1000, 0).UTC().Format("15"))
+		}
+		day = func(ts int64) string {
+			return "dt=" + time.Unix(ts/1000, 0).UTC().Format("20060102")
+		}
+		msg = &SinkMessage{
+			Topic: "event-log",
+			Event: &pb.EventLog{EventTime: eventTime, LoggedTime: loggedTime, AppType: "my/app"},
+		}
+	)
+
+	tests := []struct {
+		cfg    PartitionConfig
+		msg    *SinkMessage
+		expect string
+	}{
+		{PartitionConfig{}, msg, hour(eventTime)},
+		{PartitionConfig{TimeField: "logged_time"}, msg, hour(loggedTime)},
+		{PartitionConfig{Granularity: "day"}, msg, day(eventTime)},
+		{
+			PartitionConfig{By: "topic,app_type,event"},
+			msg,
+			filepath.Join("topic=event-log", "app_type=my_app", "event=unknown", hour(eventTime)),
+		},
+		// late event
+		{
+			PartitionConfig{MaxDelay: 600},
+			msg,
+			hour(loggedTime),
+		},
+		{
+			PartitionConfig{},
+			&SinkMessage{Event: &pb.EventLog{LoggedTime: loggedTime}},
+			hour(loggedTime),
+		},
+		// 2020-11-02 06:53:20 UTC
+		{PartitionConfig{Timezone: "UTC"}, msg, filepath.Join("dt=20201102", "hour=06")},
+		{PartitionConfig{Timezone: "Asia/Shanghai"}, msg, filepath.Join("dt=20201102", "hour=14")},
+	}
+
+	for i, test := range tests {
+		s := &partitionedSink{cfg: test.cfg}
+		s.maxDelay = int64(positive(test.cfg.MaxDelay, defaultPartitionMaxDelay)) * 1000
+		if test.cfg.Timezone != "" {
+			s.location, _ = time.LoadLocation(test.cfg.Timezone)
+		}
+		ast.Equal(test.expect, s.partition(test.msg), "case %d", i)
+	}
+
+	ast.Error((&PartitionConfig{TimeField: "now"}).Validate())
+	ast.Error((&PartitionConfig{Granularity: "minute"}).Validate())
+	ast.Error((&PartitionConfig{By: "topic,host"}).Validate())
+	ast.Error((&PartitionConfig{Timezone: "Mars/Olympus"}).Validate())
+	ast.NoError((&PartitionConfig{By: "topic, event"}).Validate())
+}
+
+func TestPartitionedSink(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-partition")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink, err := NewPartitionedSink(
+		dir,
+		".json",
+		MarshalerFunc(JSONMarshaler),
+		&PartitionConfig{By: "topic", FlushInterval: 1},
+	)
+	require.NoError(t, err)
+
+	eventTime := int64(1604300000000)
+	for i := 0; i < 3; i++ {
+		sink.Input() <- &SinkMessage{
+			Topic: fmt.Sprintf("topic-%d", i%2),
+			Ack:   &testAck{id: fmt.Sprint(i)},
+			Event: &pb.EventLog{EventId: fmt.Sprintf("event-%d", i), EventTime: eventTime, LoggedTime: eventTime},
+		}
+	}
+
+	// messages of all partitions are acked after flush
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("0", ack.(*testAck).id)
+		ast.Equal([]string{"1", "2"}, ack.(*testAck).ids)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no ack")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "topic=*", "dt=*", "hour=*", "part-*.json"))
+	require.Len(t, files, 2)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		ast.NoError(err)
+		ast.Contains(string(data), "event-")
+	}
+	sink.Close()
+}
+
+func TestPartitionedSinkClose(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-partition")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := &partitionedSink{
+		dir:          dir,
+		ext:          ".json",
+		m:            MarshalerFunc(JSONMarshaler),
+		cfg:          PartitionConfig{By: "event"},
+		host:         "localhost",
+		bufferSize:   4096,
+		maxDelay:     defaultPartitionMaxDelay * 1000,
+		maxOpenFiles: 2,
+		ack:          make(chan SinkAck, 1),
+		files:        make(map[string]*partitionFile),
+	}
+	write := func(event string) {
+		require.NoError(t, s.write(&SinkMessage{
+			Ack:   &testAck{id: event},
+			Event: &pb.EventLog{Event: event, EventTime: 1604300000000},
+		}))
+	}
+
+	write("a")
+	write("b")
+	ast.Len(s.files, 2)
+
+	// least recently written partition a is closed
+	write("c")
+	ast.Len(s.files, 2)
+	ast.Contains(s.files, s.partition(&SinkMessage{Event: &pb.EventLog{Event: "b", EventTime: 1604300000000}}))
+
+	// a new file is created when partition a is written again
+	write("a")
+	files, _ := filepath.Glob(filepath.Join(dir, "event=a", "*", "*", "part-*.json"))
+	ast.Len(files, 2)
+
+	// messages are not acked before flush
+	select {
+	case <-s.ack:
+		t.Fatal("unexpected ack")
+	default:
+	}
+	ast.NoError(s.flush())
+	ack := (<-s.ack).(*testAck)
+	ast.Equal("a", ack.id)
+	ast.Equal([]string{"b", "c", "a"}, ack.ids)
+
+	// idle partitions are closed
+	ast.NoError(s.closeIdle(time.Now().Add(time.Second)))
+	ast.Len(s.files, 0)
+	ast.NoError(s.flush())
+}
+
+func TestPartitionedSinkCloseAfterError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logserver-partition")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// partition directories can't be created under a file
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	sink, err := NewPartitionedSink(file, ".json", MarshalerFunc(JSONMarshaler), nil)
+	require.NoError(t, err)
+
+	sink.Input() <- &SinkMessage{Ack: &testAck{id: "0"}, Event: &pb.EventLog{EventId: "event-0"}}
+	select {
+	case err := <-sink.Errors():
+		assert.Error(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no error")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		sink.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close blocks after error")
+	}
+}
@@ -0,0 +1,376 @@
+package consumer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/logger"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	defaultS3Key      = "{topic}/dt={date}/hour={hour}/{host}-{ts}"
+	defaultS3FileSize = 128 << 20
+	defaultS3FileAge  = 300
+	defaultS3Retries  = 3
+)
+
+// S3Config defines the objects uploaded by s3 sink
+type S3Config struct {
+	// e.g. http://127.0.0.1:9000 of minio, empty means aws s3
+	Endpoint string `json:"endpoint"`
+	// default us-east-1
+	Region string `json:"region"`
+	// empty means aws default credential chain, e.g. env AWS_ACCESS_KEY_ID
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// bucket in path instead of host, it's required by minio in most cases
+	PathStyle bool `json:"path_style"`
+	// object key template, placeholders: {topic} {date} {hour} {host} {ts}.
+	// Time is when the file is created in local time, ts is in unixnano.
+	// Extension of marshaler is appended, e.g. .json or .json.gz
+	Key string `json:"key"`
+	// gzip objects
+	Gzip bool `json:"gzip"`
+	// bytes of multipart upload part, default 5MB
+	PartSize int64 `json:"part_size"`
+	// file is uploaded when it reaches the size in bytes before compression, default 128MB
+	FileSize int64 `json:"file_size"`
+	// file is uploaded after it's created for the seconds, default 300
+	FileAge int `json:"file_age"`
+	// max retries of failed requests, 0 means no retries.
+	// default 3 if the config is nil
+	Retries int `json:"retries"`
+	// dir of local files before upload, default os temp dir
+	TempDir string `json:"temp_dir"`
+}
+
+func (c *S3Config) Validate() error {
+	if c.PartSize != 0 && c.PartSize < s3manager.MinUploadPartSize {
+		return errors.Errorf("part_size is less than %d", s3manager.MinUploadPartSize)
+	}
+	if c.FileSize < 0 || c.FileAge < 0 || c.Retries < 0 {
+		return errors.New("negative value")
+	}
+
+	return nil
+}
+
+// s3File is the local file of topic that is uploaded when it's finished
+type s3File struct {
+	topic  string
+	file   *os.File
+	buf    *bufio.Writer
+	gz     *gzip.Writer
+	w      io.Writer
+	size   int64
+	openAt time.Time
+	ack    SinkAck
+}
+
+type s3Sink struct {
+	bucket   string
+	ext      string
+	m        Marshaler
+	cfg      S3Config
+	host     string
+	uploader *s3manager.Uploader
+
+	bufferSize int
+	fileSize   int64
+	fileAge    time.Duration
+
+	input  chan *SinkMessage
+	errors chan error
+	ack    chan SinkAck
+	quit   chan struct{}
+	// closed when run returns
+	done chan struct{}
+
+	// topic => local file
+	files map[string]*s3File
+}
+
+// NewS3Sink returns Sink that uploads marshaled messages to bucket of S3 compatible storage.
+// Messages of each topic are written to a local file, the file is uploaded by multipart upload
+// when it's finished by size or age. Offsets of the messages in a file are acked after the upload succeeds.
+func NewS3Sink(bucket, ext string, m Marshaler, cfg *S3Config, optList ...Option) (Sink, error) {
+	if bucket == "" {
+		return nil, errors.New("SinkTarget[s3] args[bucket] is missing")
+	}
+
+	s := &s3Sink{
+		bucket: bucket,
+		ext:    ext,
+		m:      m,
+		errors: make(chan error, 1),
+		ack:    make(chan SinkAck, 1),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+		files:  make(map[string]*s3File),
+	}
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, errors.Wrap(err, "SinkTarget[s3]")
+		}
+		s.cfg = *cfg
+	} else {
+		s.cfg.Retries = defaultS3Retries
+	}
+	if s.cfg.Region == "" {
+		s.cfg.Region = defaultS3Region
+	}
+	if s.cfg.Key == "" {
+		s.cfg.Key = defaultS3Key
+	}
+	if s.cfg.Gzip {
+		s.ext += ".gz"
+	}
+
+	opts := &options{
+		inputBufferSize:  100,
+		outputBufferSize: 4096,
+	}
+	for _, opt := range optList {
+		opt.apply(opts)
+	}
+	s.input = make(chan *SinkMessage, opts.inputBufferSize)
+	s.bufferSize = opts.outputBufferSize
+
+	s.fileSize = s.cfg.FileSize
+	if s.fileSize == 0 {
+		s.fileSize = defaultS3FileSize
+	}
+	s.fileAge = time.Duration(positive(s.cfg.FileAge, defaultS3FileAge)) * time.Second
+
+	awsCfg := &aws.Config{
+		Region:           aws.String(s.cfg.Region),
+		S3ForcePathStyle: aws.Bool(s.cfg.PathStyle),
+		MaxRetries:       aws.Int(s.cfg.Retries),
+	}
+	if s.cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(s.cfg.Endpoint)
+	}
+	if s.cfg.AccessKey != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentials(s.cfg.AccessKey, s.cfg.SecretKey, "")
+	}
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "SinkTarget[s3]")
+	}
+	s.uploader = s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		if s.cfg.PartSize > 0 {
+			u.PartSize = s.cfg.PartSize
+		}
+	})
+
+	if host, err := os.Hostname(); err == nil {
+		s.host = host
+	} else {
+		s.host = "localhost"
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+func (s *s3Sink) run() {
+	ticker := time.NewTicker(s.checkInterval())
+	defer func() {
+		ticker.Stop()
+		// files are left only if it stops on error, their messages are consumed again
+		s.discard()
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case msg := <-s.input:
+			if err := s.write(msg); err != nil {
+				s.errors <- err
+				return
+			}
+		case <-ticker.C:
+			for topic, f := range s.files {
+				if time.Since(f.openAt) < s.fileAge {
+					continue
+				}
+				if err := s.finish(topic); err != nil {
+					s.errors <- err
+					return
+				}
+			}
+		case <-s.quit:
+			for topic := range s.files {
+				if err := s.finish(topic); err != nil {
+					logger.Error("S3 sink close", "err", err)
+				}
+			}
+			return
+		}
+	}
+}
+
+func (s *s3Sink) checkInterval() time.Duration {
+	if interval := s.fileAge / 10; interval < time.Second {
+		return interval
+	}
+
+	return time.Second
+}
+
+func (s *s3Sink) write(msg *SinkMessage) error {
+	p, err := s.m.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	f, ok := s.files[msg.Topic]
+	if !ok {
+		if f, err = s.open(msg.Topic); err != nil {
+			return err
+		}
+	}
+	if _, err := f.w.Write(p); err != nil {
+		return errors.Wrap(err, "s3 file write")
+	}
+	f.size += int64(len(p))
+
+	if f.ack != nil {
+		f.ack = f.ack.Chain(msg.Ack)
+	} else {
+		f.ack = msg.Ack
+	}
+
+	if f.size >= s.fileSize {
+		return s.finish(msg.Topic)
+	}
+
+	return nil
+}
+
+func (s *s3Sink) open(topic string) (*s3File, error) {
+	file, err := ioutil.TempFile(s.cfg.TempDir, "logserver-s3-")
+	if err != nil {
+		return nil, errors.Wrap(err, "s3 file open")
+	}
+
+	f := &s3File{
+		topic:  topic,
+		file:   file,
+		buf:    bufio.NewWriterSize(file, s.bufferSize),
+		openAt: time.Now(),
+	}
+	f.w = f.buf
+	if s.cfg.Gzip {
+		f.gz = gzip.NewWriter(f.buf)
+		f.w = f.gz
+	}
+	s.files[topic] = f
+
+	logger.Debug("S3 file open", "topic", topic, "file", file.Name())
+
+	return f, nil
+}
+
+// key returns object key of the file
+func (s *s3Sink) key(f *s3File) string {
+	r := strings.NewReplacer(
+		"{topic}", partitionValue(f.topic),
+		"{date}", f.openAt.Format("20060102"),
+		"{hour}", f.openAt.Format("15"),
+		"{host}", s.host,
+		"{ts}", fmt.Sprint(f.openAt.UnixNano()),
+	)
+
+	return strings.TrimPrefix(r.Replace(s.cfg.Key), "/") + s.ext
+}
+
+// finish uploads the file of topic and removes it, then acks the messages in the file.
+// The messages are consumed again if the upload fails, because their offsets are not marked.
+func (s *s3Sink) finish(topic string) error {
+	f := s.files[topic]
+	delete(s.files, topic)
+	defer func() {
+		f.file.Close()
+		os.Remove(f.file.Name())
+	}()
+
+	var err error
+	if f.gz != nil {
+		err = f.gz.Close()
+	}
+	if err == nil {
+		err = f.buf.Flush()
+	}
+	if err == nil {
+		_, err = f.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "s3 file finish %s", f.file.Name())
+	}
+
+	key := s.key(f)
+	result, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   f.file,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "s3 upload %s", key)
+	}
+	logger.Debug("S3 file uploaded", "location", result.Location)
+
+	if f.ack != nil {
+		s.ack <- f.ack
+	}
+
+	return nil
+}
+
+// discard removes the local files that are not uploaded
+func (s *s3Sink) discard() {
+	for topic, f := range s.files {
+		delete(s.files, topic)
+		f.file.Close()
+		os.Remove(f.file.Name())
+	}
+}
+
+func (s *s3Sink) Ack() <-chan SinkAck {
+	return s.ack
+}
+
+func (s *s3Sink) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *s3Sink) Input() chan<- *SinkMessage {
+	return s.input
+}
+
+// Close uploads the local files, it returns immediately if the sink stopped on error
+func (s *s3Sink) Close() error {
+	select {
+	case s.quit <- struct{}{}:
+		<-s.done
+	case <-s.done:
+	}
+
+	logger.Debug("S3 sink close")
+
+	return nil
+}
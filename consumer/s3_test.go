@@ -0,0 +1,266 @@
+package consumer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// testS3 is a minimal S3 compatible server in path style
+type testS3 struct {
+	sync.Mutex
+	// requests that fail with 500
+	failures int
+	objects  map[string][]byte
+	parts    map[string]map[int][]byte
+	// multipart uploads completed
+	multiparts int
+}
+
+func newTestS3() *testS3 {
+	return &testS3{
+		objects: make(map[string][]byte),
+		parts:   make(map[string]map[int][]byte),
+	}
+}
+
+func (s *testS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		key        = strings.TrimPrefix(r.URL.Path, "/")
+		query      = r.URL.Query()
+		uploadID   = query.Get("uploadId")
+		_, uploads = query["uploads"]
+	)
+	switch {
+	case r.Method == http.MethodPost && uploads:
+		s.parts[key] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key)
+	case r.Method == http.MethodPut && uploadID != "":
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.parts[uploadID][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+	case r.Method == http.MethodPost && uploadID != "":
+		numbers := make([]int, 0)
+		for n := range s.parts[uploadID] {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		object := make([]byte, 0)
+		for _, n := range numbers {
+			object = append(object, s.parts[uploadID][n]...)
+		}
+		s.objects[key] = object
+		s.multiparts++
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Key>%s</Key><ETag>\"1\"</ETag></CompleteMultipartUploadResult>", key)
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(s.parts, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		w.Header().Set("ETag", `"1"`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newTestS3Sink(t *testing.T, server *httptest.Server, cfg *S3Config) Sink {
+	cfg.Endpoint = server.URL
+	cfg.AccessKey = "key"
+	cfg.SecretKey = "secret"
+	cfg.PathStyle = true
+	cfg.TempDir = t.TempDir()
+
+	sink, err := NewS3Sink("logs", ".json", MarshalerFunc(JSONMarshaler), cfg)
+	require.NoError(t, err)
+
+	return sink
+}
+
+func TestS3Sink(t *testing.T) {
+	ast := assert.New(t)
+
+	s3 := newTestS3()
+	s3.failures = 1
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	sink := newTestS3Sink(t, server, &S3Config{
+		Key:      "events/{topic}/{date}/{hour}/{ts}",
+		Gzip:     true,
+		FileSize: 1,
+		Retries:  1,
+	})
+
+	now := time.Now()
+	sink.Input() <- &SinkMessage{
+		Topic: "event-log",
+		Ack:   &testAck{id: "0"},
+		Event: &pb.EventLog{EventId: "event-0"},
+	}
+	// file is uploaded by size for every message
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("0", ack.(*testAck).id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no ack")
+	}
+	sink.Close()
+
+	s3.Lock()
+	defer s3.Unlock()
+	require.Len(t, s3.objects, 1)
+	for key, object := range s3.objects {
+		prefix := fmt.Sprintf("logs/events/event-log/%s/%s/", now.Format("20060102"), now.Format("15"))
+		ast.True(strings.HasPrefix(key, prefix), key)
+		ast.True(strings.HasSuffix(key, ".json.gz"), key)
+
+		r, err := gzip.NewReader(bytes.NewReader(object))
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		ast.Contains(string(data), "event-0")
+	}
+}
+
+func TestS3SinkMultipart(t *testing.T) {
+	ast := assert.New(t)
+
+	s3 := newTestS3()
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	sink := newTestS3Sink(t, server, &S3Config{FileAge: 1})
+
+	value := strings.Repeat("v", 1<<20)
+	for i := 0; i < 6; i++ {
+		sink.Input() <- &SinkMessage{
+			Topic: "event-log",
+			Ack:   &testAck{id: fmt.Sprint(i)},
+			Event: &pb.EventLog{EventId: fmt.Sprintf("event-%d", i), ExtendInfo: map[string]string{"k": value}},
+		}
+	}
+
+	// file is uploaded by age
+	select {
+	case ack := <-sink.Ack():
+		ast.Equal("0", ack.(*testAck).id)
+		ast.Equal([]string{"1", "2", "3", "4", "5"}, ack.(*testAck).ids)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ack")
+	}
+	sink.Close()
+
+	s3.Lock()
+	defer s3.Unlock()
+	ast.Equal(1, s3.multiparts)
+	require.Len(t, s3.objects, 1)
+	for _, object := range s3.objects {
+		ast.Equal(6, bytes.Count(object, []byte("\n")))
+		ast.Greater(len(object), 6<<20)
+	}
+}
+
+func TestS3SinkFailure(t *testing.T) {
+	ast := assert.New(t)
+
+	s3 := newTestS3()
+	s3.failures = 100
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	cfg := &S3Config{FileSize: 100, Retries: 1}
+	sink := newTestS3Sink(t, server, cfg)
+	// file of topic a is not finished
+	sink.Input() <- &SinkMessage{
+		Topic: "a",
+		Ack:   &testAck{id: "0"},
+		Event: &pb.EventLog{EventId: "event-0"},
+	}
+	sink.Input() <- &SinkMessage{
+		Topic: "b",
+		Ack:   &testAck{id: "1"},
+		Event: &pb.EventLog{EventId: "event-1", ExtendInfo: map[string]string{"k": strings.Repeat("v", 100)}},
+	}
+
+	// messages are not acked if upload fails
+	select {
+	case err := <-sink.Errors():
+		ast.Error(err)
+	case <-sink.Ack():
+		t.Fatal("unexpected ack")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error")
+	}
+	s3.Lock()
+	ast.Equal(98, s3.failures)
+	s3.Unlock()
+
+	closed := make(chan struct{})
+	go func() {
+		sink.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close blocks after error")
+	}
+
+	// local files of all topics are removed
+	files, _ := ioutil.ReadDir(cfg.TempDir)
+	ast.Empty(files)
+}
+
+func TestS3SinkNoRetries(t *testing.T) {
+	ast := assert.New(t)
+
+	s3 := newTestS3()
+	s3.failures = 1
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	sink := newTestS3Sink(t, server, &S3Config{FileSize: 100, Retries: 0})
+	defer sink.Close()
+	sink.Input() <- &SinkMessage{
+		Topic: "a",
+		Ack:   &testAck{id: "0"},
+		Event: &pb.EventLog{EventId: "event-0", ExtendInfo: map[string]string{"k": strings.Repeat("v", 100)}},
+	}
+
+	// the failed request isn't retried
+	select {
+	case err := <-sink.Errors():
+		ast.Error(err)
+	case <-sink.Ack():
+		t.Fatal("unexpected ack")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error")
+	}
+	s3.Lock()
+	ast.Empty(s3.objects)
+	s3.Unlock()
+}
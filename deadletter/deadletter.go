@@ -0,0 +1,54 @@
+// Package deadletter defines the record of rejected payloads, which are kept for inspection and reprocessing.
+package deadletter
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Reason codes
+const (
+	// event fails validation
+	ReasonInvalid = "invalid"
+	// no topic routes the event
+	ReasonUnroutable = "unroutable"
+	// consumer can't unmarshal the kafka message
+	ReasonUnmarshal = "unmarshal"
+)
+
+// Letter is a rejected payload
+type Letter struct {
+	Reason string `json:"reason"`
+	// errors.LError code if any
+	Code int16 `json:"code,omitempty"`
+	// error message
+	Error    string `json:"error,omitempty"`
+	SourceIP string `json:"source_ip,omitempty"`
+	// unix timestamp in milliseconds when it's rejected
+	Timestamp int64 `json:"timestamp"`
+	// kafka message position, consumer only
+	Topic     string `json:"topic,omitempty"`
+	Partition int32  `json:"partition,omitempty"`
+	Offset    int64  `json:"offset,omitempty"`
+	// original bytes, base64 encoded in json
+	Data []byte `json:"data"`
+}
+
+// New returns letter of data rejected for reason
+func New(reason string, data []byte, err error) *Letter {
+	l := &Letter{
+		Reason:    reason,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Data:      data,
+	}
+	if err != nil {
+		l.Error = err.Error()
+	}
+
+	return l
+}
+
+// Marshal implements storage.Marshaler, encodes letter as json
+func (l *Letter) Marshal() ([]byte, error) {
+	return json.Marshal(l)
+}
@@ -0,0 +1,26 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLetter(t *testing.T) {
+	ast := assert.New(t)
+
+	l := New(ReasonUnmarshal, []byte{0x0a, 0xff}, errors.New("bad wire type"))
+	l.Topic, l.Partition, l.Offset = "event-log", 1, 100
+
+	data, err := l.Marshal()
+	ast.NoError(err)
+
+	var decoded Letter
+	ast.NoError(json.Unmarshal(data, &decoded))
+	ast.Equal(*l, decoded)
+	ast.Equal("bad wire type", decoded.Error)
+	ast.Equal([]byte{0x0a, 0xff}, decoded.Data)
+	ast.NotZero(decoded.Timestamp)
+}
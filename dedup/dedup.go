@@ -0,0 +1,107 @@
+// Package dedup provides a time windowed set of keys with bounded memory, which drops events delivered more than once.
+package dedup
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const _shards = 64
+
+// Set remembers keys for the window, sharded to reduce lock contention.
+//
+// Each shard keeps two generations of keys. The current generation becomes the previous one
+// when it's older than the window or reaches its capacity, so keys are remembered for at least
+// the window unless the memory budget is exceeded.
+type Set struct {
+	window time.Duration
+	shards [_shards]*shard
+
+	// for test
+	now func() time.Time
+}
+
+type shard struct {
+	mu       sync.Mutex
+	capacity int
+	start    time.Time
+	// key => unix nano time when the key is added
+	cur  map[string]int64
+	prev map[string]int64
+}
+
+// New returns set that remembers keys for window, it keeps at most maxKeys keys
+func New(window time.Duration, maxKeys int) *Set {
+	capacity := maxKeys / _shards / 2
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	s := &Set{
+		window: window,
+		now:    time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			capacity: capacity,
+			cur:      make(map[string]int64),
+		}
+	}
+
+	return s
+}
+
+// Seen reports whether key is added in the window, and adds it if not
+func (s *Set) Seen(key string) bool {
+	now := s.now()
+	sh := s.shard(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if t, ok := sh.cur[key]; ok && now.UnixNano()-t < int64(s.window) {
+		return true
+	}
+	if t, ok := sh.prev[key]; ok && now.UnixNano()-t < int64(s.window) {
+		return true
+	}
+
+	if len(sh.cur) >= sh.capacity || now.Sub(sh.start) >= s.window {
+		sh.prev, sh.cur = sh.cur, make(map[string]int64, len(sh.cur))
+		sh.start = now
+	}
+	sh.cur[key] = now.UnixNano()
+
+	return false
+}
+
+// Forget removes key, e.g. the event is not delivered and will be retried
+func (s *Set) Forget(key string) {
+	sh := s.shard(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.cur, key)
+	delete(sh.prev, key)
+}
+
+// Len returns number of keys kept in memory, including expired ones that are not dropped yet
+func (s *Set) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		n += len(sh.cur) + len(sh.prev)
+		sh.mu.Unlock()
+	}
+
+	return n
+}
+
+func (s *Set) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return s.shards[h.Sum32()%_shards]
+}
@@ -0,0 +1,47 @@
+package dedup
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now()
+	s := New(time.Minute, 1000)
+	s.now = func() time.Time { return now }
+
+	ast.False(s.Seen("a"))
+	ast.True(s.Seen("a"))
+	ast.False(s.Seen("b"))
+
+	s.Forget("b")
+	ast.False(s.Seen("b"))
+
+	// still in the window after rotation
+	now = now.Add(50 * time.Second)
+	ast.False(s.Seen("c"))
+	now = now.Add(20 * time.Second)
+	ast.False(s.Seen("d"))
+	ast.True(s.Seen("c"))
+
+	// expired
+	ast.False(s.Seen("a"))
+}
+
+func TestSetMaxKeys(t *testing.T) {
+	ast := assert.New(t)
+
+	s := New(time.Hour, _shards*2*10)
+	for i := 0; i < 10000; i++ {
+		s.Seen(strconv.Itoa(i))
+	}
+	ast.True(s.Len() <= _shards*2*10)
+
+	// recent keys are remembered
+	ast.True(s.Seen("9999"))
+}
@@ -2,22 +2,34 @@ package errors
 
 import (
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
 )
 
 var (
+	New   = errors.New
 	Wrap  = errors.Wrap
 	Wrapf = errors.Wrapf
+	Cause = errors.Cause
 )
 
 type LError int16
 
 const (
-	ErrNoError       LError = 0
-	ErrUnknown       LError = -1
-	ErrFieldRequired LError = 1
-	ErrFieldInvalid  LError = 2
+	ErrNoError         LError = 0
+	ErrUnknown         LError = -1
+	ErrFieldRequired   LError = 1
+	ErrFieldInvalid    LError = 2
+	ErrDeliveryFailed  LError = 3
+	ErrDeliveryTimeout LError = 4
+	ErrUnauthorized    LError = 5
+	ErrAppTypeDenied   LError = 6
+	ErrRateLimited     LError = 7
 )
 
 func (err LError) Error() string {
@@ -30,6 +42,16 @@ func (err LError) Error() string {
 		return "Field value is required."
 	case ErrFieldInvalid:
 		return "Field value is not valid."
+	case ErrDeliveryFailed:
+		return "Storage delivery failed, retry later."
+	case ErrDeliveryTimeout:
+		return "Storage delivery timeout, retry later."
+	case ErrUnauthorized:
+		return "Request is not authenticated."
+	case ErrAppTypeDenied:
+		return "App type is not allowed for the api key."
+	case ErrRateLimited:
+		return "Too many requests, retry later."
 	}
 
 	return fmt.Sprintf("Unknow error. Error code = %d", err)
@@ -38,3 +60,86 @@ func (err LError) Error() string {
 func (err LError) ErrorCode() int16 {
 	return int16(err)
 }
+
+// Retryable reports whether the client should retry the request
+func (err LError) Retryable() bool {
+	switch err {
+	case ErrDeliveryFailed, ErrDeliveryTimeout:
+		return true
+	}
+	return false
+}
+
+// StatusCode implements go-kit http transport StatusCoder
+func (err LError) StatusCode() int {
+	if err.Retryable() {
+		return http.StatusServiceUnavailable
+	}
+	switch err {
+	case ErrUnauthorized:
+		return http.StatusUnauthorized
+	case ErrAppTypeDenied:
+		return http.StatusForbidden
+	case ErrRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// Headers implements go-kit http transport Headerer
+func (err LError) Headers() http.Header {
+	if err.Retryable() || err == ErrRateLimited {
+		return http.Header{"Retry-After": []string{"1"}}
+	}
+	return nil
+}
+
+// GRPCCode returns gRPC status code of the error
+func (err LError) GRPCCode() codes.Code {
+	switch err {
+	case ErrFieldRequired, ErrFieldInvalid:
+		return codes.InvalidArgument
+	case ErrDeliveryFailed:
+		return codes.Unavailable
+	case ErrDeliveryTimeout:
+		return codes.DeadlineExceeded
+	case ErrUnauthorized:
+		return codes.Unauthenticated
+	case ErrAppTypeDenied:
+		return codes.PermissionDenied
+	case ErrRateLimited:
+		return codes.ResourceExhausted
+	}
+	return codes.Unknown
+}
+
+// RetryAfter annotates err with the time that clients should wait before retrying
+func RetryAfter(err error, d time.Duration) error {
+	return &retryAfterError{err, d}
+}
+
+type retryAfterError struct {
+	error
+	retryAfter time.Duration
+}
+
+func (e *retryAfterError) Cause() error {
+	return e.error
+}
+
+// Headers implements go-kit http transport Headerer
+func (e *retryAfterError) Headers() http.Header {
+	secs := int64(math.Ceil(e.retryAfter.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return http.Header{"Retry-After": []string{strconv.FormatInt(secs, 10)}}
+}
+
+// StatusCode implements go-kit http transport StatusCoder
+func (e *retryAfterError) StatusCode() int {
+	if coder, ok := errors.Cause(e.error).(interface{ StatusCode() int }); ok {
+		return coder.StatusCode()
+	}
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,162 @@
+package eventlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/techxmind/logserver/config"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// Enricher fills properties of event logs.
+// Event logs of a request are passed together, common properties are merged by the common stage.
+type Enricher interface {
+	Enrich(ctx context.Context, common *pb.EventLogCommon, logs []*pb.EventLog)
+}
+
+type EnricherFunc func(context.Context, *pb.EventLogCommon, []*pb.EventLog)
+
+func (f EnricherFunc) Enrich(ctx context.Context, common *pb.EventLogCommon, logs []*pb.EventLog) {
+	f(ctx, common, logs)
+}
+
+// DefaultStages is the enrichment stages used when it's not configured
+var DefaultStages = []string{"udid", "common", "geoip", "useragent", "resolution"}
+
+var (
+	_enrichers    = make(map[string]func(string) (Enricher, error))
+	_defaultChain *Chain
+)
+
+func init() {
+	RegisterEnricher("common", func(_ string) (Enricher, error) {
+		return EnricherFunc(enrichCommon), nil
+	})
+	RegisterEnricher("geoip", func(_ string) (Enricher, error) {
+		return EnricherFunc(enrichGeoIP), nil
+	})
+	RegisterEnricher("useragent", func(_ string) (Enricher, error) {
+		return EnricherFunc(enrichUserAgent), nil
+	})
+	RegisterEnricher("udid", func(_ string) (Enricher, error) {
+		return EnricherFunc(enrichUdid), nil
+	})
+	RegisterEnricher("resolution", func(_ string) (Enricher, error) {
+		return EnricherFunc(enrichResolution), nil
+	})
+
+	chain, err := NewChain(nil)
+	if err != nil {
+		panic(err)
+	}
+	_defaultChain = chain
+}
+
+// RegisterEnricher registers enricher factory, factory receives args of the stage configuration.
+// It's not thread-safe, register enrichers in init.
+func RegisterEnricher(name string, factory func(string) (Enricher, error)) {
+	_enrichers[name] = factory
+}
+
+func GetEnricher(name, args string) (Enricher, error) {
+	factory, ok := _enrichers[name]
+	if !ok {
+		return nil, fmt.Errorf("Enricher[%s] is not registered", name)
+	}
+
+	return factory(args)
+}
+
+// Chain is ordered enrichment stages
+type Chain struct {
+	stages []*stage
+}
+
+type stage struct {
+	name     string
+	enricher Enricher
+	// nil means all app types
+	appTypes map[string]bool
+	exclude  map[string]bool
+}
+
+// NewChain returns chain of configured stages, or default stages if cfg is nil
+func NewChain(cfg *config.EnrichConfig) (*Chain, error) {
+	if cfg == nil {
+		cfg = &config.EnrichConfig{}
+		for _, name := range DefaultStages {
+			cfg.Stages = append(cfg.Stages, &config.EnrichStage{Name: name})
+		}
+	}
+
+	chain := &Chain{
+		stages: make([]*stage, 0, len(cfg.Stages)),
+	}
+	for _, stageCfg := range cfg.Stages {
+		enricher, err := GetEnricher(stageCfg.Name, stageCfg.Args)
+		if err != nil {
+			return nil, err
+		}
+		chain.stages = append(chain.stages, &stage{
+			name:     stageCfg.Name,
+			enricher: enricher,
+			appTypes: stringSet(stageCfg.AppTypes),
+			exclude:  stringSet(stageCfg.ExcludeAppTypes),
+		})
+	}
+
+	return chain, nil
+}
+
+// Fill fills system properties, then runs the stages in order
+func (c *Chain) Fill(ctx context.Context, common *pb.EventLogCommon, logs []*pb.EventLog) {
+	fillSystem(ctx, logs)
+
+	for _, s := range c.stages {
+		if matched := s.filter(common, logs); len(matched) > 0 {
+			s.enricher.Enrich(ctx, common, matched)
+		}
+	}
+}
+
+// FillSingle wrapper Fill func for single log case
+func (c *Chain) FillSingle(ctx context.Context, log *pb.EventLog) {
+	c.Fill(ctx, nil, []*pb.EventLog{log})
+}
+
+// filter returns logs that the stage applies to
+func (s *stage) filter(common *pb.EventLogCommon, logs []*pb.EventLog) []*pb.EventLog {
+	if s.appTypes == nil && s.exclude == nil {
+		return logs
+	}
+
+	matched := make([]*pb.EventLog, 0, len(logs))
+	for _, log := range logs {
+		appType := log.AppType
+		if appType == "" && common != nil {
+			appType = common.AppType
+		}
+		if s.appTypes != nil && !s.appTypes[appType] {
+			continue
+		}
+		if s.exclude[appType] {
+			continue
+		}
+		matched = append(matched, log)
+	}
+
+	return matched
+}
+
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
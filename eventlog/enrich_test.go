@@ -0,0 +1,88 @@
+package eventlog
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/techxmind/logserver/config"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func TestEnrichChain(t *testing.T) {
+	ast := assert.New(t)
+
+	// appends args to extend_info.stages of each log
+	RegisterEnricher("test_mark", func(args string) (Enricher, error) {
+		return EnricherFunc(func(_ context.Context, _ *pb.EventLogCommon, logs []*pb.EventLog) {
+			for _, log := range logs {
+				if log.ExtendInfo == nil {
+					log.ExtendInfo = make(map[string]string)
+				}
+				log.ExtendInfo["stages"] += args
+			}
+		}), nil
+	})
+	defer delete(_enrichers, "test_mark")
+
+	chain, err := NewChain(&config.EnrichConfig{
+		Stages: []*config.EnrichStage{
+			{Name: "test_mark", Args: "a"},
+			{Name: "common"},
+			{Name: "test_mark", Args: "b", AppTypes: []string{"myapp"}},
+			{Name: "test_mark", Args: "c", ExcludeAppTypes: []string{"myapp"}},
+		},
+	})
+	ast.NoError(err)
+
+	ctx := context.WithValue(context.Background(), "remote-ip", "127.0.0.1")
+	common := &pb.EventLogCommon{AppType: "myapp", Udid: "u1"}
+	logs := []*pb.EventLog{
+		{},
+		{AppType: "other"},
+	}
+	chain.Fill(ctx, common, logs)
+
+	// system properties are always filled
+	ast.Equal("127.0.0.1", logs[0].Ip)
+	ast.NotZero(logs[0].LoggedTime)
+	ast.Equal("u1", logs[0].Udid)
+	ast.Equal("ab", logs[0].ExtendInfo["stages"])
+	ast.Equal("ac", logs[1].ExtendInfo["stages"])
+	// stages not configured are skipped
+	ast.Equal("", logs[0].Os)
+
+	_, err = NewChain(&config.EnrichConfig{
+		Stages: []*config.EnrichStage{{Name: "unknown"}},
+	})
+	ast.Error(err)
+	ast.True(strings.Contains(err.Error(), "unknown"))
+}
+
+func TestFillClockSkew(t *testing.T) {
+	ast := assert.New(t)
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	// client clock is 1 hour behind
+	ctx := context.WithValue(context.Background(), "x-send-time", strconv.FormatInt(now-3600000, 10))
+	logs := []*pb.EventLog{
+		{EventTime: now - 3600000 - 1000},
+		{EventTimeFlag: "future"},
+	}
+	Fill(ctx, nil, logs)
+
+	ast.InDelta(3600000, logs[0].ClockSkew, 1000)
+	ast.InDelta(now-1000, logs[0].CorrectedEventTime, 1000)
+	ast.Equal(int64(0), logs[1].CorrectedEventTime)
+	ast.Equal("", logs[1].EventTimeFlag)
+
+	// no send time
+	logs = []*pb.EventLog{{EventTime: now}}
+	Fill(context.Background(), nil, logs)
+	ast.Equal(int64(0), logs[0].ClockSkew)
+	ast.Equal(now, logs[0].CorrectedEventTime)
+}
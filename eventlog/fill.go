@@ -3,6 +3,7 @@ package eventlog
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,154 +12,214 @@ import (
 	pb "github.com/techxmind/logserver/interface-defs"
 )
 
-// Fill system and common property into EventLog
+// Fill system and common property into EventLog with the default enrichment stages
 //
 func Fill(ctx context.Context, common *pb.EventLogCommon, logs []*pb.EventLog) {
+	_defaultChain.Fill(ctx, common, logs)
+}
+
+// fillSystem fills the properties that server gets from request.
+// event_time is corrected by the client clock skew if the client sends its send time in x-send-time.
+// The properties parsed from user agent are cleared, they're never taken from the client.
+func fillSystem(ctx context.Context, logs []*pb.EventLog) {
 	var (
 		loggedTime = time.Now().UnixNano() / int64(1e6)
 		ua         = getStringValueFromCtx(ctx, "user-agent")
 		ip         = getStringValueFromCtx(ctx, "remote-ip")
 		referer    = getStringValueFromCtx(ctx, "referer")
-		ipCountry  string
-		ipProvince string
-		ipCity     string
+		skew       int64
 	)
 
-	if ip != "" {
-		loc, err := ip2location.Get(ip)
-		if err == nil {
-			ipCountry = loc.Country
-			ipProvince = loc.Province
-			ipCity = loc.City
-		}
-	}
-
-	// strip base64 tail padding(=)
-	if common != nil && common.Udid != "" && strings.HasSuffix(common.Udid, "=") {
-		common.Udid = strings.TrimRight(common.Udid, "=")
+	if sendTime, err := strconv.ParseInt(getStringValueFromCtx(ctx, "x-send-time"), 10, 64); err == nil && sendTime > 0 {
+		skew = loggedTime - sendTime
 	}
 
 	for _, log := range logs {
 		log.LoggedTime = loggedTime
 		log.UserAgent = ua
 		log.Ip = ip
-		log.IpCountry = ipCountry
-		log.IpProvince = ipProvince
-		log.IpCity = ipCity
 		log.Referer = referer
+		log.ClockSkew = skew
+		log.CorrectedEventTime = 0
+		if log.EventTime > 0 {
+			log.CorrectedEventTime = log.EventTime + skew
+		}
+		log.EventTimeFlag = ""
+		log.Browser, log.BrowserVersion = "", ""
+		log.DeviceType = ""
+		log.Engine = ""
+	}
+}
 
-		// strip base64 tail padding(=)
-		if log.Udid != "" && strings.HasSuffix(log.Udid, "=") {
-			log.Udid = strings.TrimRight(log.Udid, "=")
-		}
+// enrichCommon fills empty properties of event log with common properties
+func enrichCommon(_ context.Context, common *pb.EventLogCommon, logs []*pb.EventLog) {
+	if common == nil {
+		return
+	}
 
+	for _, log := range logs {
 		// TPL.EVENT_LOG_FILL.START
-		if log.AndroidId == "" && common != nil && common.AndroidId != "" {
+		if log.AndroidId == "" && common.AndroidId != "" {
 			log.AndroidId = common.AndroidId
 		}
 
-		if log.AppChannel == "" && common != nil && common.AppChannel != "" {
+		if log.AppChannel == "" && common.AppChannel != "" {
 			log.AppChannel = common.AppChannel
 		}
 
-		if log.AppType == "" && common != nil && common.AppType != "" {
+		if log.AppType == "" && common.AppType != "" {
 			log.AppType = common.AppType
 		}
 
-		if log.AppVersion == "" && common != nil && common.AppVersion != "" {
+		if log.AppVersion == "" && common.AppVersion != "" {
 			log.AppVersion = common.AppVersion
 		}
 
-		if log.Carrier == 0 && common != nil && common.Carrier != 0 {
+		if log.Carrier == 0 && common.Carrier != 0 {
 			log.Carrier = common.Carrier
 		}
 
-		if log.DeviceBrand == "" && common != nil && common.DeviceBrand != "" {
+		if log.DeviceBrand == "" && common.DeviceBrand != "" {
 			log.DeviceBrand = common.DeviceBrand
 		}
 
-		if log.DeviceModel == "" && common != nil && common.DeviceModel != "" {
+		if log.DeviceModel == "" && common.DeviceModel != "" {
 			log.DeviceModel = common.DeviceModel
 		}
 
-		if log.DeviceVendor == "" && common != nil && common.DeviceVendor != "" {
+		if log.DeviceVendor == "" && common.DeviceVendor != "" {
 			log.DeviceVendor = common.DeviceVendor
 		}
 
-		if log.Env == "" && common != nil && common.Env != "" {
+		if log.Env == "" && common.Env != "" {
 			log.Env = common.Env
 		}
 
-		if log.Idfa == "" && common != nil && common.Idfa != "" {
+		if log.Idfa == "" && common.Idfa != "" {
 			log.Idfa = common.Idfa
 		}
 
-		if log.Imei == "" && common != nil && common.Imei != "" {
+		if log.Imei == "" && common.Imei != "" {
 			log.Imei = common.Imei
 		}
 
-		if log.Lat == "" && common != nil && common.Lat != "" {
+		if log.Lat == "" && common.Lat != "" {
 			log.Lat = common.Lat
 		}
 
-		if log.Lon == "" && common != nil && common.Lon != "" {
+		if log.Lon == "" && common.Lon != "" {
 			log.Lon = common.Lon
 		}
 
-		if log.Mac == "" && common != nil && common.Mac != "" {
+		if log.Mac == "" && common.Mac != "" {
 			log.Mac = common.Mac
 		}
 
-		if log.Mid == "" && common != nil && common.Mid != "" {
+		if log.Mid == "" && common.Mid != "" {
 			log.Mid = common.Mid
 		}
 
-		if log.Network == 0 && common != nil && common.Network != 0 {
+		if log.Network == 0 && common.Network != 0 {
 			log.Network = common.Network
 		}
 
-		if log.Oaid == "" && common != nil && common.Oaid != "" {
+		if log.Oaid == "" && common.Oaid != "" {
 			log.Oaid = common.Oaid
 		}
 
-		if log.Os == "" && common != nil && common.Os != "" {
+		if log.Os == "" && common.Os != "" {
 			log.Os = common.Os
 		}
 
-		if log.OsVersion == "" && common != nil && common.OsVersion != "" {
+		if log.OsVersion == "" && common.OsVersion != "" {
 			log.OsVersion = common.OsVersion
 		}
 
-		if log.Platform == "" && common != nil && common.Platform != "" {
+		if log.Platform == "" && common.Platform != "" {
 			log.Platform = common.Platform
 		}
 
-		if log.ScreenHeight == 0 && common != nil && common.ScreenHeight != 0 {
+		if log.ScreenHeight == 0 && common.ScreenHeight != 0 {
 			log.ScreenHeight = common.ScreenHeight
 		}
 
-		if log.ScreenResolution == "" && common != nil && common.ScreenResolution != "" {
+		if log.ScreenResolution == "" && common.ScreenResolution != "" {
 			log.ScreenResolution = common.ScreenResolution
 		}
 
-		if log.ScreenSize == "" && common != nil && common.ScreenSize != "" {
+		if log.ScreenSize == "" && common.ScreenSize != "" {
 			log.ScreenSize = common.ScreenSize
 		}
 
-		if log.ScreenWidth == 0 && common != nil && common.ScreenWidth != 0 {
+		if log.ScreenWidth == 0 && common.ScreenWidth != 0 {
 			log.ScreenWidth = common.ScreenWidth
 		}
 
-		if log.Tkid == "" && common != nil && common.Tkid != "" {
+		if log.Tkid == "" && common.Tkid != "" {
 			log.Tkid = common.Tkid
 		}
 
-		if log.Udid == "" && common != nil && common.Udid != "" {
+		if log.Udid == "" && common.Udid != "" {
 			log.Udid = common.Udid
 		}
 
 		// TPL.EVENT_LOG_FILL.END
+	}
+}
+
+// MergeCommon fills empty properties of event logs with common properties, nothing else is filled
+func MergeCommon(common *pb.EventLogCommon, logs []*pb.EventLog) {
+	enrichCommon(context.Background(), common, logs)
+}
+
+// enrichGeoIP fills ip location
+func enrichGeoIP(_ context.Context, _ *pb.EventLogCommon, logs []*pb.EventLog) {
+	var (
+		lastIP string
+		loc    ip2location.Location
+	)
 
+	for i, log := range logs {
+		// logs of a request share the same ip in most cases
+		if i == 0 || log.Ip != lastIP {
+			lastIP, loc = log.Ip, ip2location.Location{}
+			if log.Ip != "" {
+				if l, err := ip2location.Get(log.Ip); err == nil {
+					loc = *l
+				}
+			}
+		}
+		log.IpCountry = loc.Country
+		log.IpProvince = loc.Province
+		log.IpCity = loc.City
+	}
+}
+
+// enrichUserAgent fills properties parsed from user agent
+func enrichUserAgent(_ context.Context, _ *pb.EventLogCommon, logs []*pb.EventLog) {
+	for _, log := range logs {
+		if ua := ParseUserAgent(log.UserAgent); ua != nil {
+			ua.Fill(log)
+		}
+	}
+}
+
+// enrichUdid strips base64 tail padding(=) of udid
+func enrichUdid(_ context.Context, common *pb.EventLogCommon, logs []*pb.EventLog) {
+	if common != nil && common.Udid != "" && strings.HasSuffix(common.Udid, "=") {
+		common.Udid = strings.TrimRight(common.Udid, "=")
+	}
+
+	for _, log := range logs {
+		if log.Udid != "" && strings.HasSuffix(log.Udid, "=") {
+			log.Udid = strings.TrimRight(log.Udid, "=")
+		}
+	}
+}
+
+// enrichResolution fills screen resolution with screen width and height
+func enrichResolution(_ context.Context, _ *pb.EventLogCommon, logs []*pb.EventLog) {
+	for _, log := range logs {
 		if log.ScreenResolution == "" && log.ScreenWidth > 0 {
 			log.ScreenResolution = fmt.Sprintf("%dx%d", log.ScreenWidth, log.ScreenHeight)
 		}
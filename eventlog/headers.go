@@ -0,0 +1,36 @@
+package eventlog
+
+import (
+	"os"
+	"strconv"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/storage"
+)
+
+// SchemaVersion is the version of EventLog schema, bump it on incompatible changes
+const SchemaVersion = "1"
+
+var _hostname, _ = os.Hostname()
+
+// Headers returns message headers of the event log,
+// consumers can filter messages by them without unmarshalling the payload
+func Headers(log *pb.EventLog, dataType string) map[string]string {
+	contentType := storage.ContentTypeProtobuf
+	if dataType == "json" {
+		contentType = storage.ContentTypeJSON
+	}
+
+	headers := map[string]string{
+		storage.HeaderContentType:   contentType,
+		storage.HeaderSchemaVersion: SchemaVersion,
+		storage.HeaderAppType:       log.AppType,
+		storage.HeaderEvent:         log.Event,
+		storage.HeaderLoggedTime:    strconv.FormatInt(log.LoggedTime, 10),
+	}
+	if _hostname != "" {
+		headers[storage.HeaderIngestHost] = _hostname
+	}
+
+	return headers
+}
@@ -0,0 +1,65 @@
+package eventlog
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a thread-safe fixed size LRU cache
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (c *lruCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*lruEntry).value, true
+	}
+
+	return nil, false
+}
+
+func (c *lruCache) Add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*lruEntry).value = value
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key, value})
+
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *lruCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
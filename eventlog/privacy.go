@@ -0,0 +1,161 @@
+package eventlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/techxmind/logserver/config"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// Anonymizer applies privacy policies to event logs
+type Anonymizer struct {
+	cfg *config.PrivacyConfig
+
+	// hash key of the current rotation period
+	mu     sync.Mutex
+	period int64
+	key    []byte
+}
+
+// NewAnonymizer returns anonymizer of the privacy config, nil if cfg is nil
+func NewAnonymizer(cfg *config.PrivacyConfig) *Anonymizer {
+	if cfg == nil {
+		return nil
+	}
+
+	return &Anonymizer{
+		cfg:    cfg,
+		period: -1,
+	}
+}
+
+// Apply applies policy of each log's app_type, falls back to common app_type
+func (a *Anonymizer) Apply(common *pb.EventLogCommon, logs []*pb.EventLog) {
+	if a == nil {
+		return
+	}
+
+	for _, log := range logs {
+		appType := log.AppType
+		if appType == "" && common != nil {
+			appType = common.AppType
+		}
+		if policy := a.cfg.GetPolicy(appType); policy != nil {
+			a.apply(policy, log)
+		}
+	}
+}
+
+// ApplySingle wrapper Apply func for single log case
+func (a *Anonymizer) ApplySingle(log *pb.EventLog) {
+	a.Apply(nil, []*pb.EventLog{log})
+}
+
+// IP applies ip policy of the app type, e.g. to the source ip of dead letters
+func (a *Anonymizer) IP(appType, ip string) string {
+	if a == nil {
+		return ip
+	}
+
+	policy := a.cfg.GetPolicy(appType)
+	if policy == nil {
+		return ip
+	}
+
+	return a.value(policy, "ip", ip)
+}
+
+func (a *Anonymizer) apply(policy *config.PrivacyPolicy, log *pb.EventLog) {
+	fields := map[string]*string{
+		"ip":         &log.Ip,
+		"imei":       &log.Imei,
+		"mac":        &log.Mac,
+		"idfa":       &log.Idfa,
+		"oaid":       &log.Oaid,
+		"android_id": &log.AndroidId,
+		"lat":        &log.Lat,
+		"lon":        &log.Lon,
+	}
+
+	for field := range policy.Fields {
+		if v := fields[field]; v != nil && *v != "" {
+			*v = a.value(policy, field, *v)
+		}
+	}
+}
+
+func (a *Anonymizer) value(policy *config.PrivacyPolicy, field, v string) string {
+	if v == "" {
+		return v
+	}
+
+	switch policy.Fields[field] {
+	case config.PrivacyDrop:
+		return ""
+	case config.PrivacyHash:
+		return a.hash(v)
+	case config.PrivacyTruncate:
+		if field == "ip" {
+			return truncateIP(v, policy.GetIPv4Prefix(), policy.GetIPv6Prefix())
+		}
+		return truncateCoordinate(v, policy.GetCoordinatePrecision())
+	}
+
+	return v
+}
+
+// hash returns hex(hmac_sha256(key, v)), key is hmac_sha256(salt, rotation period)
+func (a *Anonymizer) hash(v string) string {
+	mac := hmac.New(sha256.New, a.hashKey(time.Now()))
+	mac.Write([]byte(v))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (a *Anonymizer) hashKey(now time.Time) []byte {
+	var period int64
+	if a.cfg.SaltRotation > 0 {
+		period = now.Unix() / int64(a.cfg.SaltRotation)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if period != a.period {
+		mac := hmac.New(sha256.New, []byte(a.cfg.Salt))
+		mac.Write([]byte(strconv.FormatInt(period, 10)))
+		a.period, a.key = period, mac.Sum(nil)
+	}
+
+	return a.key
+}
+
+// truncateIP masks ip to prefix, invalid ip is dropped
+func truncateIP(v string, v4Prefix, v6Prefix int) string {
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return ""
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(v4Prefix, 32)).String()
+	}
+
+	return ip.Mask(net.CIDRMask(v6Prefix, 128)).String()
+}
+
+// truncateCoordinate rounds coordinate to precision decimal places, invalid value is dropped
+func truncateCoordinate(v string, precision int) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(f, 'f', precision, 64)
+}
@@ -0,0 +1,84 @@
+package eventlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/techxmind/logserver/config"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func TestAnonymizer(t *testing.T) {
+	ast := assert.New(t)
+
+	a := NewAnonymizer(&config.PrivacyConfig{
+		Salt:         "salt",
+		SaltRotation: 86400,
+		Policies: map[string]*config.PrivacyPolicy{
+			"default": {
+				Fields: map[string]string{"ip": "truncate", "imei": "drop", "lat": "truncate", "lon": "truncate"},
+			},
+			"myapp": {
+				Fields:              map[string]string{"ip": "truncate", "mac": "hash", "lat": "truncate"},
+				IPv6Prefix:          intValue(32),
+				CoordinatePrecision: intValue(1),
+			},
+			"zero": {
+				Fields:              map[string]string{"ip": "truncate", "lat": "truncate"},
+				IPv4Prefix:          intValue(0),
+				CoordinatePrecision: intValue(0),
+			},
+			"trusted": {
+				Fields: map[string]string{"ip": "keep"},
+			},
+		},
+	})
+
+	common := &pb.EventLogCommon{AppType: "myapp"}
+	logs := []*pb.EventLog{
+		{Ip: "2001:db8:85a3::8a2e:370:7334", Mac: "00:1a:2b:3c:4d:5e", Lat: "31.26", Imei: "imei-1"},
+		{AppType: "other", Ip: "124.78.41.83", Imei: "imei-1", Lat: "31.234", Lon: "abc"},
+		{AppType: "trusted", Ip: "124.78.41.83", Imei: "imei-1"},
+		{AppType: "zero", Ip: "124.78.41.83", Lat: "31.26"},
+	}
+	a.Apply(common, logs)
+
+	ast.Equal("2001:db8::", logs[0].Ip)
+	ast.Len(logs[0].Mac, 64)
+	ast.Equal("31.3", logs[0].Lat)
+	ast.Equal("imei-1", logs[0].Imei)
+
+	ast.Equal("124.78.41.0", logs[1].Ip)
+	ast.Equal("", logs[1].Imei)
+	ast.Equal("31.23", logs[1].Lat)
+	// invalid value is dropped
+	ast.Equal("", logs[1].Lon)
+
+	ast.Equal("124.78.41.83", logs[2].Ip)
+	ast.Equal("imei-1", logs[2].Imei)
+
+	// 0 is not the default
+	ast.Equal("0.0.0.0", logs[3].Ip)
+	ast.Equal("31", logs[3].Lat)
+
+	ast.Equal("124.78.41.0", a.IP("other", "124.78.41.83"))
+	ast.Equal("124.78.41.83", a.IP("trusted", "124.78.41.83"))
+
+	// hash is stable in a rotation period, and changes after rotation
+	now := time.Now()
+	key := a.hashKey(now)
+	ast.Equal(key, a.hashKey(now))
+	ast.NotEqual(key, a.hashKey(now.Add(24*time.Hour)))
+
+	// nil anonymizer keeps logs
+	var nilAnonymizer *Anonymizer
+	log := &pb.EventLog{Ip: "124.78.41.83"}
+	nilAnonymizer.ApplySingle(log)
+	ast.Equal("124.78.41.83", log.Ip)
+}
+
+func intValue(v int) *int {
+	return &v
+}
@@ -0,0 +1,416 @@
+package eventlog
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/config"
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// Router routes events by rules, then by route map of the topic router
+type Router struct {
+	cfg   *config.TopicRouter
+	rules []*rule
+	// message key by topic
+	keys       map[string]func(*pb.EventLog) string
+	defaultKey func(*pb.EventLog) string
+}
+
+// Destination is where an event is written
+type Destination struct {
+	Topic string
+	// message key
+	Key string
+	// json | protobuf, empty means storage.data_type
+	DataType string
+	// event with the projected fields
+	Event *pb.EventLog
+}
+
+type rule struct {
+	conds        []func(*pb.EventLog) bool
+	destinations []*destination
+	next         bool
+}
+
+type destination struct {
+	topic    template
+	dataType string
+	// nil means key of the topic
+	key func(*pb.EventLog) string
+	// nil means all fields
+	fields []projection
+}
+
+// topic template segments, literal if get is nil
+type template []segment
+
+type segment struct {
+	literal string
+	get     func(*pb.EventLog) string
+}
+
+// projection copies a field, or an extend_info key if key isn't empty
+type projection struct {
+	index []int
+	key   string
+}
+
+// NewRouter compiles rules of the topic router
+func NewRouter(cfg *config.TopicRouter) (*Router, error) {
+	r := &Router{
+		cfg:        cfg,
+		keys:       make(map[string]func(*pb.EventLog) string, len(cfg.KeyMap)),
+		defaultKey: fieldGetter("event_id"),
+	}
+
+	for topic, key := range cfg.KeyMap {
+		if err := config.ValidateKey(key); err != nil {
+			return nil, errors.Wrapf(err, "key_map %s", topic)
+		}
+		r.keys[topic] = compileKey(key)
+	}
+	if cfg.DefaultKey != "" {
+		if err := config.ValidateKey(cfg.DefaultKey); err != nil {
+			return nil, errors.Wrap(err, "default_key")
+		}
+		r.defaultKey = compileKey(cfg.DefaultKey)
+	}
+
+	for i, ruleCfg := range cfg.Rules {
+		rule, err := compileRule(ruleCfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rules[%d]", i)
+		}
+		r.rules = append(r.rules, rule)
+	}
+
+	return r, nil
+}
+
+// Route returns destinations of the event, empty if it's not routable.
+// Route map and default topic apply only if no rule matches.
+func (r *Router) Route(event *pb.EventLog) []*Destination {
+	var (
+		dests   []*Destination
+		matched bool
+	)
+
+	for _, rule := range r.rules {
+		if !rule.match(event) {
+			continue
+		}
+		n := len(dests)
+		for _, d := range rule.destinations {
+			if dest := d.destination(event); dest != nil {
+				if d.key == nil {
+					dest.Key = r.key(dest.Topic, event)
+				}
+				dests = append(dests, dest)
+			}
+		}
+		// the rule doesn't match if all topic templates have empty fields
+		if len(dests) == n {
+			continue
+		}
+		matched = true
+		if !rule.next {
+			break
+		}
+	}
+
+	if !matched {
+		if topic := GetTopic(event, r.cfg); topic != "" {
+			dests = append(dests, &Destination{
+				Topic: topic,
+				Key:   r.key(topic, event),
+				Event: event,
+			})
+		}
+	}
+
+	return dests
+}
+
+func (r *Router) key(topic string, event *pb.EventLog) string {
+	if key, ok := r.keys[topic]; ok {
+		return key(event)
+	}
+
+	return r.defaultKey(event)
+}
+
+func (r *rule) match(event *pb.EventLog) bool {
+	for _, cond := range r.conds {
+		if !cond(event) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// destination returns nil if any field of topic template is empty
+func (d *destination) destination(event *pb.EventLog) *Destination {
+	topic, ok := d.topic.render(event)
+	if !ok {
+		return nil
+	}
+
+	dest := &Destination{
+		Topic:    topic,
+		DataType: d.dataType,
+		Event:    event,
+	}
+	if d.key != nil {
+		dest.Key = d.key(event)
+	}
+	if d.fields != nil {
+		dest.Event = project(event, d.fields)
+	}
+
+	return dest
+}
+
+func (t template) render(event *pb.EventLog) (string, bool) {
+	if len(t) == 1 && t[0].get == nil {
+		return t[0].literal, true
+	}
+
+	var b strings.Builder
+	for _, s := range t {
+		if s.get == nil {
+			b.WriteString(s.literal)
+			continue
+		}
+		v := s.get(event)
+		if v == "" {
+			return "", false
+		}
+		b.WriteString(topicSafe(v))
+	}
+
+	return b.String(), true
+}
+
+// text renders template with raw field values, empty fields are allowed
+func (t template) text(event *pb.EventLog) string {
+	var b strings.Builder
+	for _, s := range t {
+		if s.get == nil {
+			b.WriteString(s.literal)
+		} else {
+			b.WriteString(s.get(event))
+		}
+	}
+
+	return b.String()
+}
+
+func project(event *pb.EventLog, fields []projection) *pb.EventLog {
+	var (
+		projected = &pb.EventLog{}
+		src       = reflect.ValueOf(event).Elem()
+		dst       = reflect.ValueOf(projected).Elem()
+	)
+
+	for _, f := range fields {
+		if f.key == "" {
+			dst.FieldByIndex(f.index).Set(src.FieldByIndex(f.index))
+			continue
+		}
+		if v, ok := event.ExtendInfo[f.key]; ok {
+			if projected.ExtendInfo == nil {
+				projected.ExtendInfo = make(map[string]string)
+			}
+			projected.ExtendInfo[f.key] = v
+		}
+	}
+
+	return projected
+}
+
+func compileRule(cfg *config.RouteRule) (*rule, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &rule{next: cfg.Continue}
+	for _, condCfg := range cfg.Match {
+		cond, err := compileCondition(condCfg)
+		if err != nil {
+			return nil, err
+		}
+		r.conds = append(r.conds, cond)
+	}
+
+	destCfgs := cfg.Destinations
+	if cfg.Topic != "" {
+		destCfgs = append([]*config.Destination{{Topic: cfg.Topic}}, destCfgs...)
+	}
+	for _, destCfg := range destCfgs {
+		r.destinations = append(r.destinations, compileDestination(destCfg))
+	}
+
+	return r, nil
+}
+
+func compileDestination(cfg *config.Destination) *destination {
+	d := &destination{
+		topic:    compileTemplate(cfg.Topic),
+		dataType: cfg.DataType,
+	}
+	if cfg.Key != "" {
+		d.key = compileKey(cfg.Key)
+	}
+
+	if len(cfg.Fields) > 0 {
+		d.fields = make([]projection, 0, len(cfg.Fields))
+	}
+	for _, name := range cfg.Fields {
+		switch {
+		case name == "extend_info":
+			field, _ := reflect.TypeOf(pb.EventLog{}).FieldByName("ExtendInfo")
+			d.fields = append(d.fields, projection{index: field.Index})
+		case strings.HasPrefix(name, config.ExtendInfoPrefix):
+			d.fields = append(d.fields, projection{key: name[len(config.ExtendInfoPrefix):]})
+		default:
+			field, _ := config.EventLogField(name)
+			d.fields = append(d.fields, projection{index: field.Index})
+		}
+	}
+
+	return d
+}
+
+// compileKey returns message key getter of key spec: field name, template or none
+func compileKey(key string) func(*pb.EventLog) string {
+	switch {
+	case key == config.KeyNone:
+		return func(*pb.EventLog) string { return "" }
+	case strings.ContainsAny(key, "{}"):
+		return compileTemplate(key).text
+	}
+
+	return fieldGetter(key)
+}
+
+func compileTemplate(topic string) template {
+	var t template
+
+	for {
+		i := strings.IndexByte(topic, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(topic[i:], '}') + i
+		if i > 0 {
+			t = append(t, segment{literal: topic[:i]})
+		}
+		t = append(t, segment{get: fieldGetter(topic[i+1 : j])})
+		topic = topic[j+1:]
+	}
+	if topic != "" || len(t) == 0 {
+		t = append(t, segment{literal: topic})
+	}
+
+	return t
+}
+
+func compileCondition(cfg *config.RouteCondition) (func(*pb.EventLog) bool, error) {
+	get := fieldGetter(cfg.Field)
+
+	switch cfg.GetOp() {
+	case config.OpEq:
+		return func(e *pb.EventLog) bool { return get(e) == cfg.Value }, nil
+	case config.OpPrefix:
+		return func(e *pb.EventLog) bool { return strings.HasPrefix(get(e), cfg.Value) }, nil
+	case config.OpRegex:
+		rx, err := regexp.Compile(cfg.Value)
+		if err != nil {
+			return nil, err
+		}
+		return func(e *pb.EventLog) bool { return rx.MatchString(get(e)) }, nil
+	case config.OpIn:
+		set := make(map[string]bool, len(cfg.Values))
+		for _, v := range cfg.Values {
+			set[v] = true
+		}
+		return func(e *pb.EventLog) bool { return set[get(e)] }, nil
+	}
+
+	operand, err := strconv.ParseFloat(cfg.Value, 64)
+	if err != nil {
+		return nil, err
+	}
+	var compare func(float64) bool
+	switch cfg.Op {
+	case config.OpGt:
+		compare = func(v float64) bool { return v > operand }
+	case config.OpGte:
+		compare = func(v float64) bool { return v >= operand }
+	case config.OpLt:
+		compare = func(v float64) bool { return v < operand }
+	case config.OpLte:
+		compare = func(v float64) bool { return v <= operand }
+	default:
+		return nil, errors.Errorf("unknown op %s", cfg.Op)
+	}
+
+	return func(e *pb.EventLog) bool {
+		v, err := strconv.ParseFloat(get(e), 64)
+		return err == nil && compare(v)
+	}, nil
+}
+
+// fieldGetter returns getter of EventLog field or extend_info key, name must be valid
+func fieldGetter(name string) func(*pb.EventLog) string {
+	if strings.HasPrefix(name, config.ExtendInfoPrefix) {
+		key := name[len(config.ExtendInfoPrefix):]
+		return func(e *pb.EventLog) string { return e.ExtendInfo[key] }
+	}
+
+	// fast path of frequently used fields
+	switch name {
+	case "app_type":
+		return func(e *pb.EventLog) string { return e.AppType }
+	case "event":
+		return func(e *pb.EventLog) string { return e.Event }
+	case "env":
+		return func(e *pb.EventLog) string { return e.Env }
+	case "platform":
+		return func(e *pb.EventLog) string { return e.Platform }
+	case "event_id":
+		return func(e *pb.EventLog) string { return e.EventId }
+	}
+
+	field, _ := config.EventLogField(name)
+	index := field.Index
+	switch field.Type.Kind() {
+	case reflect.String:
+		return func(e *pb.EventLog) string {
+			return reflect.ValueOf(e).Elem().FieldByIndex(index).String()
+		}
+	case reflect.Int32, reflect.Int64:
+		return func(e *pb.EventLog) string {
+			return strconv.FormatInt(reflect.ValueOf(e).Elem().FieldByIndex(index).Int(), 10)
+		}
+	}
+
+	return func(*pb.EventLog) string { return "" }
+}
+
+// topicSafe replaces chars that are not allowed in kafka topic names with _
+func topicSafe(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, v)
+}
@@ -56,3 +56,219 @@ func TestGetTopic(t *testing.T) {
 		ast.Equal(test.expectTopic, GetTopic(test.event, topicRouter))
 	}
 }
+
+func TestRouterRules(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := &config.TopicRouter{
+		DefaultTopic: "default",
+		RouteMap: map[string]string{
+			"myapp": "myapp_event_default",
+		},
+		Rules: []*config.RouteRule{
+			{
+				Match: []*config.RouteCondition{
+					{Field: "app_type", Value: "myapp"},
+					{Field: "extend_info.source", Op: "in", Values: []string{"ad", "push"}},
+				},
+				Topic: "myapp_{extend_info.source}_events",
+			},
+			{
+				Match: []*config.RouteCondition{
+					{Field: "event", Op: "regex", Value: "^(?i)pay"},
+					{Field: "duration", Op: "gte", Value: "100"},
+				},
+				Topic: "slow_pay",
+			},
+			{
+				Match: []*config.RouteCondition{
+					{Field: "page_id", Op: "prefix", Value: "goods_"},
+				},
+				Topic: "{app_type}_{platform}_events",
+			},
+		},
+	}
+	ast.NoError(cfg.Validate())
+
+	router, err := NewRouter(cfg)
+	ast.NoError(err)
+
+	tests := []struct {
+		event       *pb.EventLog
+		expectTopic string
+	}{
+		{
+			&pb.EventLog{AppType: "myapp", ExtendInfo: map[string]string{"source": "push"}},
+			"myapp_push_events",
+		},
+		{
+			&pb.EventLog{AppType: "myapp", ExtendInfo: map[string]string{"source": "web"}},
+			"myapp_event_default",
+		},
+		{
+			&pb.EventLog{AppType: "other", Event: "PayOrder", Duration: 200},
+			"slow_pay",
+		},
+		{
+			&pb.EventLog{AppType: "other", Event: "PayOrder", Duration: 50},
+			"default",
+		},
+		{
+			&pb.EventLog{AppType: "shop", Platform: "h5/web", PageId: "goods_detail"},
+			"shop_h5_web_events",
+		},
+		// empty field in template
+		{
+			&pb.EventLog{AppType: "shop", PageId: "goods_detail"},
+			"default",
+		},
+	}
+
+	for _, test := range tests {
+		ast.Equal([]string{test.expectTopic}, routeTopics(router, test.event))
+	}
+
+	invalid := []*config.RouteRule{
+		{Topic: "{unknown}_events"},
+		{Topic: "{app_type_events"},
+		{Topic: "t", Match: []*config.RouteCondition{{Field: "app_type", Op: "regex", Value: "("}}},
+		{Topic: "t", Match: []*config.RouteCondition{{Field: "duration", Op: "gt", Value: "x"}}},
+		{Topic: "t", Match: []*config.RouteCondition{{Field: "app_type", Op: "like"}}},
+		{Match: []*config.RouteCondition{{Field: "app_type"}}},
+		{Destinations: []*config.Destination{{Topic: "t", DataType: "xml"}}},
+		{Destinations: []*config.Destination{{Topic: "t", Fields: []string{"unknown"}}}},
+		{Destinations: []*config.Destination{{Topic: "t", Key: "unknown"}}},
+	}
+	for _, rule := range invalid {
+		_, err := NewRouter(&config.TopicRouter{Rules: []*config.RouteRule{rule}})
+		ast.Error(err, rule.Topic)
+	}
+}
+
+func TestRouterFanOut(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := &config.TopicRouter{
+		DefaultTopic: "default",
+		Rules: []*config.RouteRule{
+			{
+				Match: []*config.RouteCondition{{Field: "env", Value: "test"}},
+				Topic: "sandbox",
+			},
+			{
+				Destinations: []*config.Destination{{Topic: "firehose", DataType: "protobuf"}},
+				Continue:     true,
+			},
+			{
+				Match: []*config.RouteCondition{{Field: "event", Value: "pay"}},
+				Destinations: []*config.Destination{
+					{
+						Topic:    "finance",
+						DataType: "json",
+						Fields:   []string{"event_id", "event", "mid", "extend_info.amount"},
+						Key:      "mid",
+					},
+				},
+			},
+		},
+	}
+	ast.NoError(cfg.Validate())
+
+	router, err := NewRouter(cfg)
+	ast.NoError(err)
+
+	ast.Equal([]string{"sandbox"}, routeTopics(router, &pb.EventLog{Env: "test", Event: "pay"}))
+	ast.Equal([]string{"firehose"}, routeTopics(router, &pb.EventLog{Event: "pv"}))
+
+	event := &pb.EventLog{
+		EventId:    "e1",
+		Event:      "pay",
+		Mid:        "m1",
+		Udid:       "u1",
+		ExtendInfo: map[string]string{"amount": "100", "coupon": "c1"},
+	}
+	dests := router.Route(event)
+	if ast.Len(dests, 2) {
+		ast.Equal("firehose", dests[0].Topic)
+		ast.Equal("e1", dests[0].Key)
+		ast.Equal("protobuf", dests[0].DataType)
+		ast.Equal(event, dests[0].Event)
+
+		ast.Equal("finance", dests[1].Topic)
+		ast.Equal("m1", dests[1].Key)
+		ast.Equal("json", dests[1].DataType)
+		ast.Equal(&pb.EventLog{
+			EventId:    "e1",
+			Event:      "pay",
+			Mid:        "m1",
+			ExtendInfo: map[string]string{"amount": "100"},
+		}, dests[1].Event)
+	}
+	// projection doesn't change the event
+	ast.Equal("u1", event.Udid)
+}
+
+func routeTopics(router *Router, event *pb.EventLog) []string {
+	topics := make([]string, 0)
+	for _, dest := range router.Route(event) {
+		topics = append(topics, dest.Topic)
+	}
+
+	return topics
+}
+
+func TestRouterKeys(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := &config.TopicRouter{
+		DefaultTopic: "default",
+		RouteMap: map[string]string{
+			"myapp":  "myapp_events",
+			"myapp2": "myapp2_events",
+		},
+		KeyMap: map[string]string{
+			"myapp_events":  "udid",
+			"myapp2_events": "none",
+			"session":       "{app_type}:{session_id}",
+		},
+		DefaultKey: "mid",
+		Rules: []*config.RouteRule{
+			{
+				Match:    []*config.RouteCondition{{Field: "event", Value: "pv"}},
+				Topic:    "session",
+				Continue: true,
+			},
+			{
+				Match: []*config.RouteCondition{{Field: "event", Value: "pv"}},
+				Destinations: []*config.Destination{
+					{Topic: "pv", Key: "{mid}-{udid}"},
+				},
+			},
+		},
+	}
+	ast.NoError(cfg.Validate())
+
+	router, err := NewRouter(cfg)
+	ast.NoError(err)
+
+	keys := func(event *pb.EventLog) []string {
+		var keys []string
+		for _, dest := range router.Route(event) {
+			keys = append(keys, dest.Topic+"="+dest.Key)
+		}
+		return keys
+	}
+
+	ast.Equal([]string{"myapp_events=u1"}, keys(&pb.EventLog{AppType: "myapp", Udid: "u1", Mid: "m1"}))
+	ast.Equal([]string{"myapp2_events="}, keys(&pb.EventLog{AppType: "myapp2", Udid: "u1", Mid: "m1"}))
+	ast.Equal([]string{"default=m1"}, keys(&pb.EventLog{AppType: "other", Udid: "u1", Mid: "m1"}))
+	ast.Equal(
+		[]string{"session=myapp:s1", "pv=-u1"},
+		keys(&pb.EventLog{AppType: "myapp", Event: "pv", SessionId: "s1", Udid: "u1"}),
+	)
+
+	for _, key := range []string{"unknown", "{unknown}", "{udid"} {
+		_, err := NewRouter(&config.TopicRouter{KeyMap: map[string]string{"t": key}})
+		ast.Error(err, key)
+	}
+}
@@ -0,0 +1,223 @@
+package eventlog
+
+import (
+	"strings"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+// Device types
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// max length of user agent that is cached, longer ones are parsed every time
+const _maxCachedUALength = 512
+
+var (
+	_uaCache = newLRUCache(10000)
+
+	_botTokens = []string{
+		"bot", "spider", "crawl", "slurp", "curl/", "wget/", "python-requests", "go-http-client", "headlesschrome",
+	}
+
+	// browser name and version token, in matching order
+	_browserTokens = []struct {
+		name  string
+		token string
+	}{
+		{"wechat", "MicroMessenger/"},
+		{"edge", "Edg/"},
+		{"edge", "EdgA/"},
+		{"edge", "EdgiOS/"},
+		{"edge", "Edge/"},
+		{"opera", "OPR/"},
+		{"opera", "Opera/"},
+		{"samsung", "SamsungBrowser/"},
+		{"uc", "UCBrowser/"},
+		{"qq", "QQBrowser/"},
+		{"firefox", "Firefox/"},
+		{"firefox", "FxiOS/"},
+		{"chrome", "CriOS/"},
+		{"chrome", "Chrome/"},
+		{"ie", "MSIE "},
+		{"ie", "rv:"}, // IE 11, matched only when Trident is present
+		{"safari", "Version/"},
+	}
+
+	_windowsVersions = map[string]string{
+		"10.0": "10",
+		"6.3":  "8.1",
+		"6.2":  "8",
+		"6.1":  "7",
+		"6.0":  "vista",
+		"5.1":  "xp",
+	}
+)
+
+// UserAgent is the parsed user agent
+type UserAgent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	DeviceType     string
+	Engine         string
+}
+
+// ParseUserAgent parses user agent with LRU cache, it returns nil if ua is empty
+func ParseUserAgent(ua string) *UserAgent {
+	if ua == "" {
+		return nil
+	}
+
+	if len(ua) > _maxCachedUALength {
+		return parseUserAgent(ua)
+	}
+
+	if v, ok := _uaCache.Get(ua); ok {
+		return v.(*UserAgent)
+	}
+
+	v := parseUserAgent(ua)
+	_uaCache.Add(ua, v)
+
+	return v
+}
+
+// Fill sets the fields that are parsed from user agent, values sent by the client are overwritten
+// except OS and OSVersion, which are set only if the client left them empty.
+func (u *UserAgent) Fill(log *pb.EventLog) {
+	log.Browser, log.BrowserVersion = u.Browser, u.BrowserVersion
+	log.DeviceType = u.DeviceType
+	log.Engine = u.Engine
+	if log.Os == "" {
+		log.Os = u.OS
+	}
+	if log.OsVersion == "" && strings.EqualFold(log.Os, u.OS) {
+		log.OsVersion = u.OSVersion
+	}
+}
+
+func parseUserAgent(ua string) *UserAgent {
+	var (
+		u     = &UserAgent{}
+		lower = strings.ToLower(ua)
+	)
+
+	u.OS, u.OSVersion = parseOS(ua)
+	u.Browser, u.BrowserVersion = parseBrowser(ua)
+	u.Engine = parseEngine(ua, u.OS)
+
+	switch {
+	case containsAny(lower, _botTokens):
+		u.DeviceType = DeviceBot
+	case strings.Contains(ua, "iPad") || strings.Contains(lower, "tablet") ||
+		(u.OS == "android" && !strings.Contains(ua, "Mobile")):
+		u.DeviceType = DeviceTablet
+	case strings.Contains(ua, "Mobi") || u.OS == "ios" || u.OS == "android" || u.OS == "windows phone" || u.OS == "harmonyos":
+		u.DeviceType = DeviceMobile
+	case u.OS != "":
+		u.DeviceType = DeviceDesktop
+	}
+
+	return u
+}
+
+func parseOS(ua string) (string, string) {
+	switch {
+	case strings.Contains(ua, "Windows Phone"):
+		return "windows phone", tokenValue(ua, "Windows Phone ")
+	case strings.Contains(ua, "Windows NT "):
+		v := tokenValue(ua, "Windows NT ")
+		if name, ok := _windowsVersions[v]; ok {
+			v = name
+		}
+		return "windows", v
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		return "ios", strings.Replace(tokenValue(ua, " OS "), "_", ".", -1)
+	case strings.Contains(ua, "HarmonyOS"):
+		return "harmonyos", tokenValue(ua, "HarmonyOS ")
+	case strings.Contains(ua, "Android"):
+		return "android", tokenValue(ua, "Android ")
+	case strings.Contains(ua, "Mac OS X"):
+		return "macos", strings.Replace(tokenValue(ua, "Mac OS X "), "_", ".", -1)
+	case strings.Contains(ua, "CrOS"):
+		return "chromeos", ""
+	case strings.Contains(ua, "Linux"):
+		return "linux", ""
+	}
+
+	return "", ""
+}
+
+func parseBrowser(ua string) (string, string) {
+	for _, b := range _browserTokens {
+		if !strings.Contains(ua, b.token) {
+			continue
+		}
+		switch b.token {
+		case "rv:":
+			if !strings.Contains(ua, "Trident/") {
+				continue
+			}
+		case "Version/":
+			if !strings.Contains(ua, "Safari/") {
+				continue
+			}
+		}
+		return b.name, tokenValue(ua, b.token)
+	}
+
+	return "", ""
+}
+
+func parseEngine(ua, os string) string {
+	switch {
+	case strings.Contains(ua, "Trident/") || strings.Contains(ua, "MSIE "):
+		return "trident"
+	case strings.Contains(ua, "Edge/"):
+		return "edgehtml"
+	case strings.Contains(ua, "Presto/"):
+		return "presto"
+	case os == "ios" && strings.Contains(ua, "AppleWebKit/"):
+		// all browsers on iOS use WebKit
+		return "webkit"
+	case strings.Contains(ua, "Chrome/") || strings.Contains(ua, "Chromium/"):
+		return "blink"
+	case strings.Contains(ua, "AppleWebKit/"):
+		return "webkit"
+	case strings.Contains(ua, "Gecko/"):
+		return "gecko"
+	}
+
+	return ""
+}
+
+// tokenValue returns value after token, ends with space, semicolon or parenthesis
+func tokenValue(ua, token string) string {
+	i := strings.Index(ua, token)
+	if i < 0 {
+		return ""
+	}
+
+	v := ua[i+len(token):]
+	if j := strings.IndexAny(v, " ;()"); j >= 0 {
+		v = v[:j]
+	}
+
+	return v
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, token := range tokens {
+		if strings.Contains(s, token) {
+			return true
+		}
+	}
+
+	return false
+}
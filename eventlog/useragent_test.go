@@ -0,0 +1,133 @@
+package eventlog
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua     string
+		expect UserAgent
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36",
+			UserAgent{"chrome", "87.0.4280.88", "windows", "10", DeviceDesktop, "blink"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.1 Safari/605.1.15",
+			UserAgent{"safari", "14.0.1", "macos", "10.15.7", DeviceDesktop, "webkit"},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 14_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/7.0.18(0x17001229) NetType/WIFI Language/zh_CN",
+			UserAgent{"wechat", "7.0.18", "ios", "14.2", DeviceMobile, "webkit"},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 13_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/87.0.4280.77 Mobile/15E148 Safari/604.1",
+			UserAgent{"chrome", "87.0.4280.77", "ios", "13.3", DeviceTablet, "webkit"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 10; SM-G9730) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Mobile Safari/537.36",
+			UserAgent{"chrome", "86.0.4240.198", "android", "10", DeviceMobile, "blink"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 9; SM-T720) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Safari/537.36",
+			UserAgent{"chrome", "86.0.4240.198", "android", "9", DeviceTablet, "blink"},
+		},
+		{
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:83.0) Gecko/20100101 Firefox/83.0",
+			UserAgent{"firefox", "83.0", "linux", "", DeviceDesktop, "gecko"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36 Edg/87.0.664.60",
+			UserAgent{"edge", "87.0.664.60", "windows", "10", DeviceDesktop, "blink"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			UserAgent{"ie", "11.0", "windows", "7", DeviceDesktop, "trident"},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgent{"", "", "", "", DeviceBot, ""},
+		},
+		{
+			"test-ua",
+			UserAgent{},
+		},
+	}
+
+	ast := assert.New(t)
+	for _, test := range tests {
+		ast.Equal(test.expect, *ParseUserAgent(test.ua), test.ua)
+	}
+	ast.Nil(ParseUserAgent(""))
+}
+
+func TestFillUserAgent(t *testing.T) {
+	ast := assert.New(t)
+
+	ctx := context.WithValue(
+		context.Background(),
+		"user-agent",
+		"Mozilla/5.0 (Linux; Android 10; SM-G9730) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Mobile Safari/537.36",
+	)
+	logs := []*pb.EventLog{
+		{},
+		{Os: "android", OsVersion: "10.0.1"},
+		{Os: "harmonyos"},
+		{Browser: "spoofed", BrowserVersion: "1.0", DeviceType: DeviceDesktop, Engine: "spoofed"},
+	}
+	Fill(ctx, nil, logs)
+
+	ast.Equal("chrome", logs[0].Browser)
+	ast.Equal("86.0.4240.198", logs[0].BrowserVersion)
+	ast.Equal(DeviceMobile, logs[0].DeviceType)
+	ast.Equal("blink", logs[0].Engine)
+	ast.Equal("android", logs[0].Os)
+	ast.Equal("10", logs[0].OsVersion)
+
+	// os and os_version from client are kept
+	ast.Equal("10.0.1", logs[1].OsVersion)
+	ast.Equal("harmonyos", logs[2].Os)
+	ast.Equal("", logs[2].OsVersion)
+
+	// other properties from client are overwritten
+	ast.Equal("chrome", logs[3].Browser)
+	ast.Equal("86.0.4240.198", logs[3].BrowserVersion)
+	ast.Equal(DeviceMobile, logs[3].DeviceType)
+	ast.Equal("blink", logs[3].Engine)
+
+	// and cleared without user agent
+	logs = []*pb.EventLog{{Browser: "spoofed", BrowserVersion: "1.0", DeviceType: DeviceDesktop, Engine: "spoofed", Os: "ios"}}
+	Fill(context.Background(), nil, logs)
+	ast.Equal("", logs[0].Browser)
+	ast.Equal("", logs[0].BrowserVersion)
+	ast.Equal("", logs[0].DeviceType)
+	ast.Equal("", logs[0].Engine)
+	ast.Equal("ios", logs[0].Os)
+}
+
+func TestLRUCache(t *testing.T) {
+	ast := assert.New(t)
+
+	c := newLRUCache(3)
+	for i := 0; i < 3; i++ {
+		c.Add(strconv.Itoa(i), i)
+	}
+	// 0 becomes the most recently used
+	_, ok := c.Get("0")
+	ast.True(ok)
+	c.Add("3", 3)
+
+	ast.Equal(3, c.Len())
+	_, ok = c.Get("1")
+	ast.False(ok)
+	v, ok := c.Get("0")
+	ast.True(ok)
+	ast.Equal(0, v)
+}
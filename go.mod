@@ -4,22 +4,29 @@ go 1.15
 
 require (
 	github.com/Shopify/sarama v1.27.1
+	github.com/aws/aws-sdk-go v1.35.20
 	github.com/go-kit/kit v0.10.0
 	github.com/gogo/protobuf v1.2.2-0.20190601103108-21df5aa0e680
-	github.com/golang/protobuf v1.3.2
+	github.com/golang/protobuf v1.3.3
 	github.com/gorilla/mux v1.7.3
 	github.com/joho/godotenv v1.3.0
+	github.com/klauspost/compress v1.13.1
 	github.com/metaverse/truss v0.2.0
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
-	github.com/pkg/errors v0.8.1
+	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.3.0
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	github.com/techxmind/go-utils v0.0.0-20201025145251-2d5da175c756
 	github.com/techxmind/ip2location v0.0.0-20201016120605-9ca74285b024
 	github.com/techxmind/rollingfile v0.0.0-20201102125758-eaabf7bcf591
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c
+	github.com/xdg/stringprep v1.0.0 // indirect
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	go.uber.org/atomic v1.7.0
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.16.0
-	google.golang.org/grpc v1.26.0
+	google.golang.org/grpc v1.27.1
+	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 )
@@ -99,20 +99,23 @@ func (EventLog_Network) EnumDescriptor() ([]byte, []int) {
 
 type EventLog struct {
 	// base info
-	EventId    string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
-	EventTime  int64  `protobuf:"varint,2,opt,name=event_time,json=eventTime,proto3" json:"event_time,omitempty"`
-	LoggedTime int64  `protobuf:"varint,3,opt,name=logged_time,json=loggedTime,proto3" json:"logged_time,omitempty"`
-	SessionId  string `protobuf:"bytes,4,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	Udid       string `protobuf:"bytes,5,opt,name=udid,proto3" json:"udid,omitempty"`
-	Tkid       string `protobuf:"bytes,6,opt,name=tkid,proto3" json:"tkid,omitempty"`
-	Mid        string `protobuf:"bytes,7,opt,name=mid,proto3" json:"mid,omitempty"`
-	Platform   string `protobuf:"bytes,8,opt,name=platform,proto3" json:"platform,omitempty"`
-	AppVersion string `protobuf:"bytes,9,opt,name=app_version,json=appVersion,proto3" json:"app_version,omitempty"`
-	AppChannel string `protobuf:"bytes,10,opt,name=app_channel,json=appChannel,proto3" json:"app_channel,omitempty"`
-	AppType    string `protobuf:"bytes,11,opt,name=app_type,json=appType,proto3" json:"app_type,omitempty"`
-	UserAgent  string `protobuf:"bytes,12,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
-	Event      string `protobuf:"bytes,13,opt,name=event,proto3" json:"event,omitempty"`
-	Env        string `protobuf:"bytes,14,opt,name=env,proto3" json:"env,omitempty"`
+	EventId            string `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	EventTime          int64  `protobuf:"varint,2,opt,name=event_time,json=eventTime,proto3" json:"event_time,omitempty"`
+	LoggedTime         int64  `protobuf:"varint,3,opt,name=logged_time,json=loggedTime,proto3" json:"logged_time,omitempty"`
+	SessionId          string `protobuf:"bytes,4,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Udid               string `protobuf:"bytes,5,opt,name=udid,proto3" json:"udid,omitempty"`
+	Tkid               string `protobuf:"bytes,6,opt,name=tkid,proto3" json:"tkid,omitempty"`
+	Mid                string `protobuf:"bytes,7,opt,name=mid,proto3" json:"mid,omitempty"`
+	Platform           string `protobuf:"bytes,8,opt,name=platform,proto3" json:"platform,omitempty"`
+	AppVersion         string `protobuf:"bytes,9,opt,name=app_version,json=appVersion,proto3" json:"app_version,omitempty"`
+	AppChannel         string `protobuf:"bytes,10,opt,name=app_channel,json=appChannel,proto3" json:"app_channel,omitempty"`
+	AppType            string `protobuf:"bytes,11,opt,name=app_type,json=appType,proto3" json:"app_type,omitempty"`
+	UserAgent          string `protobuf:"bytes,12,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	Event              string `protobuf:"bytes,13,opt,name=event,proto3" json:"event,omitempty"`
+	Env                string `protobuf:"bytes,14,opt,name=env,proto3" json:"env,omitempty"`
+	CorrectedEventTime int64  `protobuf:"varint,15,opt,name=corrected_event_time,json=correctedEventTime,proto3" json:"corrected_event_time,omitempty"`
+	ClockSkew          int64  `protobuf:"varint,16,opt,name=clock_skew,json=clockSkew,proto3" json:"clock_skew,omitempty"`
+	EventTimeFlag      string `protobuf:"bytes,17,opt,name=event_time_flag,json=eventTimeFlag,proto3" json:"event_time_flag,omitempty"`
 	// device info
 	Os               string           `protobuf:"bytes,20,opt,name=os,proto3" json:"os,omitempty"`
 	OsVersion        string           `protobuf:"bytes,21,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
@@ -127,6 +130,10 @@ type EventLog struct {
 	AndroidId        string           `protobuf:"bytes,30,opt,name=android_id,json=androidId,proto3" json:"android_id,omitempty"`
 	Idfa             string           `protobuf:"bytes,31,opt,name=idfa,proto3" json:"idfa,omitempty"`
 	Oaid             string           `protobuf:"bytes,32,opt,name=oaid,proto3" json:"oaid,omitempty"`
+	Browser          string           `protobuf:"bytes,33,opt,name=browser,proto3" json:"browser,omitempty"`
+	BrowserVersion   string           `protobuf:"bytes,34,opt,name=browser_version,json=browserVersion,proto3" json:"browser_version,omitempty"`
+	DeviceType       string           `protobuf:"bytes,35,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"`
+	Engine           string           `protobuf:"bytes,36,opt,name=engine,proto3" json:"engine,omitempty"`
 	Carrier          EventLog_Carrier `protobuf:"varint,40,opt,name=carrier,proto3,enum=EventLog_Carrier" json:"carrier,omitempty"`
 	Network          EventLog_Network `protobuf:"varint,41,opt,name=network,proto3,enum=EventLog_Network" json:"network,omitempty"`
 	Ip               string           `protobuf:"bytes,42,opt,name=ip,proto3" json:"ip,omitempty"`
@@ -286,6 +293,27 @@ func (m *EventLog) GetEnv() string {
 	return ""
 }
 
+func (m *EventLog) GetCorrectedEventTime() int64 {
+	if m != nil {
+		return m.CorrectedEventTime
+	}
+	return 0
+}
+
+func (m *EventLog) GetClockSkew() int64 {
+	if m != nil {
+		return m.ClockSkew
+	}
+	return 0
+}
+
+func (m *EventLog) GetEventTimeFlag() string {
+	if m != nil {
+		return m.EventTimeFlag
+	}
+	return ""
+}
+
 func (m *EventLog) GetOs() string {
 	if m != nil {
 		return m.Os
@@ -377,6 +405,34 @@ func (m *EventLog) GetOaid() string {
 	return ""
 }
 
+func (m *EventLog) GetBrowser() string {
+	if m != nil {
+		return m.Browser
+	}
+	return ""
+}
+
+func (m *EventLog) GetBrowserVersion() string {
+	if m != nil {
+		return m.BrowserVersion
+	}
+	return ""
+}
+
+func (m *EventLog) GetDeviceType() string {
+	if m != nil {
+		return m.DeviceType
+	}
+	return ""
+}
+
+func (m *EventLog) GetEngine() string {
+	if m != nil {
+		return m.Engine
+	}
+	return ""
+}
+
 func (m *EventLog) GetCarrier() EventLog_Carrier {
 	if m != nil {
 		return m.Carrier
@@ -784,8 +840,9 @@ func (m *EventLogCommon) GetMac() string {
 }
 
 type EventLogs struct {
-	Common *EventLogCommon `protobuf:"bytes,1,opt,name=common,proto3" json:"common,omitempty"`
-	Events []*EventLog     `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	Common   *EventLogCommon `protobuf:"bytes,1,opt,name=common,proto3" json:"common,omitempty"`
+	Events   []*EventLog     `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	SendTime int64           `protobuf:"varint,3,opt,name=send_time,json=sendTime,proto3" json:"send_time,omitempty"`
 }
 
 func (m *EventLogs) Reset()         { *m = EventLogs{} }
@@ -835,9 +892,20 @@ func (m *EventLogs) GetEvents() []*EventLog {
 	return nil
 }
 
+func (m *EventLogs) GetSendTime() int64 {
+	if m != nil {
+		return m.SendTime
+	}
+	return 0
+}
+
 type Response struct {
 	Code int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
 	Msg  string `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	// SubmitMultiple 批量提交结果
+	Accepted       int32            `protobuf:"varint,3,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected       int32            `protobuf:"varint,4,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	RejectedEvents []*RejectedEvent `protobuf:"bytes,5,rep,name=rejected_events,json=rejectedEvents,proto3" json:"rejected_events,omitempty"`
 }
 
 func (m *Response) Reset()         { *m = Response{} }
@@ -887,6 +955,96 @@ func (m *Response) GetMsg() string {
 	return ""
 }
 
+func (m *Response) GetAccepted() int32 {
+	if m != nil {
+		return m.Accepted
+	}
+	return 0
+}
+
+func (m *Response) GetRejected() int32 {
+	if m != nil {
+		return m.Rejected
+	}
+	return 0
+}
+
+func (m *Response) GetRejectedEvents() []*RejectedEvent {
+	if m != nil {
+		return m.RejectedEvents
+	}
+	return nil
+}
+
+// 被拒绝的事件
+type RejectedEvent struct {
+	Index   int32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	EventId string `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Code    int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Msg     string `protobuf:"bytes,4,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (m *RejectedEvent) Reset()         { *m = RejectedEvent{} }
+func (m *RejectedEvent) String() string { return proto.CompactTextString(m) }
+func (*RejectedEvent) ProtoMessage()    {}
+func (*RejectedEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_443313318a2fd90c, []int{4}
+}
+func (m *RejectedEvent) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RejectedEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RejectedEvent.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalTo(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RejectedEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RejectedEvent.Merge(m, src)
+}
+func (m *RejectedEvent) XXX_Size() int {
+	return m.Size()
+}
+func (m *RejectedEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_RejectedEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RejectedEvent proto.InternalMessageInfo
+
+func (m *RejectedEvent) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *RejectedEvent) GetEventId() string {
+	if m != nil {
+		return m.EventId
+	}
+	return ""
+}
+
+func (m *RejectedEvent) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *RejectedEvent) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
 type Empty struct {
 }
 
@@ -894,7 +1052,7 @@ func (m *Empty) Reset()         { *m = Empty{} }
 func (m *Empty) String() string { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()    {}
 func (*Empty) Descriptor() ([]byte, []int) {
-	return fileDescriptor_443313318a2fd90c, []int{4}
+	return fileDescriptor_443313318a2fd90c, []int{5}
 }
 func (m *Empty) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -931,96 +1089,110 @@ func init() {
 	proto.RegisterType((*EventLogCommon)(nil), "EventLogCommon")
 	proto.RegisterType((*EventLogs)(nil), "EventLogs")
 	proto.RegisterType((*Response)(nil), "Response")
+	proto.RegisterType((*RejectedEvent)(nil), "RejectedEvent")
 	proto.RegisterType((*Empty)(nil), "Empty")
 }
 
 func init() { proto.RegisterFile("event_log.proto", fileDescriptor_443313318a2fd90c) }
 
 var fileDescriptor_443313318a2fd90c = []byte{
-	// 1316 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0xcd, 0x6e, 0x1b, 0xb7,
-	0x13, 0xf7, 0x4a, 0x96, 0x64, 0x51, 0xb6, 0xac, 0x30, 0x5f, 0x8c, 0xf3, 0x8f, 0xa2, 0xe8, 0x7f,
-	0xa8, 0x9a, 0xb4, 0x52, 0xe0, 0xb4, 0x40, 0x90, 0xa2, 0x87, 0x44, 0x50, 0x5d, 0x21, 0xb1, 0xe3,
-	0xae, 0x9d, 0x18, 0xe8, 0x97, 0xb0, 0x16, 0xa9, 0x35, 0x91, 0x5d, 0x92, 0xe0, 0x72, 0x95, 0x2a,
-	0xc7, 0x3e, 0x41, 0x81, 0x3e, 0x43, 0xdf, 0xa3, 0xc7, 0x1c, 0x03, 0xf4, 0xd2, 0x63, 0x91, 0xf4,
-	0x01, 0xfa, 0x08, 0xc5, 0x90, 0xbb, 0xb2, 0x9d, 0x38, 0xb1, 0x7b, 0xe3, 0xfc, 0xe6, 0x83, 0xc3,
-	0xd9, 0x99, 0xf9, 0x2d, 0x5a, 0x65, 0x53, 0x26, 0xcc, 0x28, 0x92, 0x61, 0x57, 0x69, 0x69, 0xe4,
-	0xda, 0x20, 0xe4, 0xe6, 0x20, 0xdd, 0xef, 0x8e, 0x65, 0xdc, 0x8b, 0x99, 0x09, 0xa6, 0x4c, 0x27,
-	0xac, 0x67, 0x74, 0x9a, 0x24, 0x3d, 0xca, 0x26, 0x46, 0x33, 0xd6, 0x0b, 0xa5, 0x0c, 0x23, 0x66,
-	0x0e, 0xb8, 0xa6, 0x2a, 0xd0, 0x66, 0xd6, 0x0b, 0x84, 0x90, 0x26, 0x30, 0x5c, 0x8a, 0x24, 0x0b,
-	0x73, 0xd5, 0xd9, 0xf4, 0xac, 0xb4, 0x9f, 0x4e, 0x7a, 0x2c, 0x56, 0x66, 0xe6, 0x94, 0xed, 0xdf,
-	0x57, 0xd0, 0xd2, 0x00, 0xee, 0x7d, 0x24, 0x43, 0x7c, 0x05, 0x2d, 0xb9, 0x1c, 0x38, 0x25, 0x5e,
-	0xcb, 0xeb, 0x54, 0xfd, 0x8a, 0x95, 0x87, 0x14, 0x5f, 0x43, 0xc8, 0xa9, 0x0c, 0x8f, 0x19, 0x29,
-	0xb4, 0xbc, 0x4e, 0xd1, 0xaf, 0x5a, 0x64, 0x97, 0xc7, 0x0c, 0x5f, 0x47, 0xb5, 0x48, 0x86, 0x21,
-	0xa3, 0x4e, 0x5f, 0xb4, 0x7a, 0xe4, 0x20, 0x6b, 0x70, 0x0d, 0xa1, 0x84, 0x25, 0x09, 0x97, 0x02,
-	0x82, 0x2f, 0xda, 0xe0, 0xd5, 0x0c, 0x19, 0x52, 0x8c, 0xd1, 0x62, 0x4a, 0x39, 0x25, 0x25, 0xab,
-	0xb0, 0x67, 0xc0, 0xcc, 0x33, 0x4e, 0x49, 0xd9, 0x61, 0x70, 0xc6, 0x0d, 0x54, 0x8c, 0x39, 0x25,
-	0x15, 0x0b, 0xc1, 0x11, 0xaf, 0xa1, 0x25, 0x15, 0x05, 0x66, 0x22, 0x75, 0x4c, 0x96, 0x2c, 0x3c,
-	0x97, 0x21, 0xab, 0x40, 0xa9, 0x11, 0xd4, 0x8d, 0x4b, 0x41, 0xaa, 0x56, 0x8d, 0x02, 0xa5, 0x9e,
-	0x3a, 0x24, 0x37, 0x18, 0x1f, 0x04, 0x42, 0xb0, 0x88, 0xa0, 0xb9, 0x41, 0xdf, 0x21, 0x50, 0x11,
-	0x30, 0x30, 0x33, 0xc5, 0x48, 0xcd, 0x55, 0x24, 0x50, 0x6a, 0x77, 0xa6, 0xec, 0x8b, 0xd2, 0x84,
-	0xe9, 0x51, 0x10, 0x32, 0x61, 0xc8, 0xb2, 0x7b, 0x11, 0x20, 0xf7, 0x01, 0xc0, 0x17, 0x50, 0xc9,
-	0x96, 0x87, 0xac, 0x58, 0x8d, 0x13, 0x20, 0x7f, 0x26, 0xa6, 0xa4, 0xee, 0xf2, 0x67, 0x62, 0x8a,
-	0xeb, 0xa8, 0x20, 0x13, 0x72, 0xc1, 0x02, 0x05, 0x99, 0x40, 0x58, 0x99, 0xcc, 0x53, 0xbe, 0xe8,
-	0xc2, 0xca, 0x24, 0xcf, 0xf8, 0x06, 0x5a, 0xa6, 0x6c, 0xca, 0xc7, 0x6c, 0x14, 0x4b, 0xca, 0x22,
-	0x72, 0xc9, 0x1a, 0xd4, 0x1c, 0xb6, 0x09, 0x10, 0xfe, 0x3f, 0x5a, 0xc9, 0x4c, 0xa6, 0x4c, 0x50,
-	0xa9, 0xc9, 0x65, 0x6b, 0x93, 0xf9, 0x3d, 0xb5, 0xd8, 0x91, 0x38, 0xfb, 0x3a, 0x10, 0x94, 0x90,
-	0xa3, 0x71, 0x1e, 0x00, 0x04, 0xc5, 0x49, 0xc6, 0x9a, 0x31, 0x31, 0x4a, 0xf8, 0x0b, 0x46, 0xae,
-	0xb8, 0xe2, 0x38, 0x68, 0x87, 0xbf, 0x60, 0x10, 0x23, 0x33, 0x78, 0xce, 0xa9, 0x39, 0x20, 0x6b,
-	0x2d, 0xaf, 0x53, 0xf2, 0x33, 0xa7, 0x3d, 0x80, 0x20, 0x97, 0xcc, 0xe4, 0x80, 0xf1, 0xf0, 0xc0,
-	0x90, 0xab, 0xd6, 0x26, 0xf3, 0xfb, 0xda, 0x62, 0xf8, 0x16, 0x3a, 0x97, 0x19, 0x69, 0x96, 0xc8,
-	0x28, 0x85, 0xe6, 0x25, 0xff, 0xb3, 0xd7, 0x35, 0x9c, 0xc2, 0x9f, 0xe3, 0xd0, 0x15, 0x3c, 0x66,
-	0x9c, 0x5c, 0x73, 0x5d, 0x01, 0x67, 0xa8, 0x59, 0x20, 0xa8, 0x96, 0x9c, 0x42, 0x73, 0x35, 0x5d,
-	0xcd, 0x32, 0xc4, 0x35, 0x17, 0xa7, 0x93, 0x80, 0x5c, 0xcf, 0x5c, 0xe8, 0x24, 0x00, 0x4c, 0x06,
-	0x9c, 0x92, 0x96, 0xc3, 0xe0, 0x8c, 0x6f, 0xa1, 0xca, 0x38, 0xd0, 0x9a, 0x33, 0x4d, 0x3a, 0x2d,
-	0xaf, 0x53, 0x5f, 0x3f, 0xd7, 0xcd, 0x47, 0xa3, 0xdb, 0x77, 0x0a, 0x3f, 0xb7, 0x00, 0x63, 0xc1,
-	0xcc, 0x73, 0xa9, 0x9f, 0x91, 0x8f, 0xdf, 0x36, 0xde, 0x72, 0x0a, 0x3f, 0xb7, 0x80, 0x8f, 0xcc,
-	0x15, 0xb9, 0xe9, 0x3e, 0x32, 0x57, 0x90, 0x30, 0x57, 0xa3, 0xb1, 0x4c, 0x85, 0xd1, 0x33, 0x72,
-	0xcb, 0x25, 0xcc, 0x55, 0xdf, 0x01, 0x50, 0x79, 0xae, 0x46, 0x4a, 0xcb, 0x29, 0x17, 0x63, 0x46,
-	0x3e, 0x71, 0x95, 0xe7, 0x6a, 0x3b, 0x43, 0xf0, 0x65, 0x54, 0x01, 0x7f, 0x6e, 0x66, 0xe4, 0x53,
-	0xab, 0x2c, 0x73, 0xd5, 0xe7, 0x66, 0x06, 0xfd, 0x15, 0x49, 0x41, 0xba, 0xae, 0xbf, 0x22, 0x29,
-	0x2c, 0x12, 0x18, 0xd2, 0xcb, 0x90, 0xc0, 0xf6, 0x60, 0x1c, 0x8c, 0xc9, 0xed, 0x6c, 0x86, 0x82,
-	0x31, 0x84, 0x53, 0x41, 0xc8, 0xa0, 0x78, 0xdb, 0x2e, 0x1c, 0x88, 0x43, 0x8a, 0xcf, 0xa3, 0x92,
-	0x9a, 0x02, 0xfc, 0x8d, 0x2b, 0x93, 0x9a, 0x0e, 0x29, 0xbe, 0x8a, 0xaa, 0x51, 0x30, 0x93, 0xa9,
-	0x5d, 0x13, 0xbe, 0x1b, 0x39, 0x07, 0x0c, 0x29, 0x0c, 0x8c, 0x0d, 0xf5, 0x8c, 0xcd, 0xc8, 0x8e,
-	0x1b, 0x18, 0x90, 0x1f, 0xb2, 0x19, 0xf8, 0xc5, 0x92, 0xa6, 0x91, 0xbd, 0x67, 0xd7, 0xf9, 0x39,
-	0x60, 0x68, 0x9b, 0x2d, 0x18, 0xc3, 0x07, 0x76, 0xb3, 0xf6, 0x24, 0x9b, 0x44, 0x0b, 0xd9, 0x71,
-	0x6b, 0xa2, 0x9a, 0x66, 0x93, 0x51, 0x9e, 0xe7, 0xb7, 0xae, 0x66, 0x9a, 0x4d, 0xb6, 0x5d, 0xaa,
-	0x6b, 0xa8, 0x6a, 0xf5, 0x36, 0xdd, 0xef, 0xdc, 0xcd, 0xa0, 0x85, 0x8c, 0xdb, 0x68, 0x05, 0x74,
-	0x87, 0x59, 0x7f, 0xef, 0xba, 0x5d, 0xb3, 0xc9, 0xa3, 0x3c, 0xf1, 0x16, 0x5a, 0x9e, 0xc7, 0x87,
-	0xe4, 0x7f, 0x70, 0x19, 0x64, 0x17, 0x40, 0xfe, 0x59, 0x94, 0xc3, 0x37, 0xfc, 0x38, 0x8f, 0xb2,
-	0x99, 0x3f, 0x83, 0x20, 0xb8, 0x94, 0x69, 0xa6, 0xc9, 0x78, 0x9e, 0x03, 0x88, 0xb0, 0xa7, 0x68,
-	0xaa, 0xed, 0x62, 0x26, 0xd4, 0xae, 0xc7, 0xb9, 0x8c, 0xbf, 0x40, 0x35, 0xf6, 0x93, 0x61, 0x82,
-	0x8e, 0xb8, 0x98, 0x48, 0xf2, 0xd2, 0x6b, 0x15, 0x3b, 0xb5, 0xf5, 0x2b, 0x87, 0x0d, 0x35, 0xb0,
-	0xda, 0xa1, 0x98, 0xc8, 0x01, 0x34, 0x88, 0x8f, 0xd8, 0x1c, 0x58, 0xfb, 0x12, 0xad, 0xbe, 0xa5,
-	0x86, 0x2f, 0x0c, 0x4f, 0x70, 0x2b, 0x1c, 0x8e, 0xb0, 0x8d, 0xa6, 0x41, 0x94, 0xba, 0xcd, 0x5d,
-	0xf5, 0x9d, 0x70, 0xaf, 0x70, 0xd7, 0x6b, 0x6f, 0xa1, 0x4a, 0xd6, 0xdb, 0xf8, 0x3c, 0x5a, 0xed,
-	0xdf, 0xf7, 0xfd, 0xe1, 0xc0, 0x1f, 0x3d, 0xd9, 0x7a, 0xb8, 0xf5, 0x78, 0x6f, 0xab, 0xb1, 0x80,
-	0xeb, 0x08, 0xe5, 0x60, 0x7f, 0xb3, 0xe1, 0x1d, 0x93, 0x9f, 0x34, 0x0a, 0xc7, 0xe4, 0xdd, 0x46,
-	0xb1, 0xad, 0x50, 0x25, 0x6b, 0x7f, 0x88, 0xb7, 0x35, 0xd8, 0xdd, 0x7b, 0xec, 0x3f, 0x3c, 0x12,
-	0xaf, 0x81, 0x96, 0x73, 0x70, 0x6f, 0xf8, 0xd5, 0xd0, 0x45, 0xcc, 0x91, 0xf5, 0x0d, 0x17, 0x31,
-	0x97, 0xef, 0x6c, 0x34, 0x8a, 0x47, 0xe5, 0xcf, 0x36, 0x1a, 0x8b, 0x47, 0xe5, 0xcf, 0x37, 0x1a,
-	0xa5, 0xf6, 0x3f, 0x25, 0x54, 0xcf, 0x2b, 0xd5, 0x97, 0x71, 0xec, 0x96, 0x84, 0xa5, 0x13, 0xef,
-	0x04, 0x3a, 0x29, 0xbc, 0x4b, 0x27, 0xc5, 0x93, 0xe9, 0x64, 0xf1, 0xc3, 0x74, 0x52, 0x3a, 0x8d,
-	0x4e, 0xca, 0x1f, 0xa4, 0x93, 0xca, 0x71, 0x3a, 0xc9, 0x98, 0x61, 0xe9, 0x6d, 0x66, 0x40, 0xef,
-	0x61, 0x86, 0xda, 0x69, 0xcc, 0xb0, 0x7c, 0x06, 0x66, 0x58, 0x39, 0x03, 0x33, 0xd4, 0x4f, 0x65,
-	0x86, 0xd5, 0x53, 0x99, 0xa1, 0x71, 0x06, 0x66, 0x38, 0x77, 0x56, 0x66, 0xc0, 0xa7, 0x30, 0xc3,
-	0xf9, 0xf7, 0x32, 0xc3, 0x85, 0xf7, 0x31, 0xc3, 0xc5, 0x13, 0x98, 0xe1, 0xd2, 0xc9, 0xcc, 0xd0,
-	0xfc, 0x2f, 0xcc, 0x70, 0xfd, 0x54, 0x66, 0xc8, 0x16, 0x76, 0xeb, 0x9d, 0x85, 0x7d, 0xe3, 0x9d,
-	0x85, 0xdd, 0x9e, 0x2f, 0xec, 0xf6, 0x1e, 0xaa, 0xe6, 0x21, 0x13, 0xfc, 0x11, 0x2a, 0x8f, 0x6d,
-	0xdb, 0xdb, 0x76, 0xaf, 0xad, 0xaf, 0x76, 0x8f, 0x4f, 0x83, 0x9f, 0xa9, 0xf1, 0x0d, 0x54, 0xb6,
-	0x7f, 0x21, 0x09, 0x29, 0xd8, 0x05, 0x53, 0x9d, 0x1b, 0xfa, 0x99, 0xa2, 0x7d, 0x1b, 0x2d, 0xf9,
-	0x2c, 0x51, 0x52, 0x24, 0x0c, 0x0a, 0x31, 0x96, 0x94, 0xd9, 0xa8, 0x25, 0xdf, 0x9e, 0x6d, 0x2a,
-	0x49, 0x98, 0xcd, 0x10, 0x1c, 0xdb, 0x15, 0x54, 0x1a, 0xc0, 0xff, 0xe4, 0xfa, 0x6f, 0x1e, 0x42,
-	0x8f, 0x64, 0xb8, 0xc3, 0x34, 0x34, 0x0a, 0xbe, 0x83, 0x96, 0x77, 0xd2, 0xfd, 0x98, 0x9b, 0x1d,
-	0x2e, 0xc2, 0x88, 0xe1, 0xc3, 0xcb, 0xd6, 0xaa, 0xdd, 0xfc, 0x8e, 0xf6, 0xca, 0xcf, 0x7f, 0xfc,
-	0xfd, 0x6b, 0xa1, 0xd2, 0x2e, 0xf4, 0x92, 0x7b, 0xde, 0x4d, 0x7c, 0x17, 0xd5, 0x9d, 0xd3, 0x66,
-	0x1a, 0x19, 0xae, 0x22, 0x86, 0xd1, 0xdc, 0x2d, 0x39, 0xea, 0xb7, 0x6a, 0xfd, 0xaa, 0xed, 0xc5,
-	0x5e, 0x9c, 0x46, 0xe0, 0xd9, 0x41, 0x8b, 0xdb, 0x5c, 0x84, 0xb8, 0xdc, 0xb5, 0xd9, 0x9c, 0x70,
-	0x07, 0x2e, 0xf5, 0x14, 0x17, 0xe1, 0x03, 0xf2, 0xf2, 0x75, 0xd3, 0x7b, 0xf5, 0xba, 0xe9, 0xfd,
-	0xf5, 0xba, 0xe9, 0xfd, 0xf2, 0xa6, 0xb9, 0xf0, 0xea, 0x4d, 0x73, 0xe1, 0xcf, 0x37, 0xcd, 0x85,
-	0xfd, 0xb2, 0xfd, 0x25, 0xbe, 0xf3, 0x6f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x58, 0x3f, 0x5f, 0x70,
-	0x89, 0x0b, 0x00, 0x00,
+	// 1524 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x57, 0xdd, 0x72, 0x13, 0xc7,
+	0x12, 0xb6, 0x24, 0xcb, 0xb2, 0x46, 0xb6, 0x24, 0x0f, 0x06, 0x06, 0x73, 0x2c, 0x64, 0x71, 0xea,
+	0xe0, 0x03, 0xe7, 0x48, 0x94, 0x39, 0xa7, 0x42, 0x91, 0xca, 0x05, 0xb8, 0x84, 0xa3, 0x02, 0x1b,
+	0x67, 0x6d, 0x70, 0x55, 0xfe, 0x54, 0xeb, 0xdd, 0xd6, 0x7a, 0xe2, 0xdd, 0x9d, 0xad, 0xd9, 0x95,
+	0x8c, 0xb8, 0xcc, 0x13, 0xa4, 0x2a, 0x4f, 0x90, 0x8b, 0x3c, 0x42, 0xde, 0x81, 0x4b, 0xaa, 0x72,
+	0x93, 0xcb, 0x14, 0xe4, 0x01, 0xf2, 0x08, 0xa9, 0x9e, 0x99, 0x5d, 0xcb, 0xd8, 0x60, 0xe7, 0x6e,
+	0xfa, 0xeb, 0x9e, 0x9e, 0xee, 0x9e, 0x9e, 0xfe, 0x76, 0x49, 0x0d, 0x46, 0x10, 0x26, 0x7d, 0x5f,
+	0x78, 0xed, 0x48, 0x8a, 0x44, 0x2c, 0x75, 0x3d, 0x9e, 0x1c, 0x0c, 0xf7, 0xdb, 0x8e, 0x08, 0x3a,
+	0x01, 0x24, 0xf6, 0x08, 0x64, 0x0c, 0x9d, 0x44, 0x0e, 0xe3, 0xb8, 0xe3, 0xc2, 0x20, 0x91, 0x00,
+	0x1d, 0x4f, 0x08, 0xcf, 0x87, 0xe4, 0x80, 0x4b, 0x37, 0xb2, 0x65, 0x32, 0xee, 0xd8, 0x61, 0x28,
+	0x12, 0x3b, 0xe1, 0x22, 0x8c, 0x8d, 0x9b, 0xeb, 0xda, 0xa6, 0xa3, 0xa4, 0xfd, 0xe1, 0xa0, 0x03,
+	0x41, 0x94, 0x8c, 0xb5, 0xb2, 0xf5, 0x4b, 0x8d, 0xcc, 0x76, 0xf1, 0xdc, 0xa7, 0xc2, 0xa3, 0xd7,
+	0xc8, 0xac, 0x8e, 0x81, 0xbb, 0x2c, 0xd7, 0xcc, 0xad, 0x96, 0xad, 0x92, 0x92, 0x7b, 0x2e, 0x5d,
+	0x26, 0x44, 0xab, 0x12, 0x1e, 0x00, 0xcb, 0x37, 0x73, 0xab, 0x05, 0xab, 0xac, 0x90, 0x5d, 0x1e,
+	0x00, 0xbd, 0x41, 0x2a, 0xbe, 0xf0, 0x3c, 0x70, 0xb5, 0xbe, 0xa0, 0xf4, 0x44, 0x43, 0xca, 0x60,
+	0x99, 0x90, 0x18, 0xe2, 0x98, 0x8b, 0x10, 0x9d, 0x4f, 0x2b, 0xe7, 0x65, 0x83, 0xf4, 0x5c, 0x4a,
+	0xc9, 0xf4, 0xd0, 0xe5, 0x2e, 0x2b, 0x2a, 0x85, 0x5a, 0x23, 0x96, 0x1c, 0x72, 0x97, 0xcd, 0x68,
+	0x0c, 0xd7, 0xb4, 0x4e, 0x0a, 0x01, 0x77, 0x59, 0x49, 0x41, 0xb8, 0xa4, 0x4b, 0x64, 0x36, 0xf2,
+	0xed, 0x64, 0x20, 0x64, 0xc0, 0x66, 0x15, 0x9c, 0xc9, 0x18, 0x95, 0x1d, 0x45, 0x7d, 0xac, 0x1b,
+	0x17, 0x21, 0x2b, 0x2b, 0x35, 0xb1, 0xa3, 0xe8, 0x85, 0x46, 0x52, 0x03, 0xe7, 0xc0, 0x0e, 0x43,
+	0xf0, 0x19, 0xc9, 0x0c, 0xd6, 0x35, 0x82, 0x15, 0x41, 0x83, 0x64, 0x1c, 0x01, 0xab, 0xe8, 0x8a,
+	0xd8, 0x51, 0xb4, 0x3b, 0x8e, 0x54, 0x46, 0xc3, 0x18, 0x64, 0xdf, 0xf6, 0x20, 0x4c, 0xd8, 0x9c,
+	0xce, 0x08, 0x91, 0x87, 0x08, 0xd0, 0x45, 0x52, 0x54, 0xe5, 0x61, 0xf3, 0x4a, 0xa3, 0x05, 0x8c,
+	0x1f, 0xc2, 0x11, 0xab, 0xea, 0xf8, 0x21, 0x1c, 0xd1, 0xbb, 0x64, 0xd1, 0x11, 0x52, 0x82, 0x93,
+	0x80, 0xdb, 0x9f, 0x28, 0x71, 0x4d, 0x95, 0x90, 0x66, 0xba, 0x6e, 0x56, 0xeb, 0x65, 0x42, 0x1c,
+	0x5f, 0x38, 0x87, 0xfd, 0xf8, 0x10, 0x8e, 0x58, 0x5d, 0x5f, 0x85, 0x42, 0x76, 0x0e, 0xe1, 0x88,
+	0xfe, 0x8b, 0xd4, 0x8e, 0xdd, 0xf4, 0x07, 0xbe, 0xed, 0xb1, 0x05, 0x75, 0xdc, 0x7c, 0x76, 0x5d,
+	0x8f, 0x7d, 0xdb, 0xa3, 0x55, 0x92, 0x17, 0x31, 0x5b, 0x54, 0xaa, 0xbc, 0x88, 0xd1, 0xad, 0x88,
+	0xb3, 0x5a, 0x5d, 0xd6, 0xf9, 0x88, 0x38, 0x2d, 0xd5, 0x0a, 0x99, 0x73, 0x61, 0xc4, 0x1d, 0xe8,
+	0x07, 0xc2, 0x05, 0x9f, 0x5d, 0x51, 0x06, 0x15, 0x8d, 0x6d, 0x22, 0x44, 0x6f, 0x92, 0x79, 0x63,
+	0x32, 0x82, 0xd0, 0x15, 0x92, 0x5d, 0x55, 0x36, 0x66, 0xdf, 0x0b, 0x85, 0x4d, 0xf8, 0xd9, 0x97,
+	0x76, 0xe8, 0x32, 0x36, 0xe9, 0xe7, 0x11, 0x42, 0x78, 0x2b, 0xb1, 0x23, 0x01, 0xc2, 0x7e, 0xcc,
+	0x5f, 0x01, 0xbb, 0xa6, 0x6f, 0x45, 0x43, 0x3b, 0xfc, 0x15, 0xa0, 0x0f, 0x63, 0x70, 0xc4, 0xdd,
+	0xe4, 0x80, 0x2d, 0x35, 0x73, 0xab, 0x45, 0xcb, 0x6c, 0xda, 0x43, 0x08, 0x63, 0x31, 0x26, 0x07,
+	0xc0, 0xbd, 0x83, 0x84, 0x5d, 0x57, 0x36, 0x66, 0xdf, 0xe7, 0x0a, 0xa3, 0x77, 0xc8, 0x82, 0x31,
+	0x92, 0x10, 0x0b, 0x7f, 0x88, 0xaf, 0x86, 0xfd, 0x43, 0x1d, 0x57, 0xd7, 0x0a, 0x2b, 0xc3, 0xb1,
+	0x1d, 0x79, 0x00, 0x9c, 0x2d, 0xeb, 0x76, 0xc4, 0x35, 0xd6, 0xcc, 0x0e, 0x5d, 0x29, 0xb8, 0x8b,
+	0x5d, 0xdd, 0xd0, 0x35, 0x33, 0x88, 0xee, 0x6a, 0xee, 0x0e, 0x6c, 0x76, 0xc3, 0x6c, 0x71, 0x07,
+	0x36, 0x62, 0xc2, 0xe6, 0x2e, 0x6b, 0x6a, 0x0c, 0xd7, 0x94, 0x91, 0xd2, 0xbe, 0x14, 0x47, 0x31,
+	0x48, 0xb6, 0xa2, 0x9b, 0xcc, 0x88, 0xf4, 0x16, 0xa9, 0x99, 0x65, 0x76, 0x33, 0x2d, 0x65, 0x51,
+	0x35, 0xf0, 0x44, 0x27, 0x9b, 0xb2, 0xaa, 0x5e, 0xbd, 0xa9, 0x6b, 0xa6, 0x21, 0xd5, 0xae, 0x57,
+	0xc8, 0x0c, 0x84, 0x1e, 0x0f, 0x81, 0xfd, 0x53, 0xe9, 0x8c, 0x44, 0xef, 0x90, 0x92, 0x63, 0x4b,
+	0xc9, 0x41, 0xb2, 0xd5, 0x66, 0x6e, 0xb5, 0xba, 0xb6, 0xd0, 0x4e, 0xe7, 0x41, 0x7b, 0x5d, 0x2b,
+	0xac, 0xd4, 0x02, 0x8d, 0x43, 0x48, 0x8e, 0x84, 0x3c, 0x64, 0xff, 0x7e, 0xdf, 0x78, 0x4b, 0x2b,
+	0xac, 0xd4, 0x02, 0x1b, 0x8c, 0x47, 0xec, 0xb6, 0x6e, 0x30, 0x1e, 0x61, 0xb1, 0x78, 0xd4, 0x77,
+	0xc4, 0x30, 0x4c, 0xe4, 0x98, 0xdd, 0xd1, 0xc5, 0xe2, 0xd1, 0xba, 0x06, 0x30, 0x03, 0x1e, 0xf5,
+	0x23, 0x29, 0x46, 0x3c, 0x74, 0x80, 0xfd, 0x47, 0x67, 0xc0, 0xa3, 0x6d, 0x83, 0xd0, 0xab, 0xa4,
+	0x84, 0xfb, 0x79, 0x32, 0x66, 0xff, 0xd5, 0x29, 0xf0, 0x68, 0x9d, 0x27, 0x63, 0x7c, 0x54, 0xbe,
+	0x08, 0x59, 0x5b, 0x3f, 0x2a, 0x5f, 0x84, 0x0a, 0xb1, 0x13, 0xd6, 0x31, 0x88, 0xad, 0x1e, 0x5e,
+	0x60, 0x3b, 0xec, 0xae, 0x46, 0x02, 0xdb, 0x41, 0x77, 0x91, 0xed, 0x01, 0x5e, 0xdc, 0xb6, 0x76,
+	0x87, 0x62, 0xcf, 0xa5, 0x97, 0x48, 0x31, 0x1a, 0x21, 0xfc, 0x85, 0xbe, 0xa2, 0x68, 0xd4, 0x73,
+	0xe9, 0x75, 0x52, 0xf6, 0xed, 0xb1, 0x18, 0xaa, 0xd9, 0x68, 0xe9, 0x39, 0xa3, 0x81, 0x9e, 0x8b,
+	0x53, 0x42, 0xb9, 0x3a, 0x84, 0x31, 0xdb, 0xd1, 0x17, 0x88, 0xf2, 0x13, 0x18, 0xe3, 0xbe, 0x40,
+	0xb8, 0x43, 0x5f, 0x9d, 0xb3, 0xab, 0xf7, 0x69, 0xa0, 0xa7, 0x1a, 0xdd, 0x76, 0xb0, 0xb9, 0xf4,
+	0xa5, 0x3d, 0x37, 0xe3, 0x47, 0x41, 0xea, 0xd2, 0x1a, 0xa4, 0x22, 0x61, 0xd0, 0x4f, 0xe3, 0xfc,
+	0x52, 0xd7, 0x4c, 0xc2, 0x60, 0x5b, 0x87, 0xba, 0x44, 0xca, 0x4a, 0xaf, 0xc2, 0xfd, 0x4a, 0x9f,
+	0x8c, 0x5a, 0x8c, 0xb8, 0x45, 0xe6, 0x51, 0x77, 0x1c, 0xf5, 0xd7, 0xfa, 0xa5, 0x49, 0x18, 0x3c,
+	0x4d, 0x03, 0x6f, 0x92, 0xb9, 0xcc, 0x3f, 0x06, 0xff, 0x8d, 0x8e, 0xc0, 0x1c, 0x80, 0xf1, 0x1b,
+	0x2f, 0xc7, 0x39, 0x7c, 0x9b, 0x79, 0xd9, 0x4c, 0xd3, 0x60, 0x04, 0x0f, 0x05, 0x09, 0x92, 0x39,
+	0x59, 0x0c, 0x28, 0xe2, 0x70, 0x76, 0x87, 0x52, 0xb1, 0x11, 0x73, 0xd5, 0xa0, 0xca, 0x64, 0xfa,
+	0x29, 0xa9, 0xc0, 0xcb, 0x04, 0x42, 0xb7, 0xcf, 0xc3, 0x81, 0x60, 0xaf, 0x73, 0xcd, 0xc2, 0x6a,
+	0x65, 0xed, 0xda, 0x71, 0x43, 0x75, 0x95, 0xb6, 0x17, 0x0e, 0x44, 0x17, 0x1b, 0xc4, 0x22, 0x90,
+	0x01, 0x4b, 0x9f, 0x91, 0xda, 0x7b, 0x6a, 0xbc, 0x61, 0x4c, 0x41, 0xf3, 0x16, 0x2e, 0x71, 0x04,
+	0x8f, 0x6c, 0x7f, 0xa8, 0xe9, 0xaa, 0x6c, 0x69, 0xe1, 0x41, 0xfe, 0x7e, 0xae, 0xb5, 0x45, 0x4a,
+	0xa6, 0xb7, 0xe9, 0x25, 0x52, 0x5b, 0x7f, 0x68, 0x59, 0xbd, 0xae, 0xd5, 0x7f, 0xbe, 0xf5, 0x64,
+	0xeb, 0xd9, 0xde, 0x56, 0x7d, 0x8a, 0x56, 0x09, 0x49, 0xc1, 0xf5, 0xcd, 0x7a, 0xee, 0x84, 0xfc,
+	0xbc, 0x9e, 0x3f, 0x21, 0xef, 0xd6, 0x0b, 0xad, 0x88, 0x94, 0x4c, 0xfb, 0xa3, 0xbf, 0xad, 0xee,
+	0xee, 0xde, 0x33, 0xeb, 0xc9, 0x84, 0xbf, 0x3a, 0x99, 0x4b, 0xc1, 0xbd, 0xde, 0xe3, 0x9e, 0xf6,
+	0x98, 0x22, 0x6b, 0x1b, 0xf5, 0xfc, 0xa4, 0x7c, 0x6f, 0xa3, 0x5e, 0x98, 0x94, 0xff, 0xb7, 0x51,
+	0x9f, 0x9e, 0x94, 0xff, 0xbf, 0x51, 0x2f, 0xb6, 0xfe, 0x2c, 0x92, 0x6a, 0x5a, 0xa9, 0x75, 0x11,
+	0x04, 0x7a, 0x40, 0x29, 0x0e, 0xcd, 0x9d, 0xc1, 0xa1, 0xf9, 0xd3, 0x1c, 0x5a, 0x38, 0x9b, 0x43,
+	0xa7, 0x3f, 0xce, 0xa1, 0xc5, 0xf3, 0x38, 0x74, 0xe6, 0xa3, 0x1c, 0x5a, 0x3a, 0xc9, 0xa1, 0x86,
+	0x0e, 0x67, 0x8f, 0xe9, 0x50, 0xb3, 0x12, 0xf9, 0x00, 0x2b, 0x55, 0xce, 0x63, 0xa5, 0xb9, 0x0b,
+	0xb0, 0xd2, 0xfc, 0x05, 0x58, 0xa9, 0x7a, 0x2e, 0x2b, 0xd5, 0xce, 0x65, 0xa5, 0xfa, 0x05, 0x58,
+	0x69, 0xe1, 0xa2, 0xac, 0x44, 0xcf, 0x61, 0xa5, 0x4b, 0x1f, 0x64, 0xa5, 0xc5, 0x0f, 0xb1, 0xd2,
+	0xe5, 0x33, 0x58, 0xe9, 0xca, 0x04, 0x2b, 0x4d, 0x30, 0x43, 0xe3, 0xef, 0x30, 0xc3, 0x8d, 0x73,
+	0x99, 0xc1, 0x0c, 0xec, 0xe6, 0xa9, 0x81, 0xbd, 0x72, 0x6a, 0x60, 0xb7, 0xb2, 0x81, 0xdd, 0x4a,
+	0x48, 0x39, 0x75, 0x19, 0xd3, 0x5b, 0x64, 0xc6, 0x51, 0x6d, 0xaf, 0xda, 0xbd, 0xb2, 0x56, 0x6b,
+	0x9f, 0x7c, 0x0d, 0x96, 0x51, 0xd3, 0x15, 0x32, 0xa3, 0xbe, 0x7b, 0x62, 0x96, 0x57, 0x03, 0xa6,
+	0x9c, 0x19, 0x5a, 0x46, 0x81, 0x33, 0x3a, 0xc6, 0x39, 0x34, 0xf1, 0xe9, 0x3a, 0x8b, 0x00, 0x7e,
+	0x2a, 0xb5, 0x7e, 0xca, 0x91, 0x59, 0x0b, 0xe2, 0x48, 0x84, 0x31, 0x60, 0x99, 0x1c, 0xe1, 0x82,
+	0x3a, 0xb3, 0x68, 0xa9, 0xb5, 0x0a, 0x34, 0xf6, 0xcc, 0x0b, 0xc3, 0x25, 0x3e, 0x27, 0xdb, 0x71,
+	0x20, 0x4a, 0x40, 0xbf, 0xb2, 0xa2, 0x95, 0xc9, 0xa8, 0x93, 0xf0, 0x9d, 0xfa, 0xa2, 0x53, 0x4f,
+	0xad, 0x68, 0x65, 0x32, 0xfd, 0x84, 0xd4, 0xd2, 0x75, 0xdf, 0xc4, 0x5c, 0x54, 0x31, 0x57, 0xdb,
+	0x96, 0xc1, 0x55, 0xec, 0x56, 0x55, 0x4e, 0x8a, 0x71, 0x6b, 0x40, 0xe6, 0x4f, 0x18, 0xe0, 0xe4,
+	0xe3, 0xa1, 0x0b, 0x2f, 0x4d, 0xa0, 0x5a, 0x38, 0xf1, 0x79, 0x9f, 0x3f, 0xf9, 0x79, 0x9f, 0x26,
+	0x56, 0x38, 0x9d, 0xd8, 0x74, 0x96, 0x58, 0xab, 0x44, 0x8a, 0x5d, 0xfc, 0x77, 0x58, 0xfb, 0x39,
+	0x47, 0xc8, 0x53, 0xe1, 0xed, 0x80, 0xc4, 0xf7, 0x41, 0xef, 0x91, 0xb9, 0x9d, 0xe1, 0x7e, 0xc0,
+	0x93, 0x1d, 0x1e, 0x7a, 0x3e, 0xd0, 0xe3, 0x1a, 0x2f, 0x95, 0xdb, 0x69, 0xf1, 0x5a, 0xf3, 0xdf,
+	0xff, 0xfa, 0xc7, 0x8f, 0xf9, 0xd2, 0x83, 0xdc, 0xed, 0x56, 0xbe, 0x13, 0xd3, 0xfb, 0xa4, 0xaa,
+	0x37, 0x6d, 0x0e, 0xfd, 0x84, 0x47, 0x3e, 0x50, 0x92, 0x6d, 0x8b, 0x27, 0xf7, 0xd5, 0xd4, 0xbe,
+	0x32, 0xee, 0x9b, 0xee, 0x04, 0x43, 0x9f, 0xae, 0x92, 0xe9, 0x6d, 0x1e, 0x7a, 0x74, 0xa6, 0xad,
+	0xa2, 0x39, 0xe3, 0x0c, 0x5a, 0xec, 0x44, 0x3c, 0xf4, 0x1e, 0xb1, 0xd7, 0x6f, 0x1b, 0xb9, 0x37,
+	0x6f, 0x1b, 0xb9, 0xdf, 0xdf, 0x36, 0x72, 0x3f, 0xbc, 0x6b, 0x4c, 0xbd, 0x79, 0xd7, 0x98, 0xfa,
+	0xed, 0x5d, 0x63, 0x6a, 0x7f, 0x46, 0xfd, 0xfe, 0xdc, 0xfb, 0x6b, 0x00, 0xce, 0x4d, 0xe4, 0x54,
+	0x75, 0x0d, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1272,6 +1444,26 @@ func (m *EventLog) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Env)))
 		i += copy(dAtA[i:], m.Env)
 	}
+	if m.CorrectedEventTime != 0 {
+		dAtA[i] = 0x78
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.CorrectedEventTime))
+	}
+	if m.ClockSkew != 0 {
+		dAtA[i] = 0x80
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.ClockSkew))
+	}
+	if len(m.EventTimeFlag) > 0 {
+		dAtA[i] = 0x8a
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.EventTimeFlag)))
+		i += copy(dAtA[i:], m.EventTimeFlag)
+	}
 	if len(m.Os) > 0 {
 		dAtA[i] = 0xa2
 		i++
@@ -1374,6 +1566,38 @@ func (m *EventLog) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Oaid)))
 		i += copy(dAtA[i:], m.Oaid)
 	}
+	if len(m.Browser) > 0 {
+		dAtA[i] = 0x8a
+		i++
+		dAtA[i] = 0x2
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Browser)))
+		i += copy(dAtA[i:], m.Browser)
+	}
+	if len(m.BrowserVersion) > 0 {
+		dAtA[i] = 0x92
+		i++
+		dAtA[i] = 0x2
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.BrowserVersion)))
+		i += copy(dAtA[i:], m.BrowserVersion)
+	}
+	if len(m.DeviceType) > 0 {
+		dAtA[i] = 0x9a
+		i++
+		dAtA[i] = 0x2
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.DeviceType)))
+		i += copy(dAtA[i:], m.DeviceType)
+	}
+	if len(m.Engine) > 0 {
+		dAtA[i] = 0xa2
+		i++
+		dAtA[i] = 0x2
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Engine)))
+		i += copy(dAtA[i:], m.Engine)
+	}
 	if m.Carrier != 0 {
 		dAtA[i] = 0xc0
 		i++
@@ -1800,6 +2024,11 @@ func (m *EventLogs) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if m.SendTime != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.SendTime))
+	}
 	return i, nil
 }
 
@@ -1829,6 +2058,68 @@ func (m *Response) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Msg)))
 		i += copy(dAtA[i:], m.Msg)
 	}
+	if m.Accepted != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.Accepted))
+	}
+	if m.Rejected != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.Rejected))
+	}
+	if len(m.RejectedEvents) > 0 {
+		for _, msg := range m.RejectedEvents {
+			dAtA[i] = 0x2a
+			i++
+			i = encodeVarintEventLog(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
+}
+
+func (m *RejectedEvent) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RejectedEvent) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Index != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.Index))
+	}
+	if len(m.EventId) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.EventId)))
+		i += copy(dAtA[i:], m.EventId)
+	}
+	if m.Code != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(m.Code))
+	}
+	if len(m.Msg) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEventLog(dAtA, i, uint64(len(m.Msg)))
+		i += copy(dAtA[i:], m.Msg)
+	}
 	return i, nil
 }
 
@@ -1919,6 +2210,16 @@ func (m *EventLog) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovEventLog(uint64(l))
 	}
+	if m.CorrectedEventTime != 0 {
+		n += 1 + sovEventLog(uint64(m.CorrectedEventTime))
+	}
+	if m.ClockSkew != 0 {
+		n += 2 + sovEventLog(uint64(m.ClockSkew))
+	}
+	l = len(m.EventTimeFlag)
+	if l > 0 {
+		n += 2 + l + sovEventLog(uint64(l))
+	}
 	l = len(m.Os)
 	if l > 0 {
 		n += 2 + l + sovEventLog(uint64(l))
@@ -1969,6 +2270,22 @@ func (m *EventLog) Size() (n int) {
 	if l > 0 {
 		n += 2 + l + sovEventLog(uint64(l))
 	}
+	l = len(m.Browser)
+	if l > 0 {
+		n += 2 + l + sovEventLog(uint64(l))
+	}
+	l = len(m.BrowserVersion)
+	if l > 0 {
+		n += 2 + l + sovEventLog(uint64(l))
+	}
+	l = len(m.DeviceType)
+	if l > 0 {
+		n += 2 + l + sovEventLog(uint64(l))
+	}
+	l = len(m.Engine)
+	if l > 0 {
+		n += 2 + l + sovEventLog(uint64(l))
+	}
 	if m.Carrier != 0 {
 		n += 2 + sovEventLog(uint64(m.Carrier))
 	}
@@ -2190,6 +2507,9 @@ func (m *EventLogs) Size() (n int) {
 			n += 1 + l + sovEventLog(uint64(l))
 		}
 	}
+	if m.SendTime != 0 {
+		n += 1 + sovEventLog(uint64(m.SendTime))
+	}
 	return n
 }
 
@@ -2206,6 +2526,41 @@ func (m *Response) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovEventLog(uint64(l))
 	}
+	if m.Accepted != 0 {
+		n += 1 + sovEventLog(uint64(m.Accepted))
+	}
+	if m.Rejected != 0 {
+		n += 1 + sovEventLog(uint64(m.Rejected))
+	}
+	if len(m.RejectedEvents) > 0 {
+		for _, e := range m.RejectedEvents {
+			l = e.Size()
+			n += 1 + l + sovEventLog(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *RejectedEvent) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Index != 0 {
+		n += 1 + sovEventLog(uint64(m.Index))
+	}
+	l = len(m.EventId)
+	if l > 0 {
+		n += 1 + l + sovEventLog(uint64(l))
+	}
+	if m.Code != 0 {
+		n += 1 + sovEventLog(uint64(m.Code))
+	}
+	l = len(m.Msg)
+	if l > 0 {
+		n += 1 + l + sovEventLog(uint64(l))
+	}
 	return n
 }
 
@@ -2675,11 +3030,11 @@ func (m *EventLog) Unmarshal(dAtA []byte) error {
 			}
 			m.Env = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 20:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Os", wireType)
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CorrectedEventTime", wireType)
 			}
-			var stringLen uint64
+			m.CorrectedEventTime = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEventLog
@@ -2689,29 +3044,99 @@ func (m *EventLog) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.CorrectedEventTime |= int64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthEventLog
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthEventLog
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Os = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 21:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field OsVersion", wireType)
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClockSkew", wireType)
 			}
-			var stringLen uint64
+			m.ClockSkew = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClockSkew |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 17:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EventTimeFlag", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EventTimeFlag = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Os", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Os = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OsVersion", wireType)
+			}
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEventLog
@@ -3065,6 +3490,134 @@ func (m *EventLog) Unmarshal(dAtA []byte) error {
 			}
 			m.Oaid = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 33:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Browser", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Browser = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 34:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BrowserVersion", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BrowserVersion = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 35:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DeviceType", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DeviceType = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 36:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Engine", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Engine = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 40:
 			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Carrier", wireType)
@@ -4813,6 +5366,25 @@ func (m *EventLogs) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SendTime", wireType)
+			}
+			m.SendTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SendTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEventLog(dAtA[iNdEx:])
@@ -4917,6 +5489,233 @@ func (m *Response) Unmarshal(dAtA []byte) error {
 			}
 			m.Msg = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Accepted", wireType)
+			}
+			m.Accepted = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Accepted |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Rejected", wireType)
+			}
+			m.Rejected = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Rejected |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RejectedEvents", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RejectedEvents = append(m.RejectedEvents, &RejectedEvent{})
+			if err := m.RejectedEvents[len(m.RejectedEvents)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEventLog(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RejectedEvent) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEventLog
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RejectedEvent: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RejectedEvent: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EventId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EventId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Code", wireType)
+			}
+			m.Code = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Code |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Msg", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEventLog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEventLog
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Msg = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEventLog(dAtA[iNdEx:])
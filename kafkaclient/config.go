@@ -0,0 +1,241 @@
+// Package kafkaclient defines kafka client options shared by kafka storage and consumer,
+// they can be set from configuration file and flags, and applied on sarama.Config.
+package kafkaclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// SASL mechanisms
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// Config is kafka client options, zero values keep the sarama defaults.
+// Producer options are ignored by consumer.
+type Config struct {
+	// e.g. 2.1.0, zstd compression requires 2.1.0 or later
+	Version  string      `json:"version,omitempty"`
+	ClientID string      `json:"client_id,omitempty"`
+	TLS      *TLSConfig  `json:"tls,omitempty"`
+	SASL     *SASLConfig `json:"sasl,omitempty"`
+
+	// none | gzip | snappy | lz4 | zstd
+	Compression string `json:"compression,omitempty"`
+	// none | leader | all
+	RequiredAcks string `json:"required_acks,omitempty"`
+	// exactly once delivery per partition, requires required_acks all
+	Idempotent bool `json:"idempotent,omitempty"`
+	// milliseconds that messages are buffered before they are sent in batch
+	Linger int `json:"linger,omitempty"`
+	// bytes that trigger a flush
+	FlushBytes int `json:"flush_bytes,omitempty"`
+	// max bytes of a message, it should not be greater than broker's message.max.bytes
+	MaxMessageBytes int `json:"max_message_bytes,omitempty"`
+}
+
+type TLSConfig struct {
+	Enable bool `json:"enable"`
+	// PEM encoded CA certificates, empty means system CAs
+	CAFile string `json:"ca_file,omitempty"`
+	// PEM encoded client certificate and key, required by mutual TLS
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+type SASLConfig struct {
+	// PLAIN | SCRAM-SHA-256 | SCRAM-SHA-512, empty means disabled
+	Mechanism string `json:"mechanism"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+}
+
+func (c *Config) Validate() error {
+	if c.Version != "" {
+		if _, err := sarama.ParseKafkaVersion(c.Version); err != nil {
+			return err
+		}
+	}
+	if _, err := parseCompression(c.Compression); err != nil {
+		return err
+	}
+	acks, err := parseRequiredAcks(c.RequiredAcks)
+	if err != nil {
+		return err
+	}
+	if c.Idempotent && c.RequiredAcks != "" && acks != sarama.WaitForAll {
+		return errors.New("idempotent requires required_acks all")
+	}
+	if c.Linger < 0 || c.FlushBytes < 0 || c.MaxMessageBytes < 0 {
+		return errors.New("negative value")
+	}
+	if c.TLS != nil {
+		if err := c.TLS.Validate(); err != nil {
+			return errors.Wrap(err, "tls")
+		}
+	}
+	if c.SASL != nil {
+		if err := c.SASL.Validate(); err != nil {
+			return errors.Wrap(err, "sasl")
+		}
+	}
+
+	return nil
+}
+
+func (c *TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+
+	return nil
+}
+
+func (c *SASLConfig) Validate() error {
+	switch c.Mechanism {
+	case "":
+		return nil
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+	default:
+		return errors.Errorf("unknown mechanism %s", c.Mechanism)
+	}
+	if c.Username == "" {
+		return errors.New("username is missing")
+	}
+
+	return nil
+}
+
+// Apply sets the options on sc, certificate files are loaded
+func (c *Config) Apply(sc *sarama.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	if c.Version != "" {
+		sc.Version, _ = sarama.ParseKafkaVersion(c.Version)
+	}
+	if c.ClientID != "" {
+		sc.ClientID = c.ClientID
+	}
+
+	if c.TLS != nil && c.TLS.Enable {
+		tlsConfig, err := c.TLS.Load()
+		if err != nil {
+			return errors.Wrap(err, "tls")
+		}
+		sc.Net.TLS.Enable = true
+		sc.Net.TLS.Config = tlsConfig
+	}
+
+	if c.SASL != nil && c.SASL.Mechanism != "" {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.User = c.SASL.Username
+		sc.Net.SASL.Password = c.SASL.Password
+		sc.Net.SASL.Handshake = true
+		switch c.SASL.Mechanism {
+		case SASLPlain:
+			sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case SASLScramSHA256:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return newScramClient(sha256Hash) }
+		case SASLScramSHA512:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return newScramClient(sha512Hash) }
+		}
+	}
+
+	if c.Compression != "" {
+		sc.Producer.Compression, _ = parseCompression(c.Compression)
+	}
+	if c.RequiredAcks != "" {
+		sc.Producer.RequiredAcks, _ = parseRequiredAcks(c.RequiredAcks)
+	}
+	if c.Idempotent {
+		sc.Producer.Idempotent = true
+		sc.Producer.RequiredAcks = sarama.WaitForAll
+		sc.Net.MaxOpenRequests = 1
+	}
+	if c.Linger > 0 {
+		sc.Producer.Flush.Frequency = time.Duration(c.Linger) * time.Millisecond
+	}
+	if c.FlushBytes > 0 {
+		sc.Producer.Flush.Bytes = c.FlushBytes
+	}
+	if c.MaxMessageBytes > 0 {
+		sc.Producer.MaxMessageBytes = c.MaxMessageBytes
+	}
+
+	return nil
+}
+
+// Load returns tls.Config with the certificates loaded
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "ca_file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in ca_file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cert_file")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseCompression(name string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	}
+
+	return sarama.CompressionNone, errors.Errorf("unknown compression %s", name)
+}
+
+func parseRequiredAcks(name string) (sarama.RequiredAcks, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return sarama.WaitForLocal, nil
+	case "none", "0":
+		return sarama.NoResponse, nil
+	case "leader", "1":
+		return sarama.WaitForLocal, nil
+	case "all", "-1":
+		return sarama.WaitForAll, nil
+	}
+
+	return sarama.NoResponse, errors.Errorf("unknown required_acks %s", name)
+}
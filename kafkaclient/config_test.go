@@ -0,0 +1,141 @@
+package kafkaclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	ast := assert.New(t)
+
+	c := &Config{
+		Version:         "2.1.0",
+		ClientID:        "logserver",
+		Compression:     "zstd",
+		Idempotent:      true,
+		Linger:          5,
+		FlushBytes:      1 << 20,
+		MaxMessageBytes: 2 << 20,
+		SASL: &SASLConfig{
+			Mechanism: SASLScramSHA512,
+			Username:  "user",
+			Password:  "pass",
+		},
+		TLS: &TLSConfig{},
+	}
+
+	sc := sarama.NewConfig()
+	ast.NoError(c.Apply(sc))
+	ast.Equal(sarama.V2_1_0_0, sc.Version)
+	ast.Equal("logserver", sc.ClientID)
+	ast.Equal(sarama.CompressionZSTD, sc.Producer.Compression)
+	ast.True(sc.Producer.Idempotent)
+	ast.Equal(sarama.WaitForAll, sc.Producer.RequiredAcks)
+	ast.Equal(1, sc.Net.MaxOpenRequests)
+	ast.Equal(5*time.Millisecond, sc.Producer.Flush.Frequency)
+	ast.Equal(1<<20, sc.Producer.Flush.Bytes)
+	ast.Equal(2<<20, sc.Producer.MaxMessageBytes)
+	ast.True(sc.Net.SASL.Enable)
+	ast.Equal(sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), sc.Net.SASL.Mechanism)
+	ast.Equal("user", sc.Net.SASL.User)
+	ast.NotNil(sc.Net.SASL.SCRAMClientGeneratorFunc)
+	// tls is not enabled
+	ast.False(sc.Net.TLS.Enable)
+	ast.NoError(sc.Validate())
+
+	// zero values keep sarama defaults
+	sc = sarama.NewConfig()
+	ast.NoError((&Config{}).Apply(sc))
+	ast.Equal(sarama.DefaultVersion, sc.Version)
+	ast.Equal(sarama.CompressionNone, sc.Producer.Compression)
+	ast.Equal(sarama.WaitForLocal, sc.Producer.RequiredAcks)
+	ast.Equal(sarama.NewConfig().Producer.MaxMessageBytes, sc.Producer.MaxMessageBytes)
+	ast.False(sc.Net.SASL.Enable)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []*Config{
+		{Version: "x.1"},
+		{Compression: "brotli"},
+		{RequiredAcks: "2"},
+		{RequiredAcks: "leader", Idempotent: true},
+		{Linger: -1},
+		{SASL: &SASLConfig{Mechanism: "GSSAPI", Username: "user"}},
+		{SASL: &SASLConfig{Mechanism: SASLPlain}},
+		{TLS: &TLSConfig{Enable: true, CertFile: "client.pem"}},
+	}
+
+	for _, c := range tests {
+		assert.Error(t, c.Validate(), "%+v", c)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ast := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logserver-kafkaclient")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCert(t, dir)
+
+	c := &Config{
+		TLS: &TLSConfig{
+			Enable:   true,
+			CAFile:   certFile,
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		},
+	}
+	sc := sarama.NewConfig()
+	ast.NoError(c.Apply(sc))
+	ast.True(sc.Net.TLS.Enable)
+	ast.NotNil(sc.Net.TLS.Config.RootCAs)
+	ast.Len(sc.Net.TLS.Config.Certificates, 1)
+
+	c.TLS.CAFile = keyFile
+	ast.Error(c.Apply(sarama.NewConfig()))
+
+	c.TLS.CAFile = filepath.Join(dir, "missing.pem")
+	ast.Error(c.Apply(sarama.NewConfig()))
+}
+
+// writeTestCert writes a self-signed certificate and its key in dir
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return
+}
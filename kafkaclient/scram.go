@@ -0,0 +1,43 @@
+package kafkaclient
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/xdg/scram"
+)
+
+var (
+	sha256Hash scram.HashGeneratorFcn = sha256.New
+	sha512Hash scram.HashGeneratorFcn = sha512.New
+)
+
+// scramClient implements sarama.SCRAMClient
+type scramClient struct {
+	hash scram.HashGeneratorFcn
+	conv *scram.ClientConversation
+}
+
+func newScramClient(hash scram.HashGeneratorFcn) *scramClient {
+	return &scramClient{
+		hash: hash,
+	}
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hash.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conv = client.NewConversation()
+
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conv.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conv.Done()
+}
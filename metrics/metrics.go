@@ -23,6 +23,8 @@ var (
 	// common action counter and gauge
 	// labels: server, action
 	_actionCounter metrics.Counter
+	// labels: server, action
+	_actionGauge metrics.Gauge
 
 	// outstanding request count
 	// labels: server, method
@@ -35,6 +37,14 @@ var (
 	_requestCounter metrics.Counter
 	// labels: server, method, code
 	_requestDuration metrics.Histogram
+
+	// rejected events by rate limiter
+	// labels: server, method, limit, app_type
+	_rateLimitedCounter metrics.Counter
+
+	// decompressed size / compressed size of request body
+	// labels: server, encoding
+	_compressionRatio metrics.Histogram
 )
 
 func init() {
@@ -54,6 +64,16 @@ func init() {
 		_actionCounter = prometheus.NewCounterFrom(opts, labels)
 	}
 
+	{
+		opts := stdprometheus.GaugeOpts{
+			Namespace: "logserver",
+			Help:      "logserver action gauge",
+			Name:      "action_gauge",
+		}
+		labels := []string{"server", "action"}
+		_actionGauge = prometheus.NewGaugeFrom(opts, labels)
+	}
+
 	{
 		opts := stdprometheus.GaugeOpts{
 			Namespace: "logserver",
@@ -96,6 +116,29 @@ func init() {
 		labels := []string{"server", "method", "code"}
 		_requestDuration = prometheus.NewHistogramFrom(opts, labels)
 	}
+
+	{
+		opts := stdprometheus.CounterOpts{
+			Namespace: "logserver",
+			Help:      "logserver rate limited event counter",
+			Name:      "rate_limited_count",
+		}
+		labels := []string{"server", "method", "limit", "app_type"}
+		_rateLimitedCounter = prometheus.NewCounterFrom(opts, labels)
+	}
+
+	{
+		opts := stdprometheus.HistogramOpts{
+			Namespace: "logserver",
+			Help:      "logserver request body compression ratio",
+			Name:      "compression_ratio",
+			Buckets: []float64{
+				1, 2, 3, 5, 8, 10, 15, 20, 30, 50,
+			},
+		}
+		labels := []string{"server", "encoding"}
+		_compressionRatio = prometheus.NewHistogramFrom(opts, labels)
+	}
 }
 
 // RequestMetrics is LabeledMiddleware, collect request count and latency
@@ -160,9 +203,32 @@ func CountEvent(topic, appType, event string) {
 	).Add(1)
 }
 
+func CountRateLimited(method, limit, appType string, events int) {
+	_rateLimitedCounter.With(
+		"server", _hostname,
+		"method", method,
+		"limit", limit,
+		"app_type", appType,
+	).Add(float64(events))
+}
+
 func CounterAdd(action string, delta float64) {
 	_actionCounter.With(
 		"server", _hostname,
 		"action", action,
 	).Add(delta)
 }
+
+func GaugeSet(action string, value float64) {
+	_actionGauge.With(
+		"server", _hostname,
+		"action", action,
+	).Set(value)
+}
+
+func ObserveCompressionRatio(encoding string, ratio float64) {
+	_compressionRatio.With(
+		"server", _hostname,
+		"encoding", encoding,
+	).Observe(ratio)
+}
@@ -7,6 +7,7 @@ package main
 
 import (
 	"flag"
+	"log"
 
 	// This Service
 	"github.com/techxmind/logserver/config"
@@ -27,6 +28,11 @@ func main() {
 	// Update addresses if they have been overwritten by flags
 	flag.Parse()
 
+	// Load configuration file if specified
+	if err := config.Init(); err != nil {
+		log.Fatal(err)
+	}
+
 	config.DefaultConfig.Version = version
 	config.DefaultConfig.VersionDate = date
 
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/errors"
+	"github.com/techxmind/logserver/metrics"
+)
+
+// authMiddleware authenticates requests with api key or HMAC signature, see config.AuthConfig.
+// getConfig returns nil if authentication is disabled.
+func authMiddleware(getConfig func() *config.AuthConfig) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if cfg := getConfig(); cfg != nil {
+				if err := authenticate(ctx, cfg, request); err != nil {
+					metrics.CounterAdd("auth_rejected", 1)
+					return nil, err
+				}
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func authenticate(ctx context.Context, cfg *config.AuthConfig, request interface{}) error {
+	app := cfg.GetApp(contextString(ctx, "x-api-key"))
+	if app == nil {
+		return errors.Wrap(errors.ErrUnauthorized, "invalid api key")
+	}
+
+	if app.Secret != "" {
+		if err := checkSignature(ctx, cfg, app); err != nil {
+			return err
+		}
+	}
+
+	for appType := range countAppTypes(request) {
+		if !app.Allow(appType) {
+			return errors.Wrapf(errors.ErrAppTypeDenied, "app_type '%s'", appType)
+		}
+	}
+
+	return nil
+}
+
+func checkSignature(ctx context.Context, cfg *config.AuthConfig, app *config.AppAuth) error {
+	timestamp := contextString(ctx, "x-timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Wrap(errors.ErrUnauthorized, "invalid timestamp")
+	}
+	if diff := time.Since(time.Unix(ts, 0)); diff > cfg.GetWindow() || diff < -cfg.GetWindow() {
+		return errors.Wrap(errors.ErrUnauthorized, "timestamp is out of window")
+	}
+
+	signature, err := hex.DecodeString(contextString(ctx, "x-signature"))
+	if err != nil || len(signature) == 0 {
+		return errors.Wrap(errors.ErrUnauthorized, "invalid signature")
+	}
+
+	// raw body of http request, serialized request message of gRPC as it's sent.
+	// Decoded request is never marshaled again, the bytes may differ, e.g. order of map entries.
+	body, ok := ctx.Value("request-body").([]byte)
+	if !ok {
+		return errors.Wrap(errors.ErrUnauthorized, "request body is unavailable")
+	}
+
+	mac := hmac.New(sha256.New, []byte(app.Secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errors.Wrap(errors.ErrUnauthorized, "signature mismatch")
+	}
+
+	return nil
+}
+
+func contextString(ctx context.Context, key string) string {
+	v, _ := ctx.Value(key).(string)
+	return v
+}
@@ -3,11 +3,16 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	_ "github.com/joho/godotenv/autoload"
-	"github.com/pkg/errors"
 
 	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/deadletter"
+	"github.com/techxmind/logserver/dedup"
+	"github.com/techxmind/logserver/errors"
 	"github.com/techxmind/logserver/eventlog"
 	pb "github.com/techxmind/logserver/interface-defs"
 	"github.com/techxmind/logserver/logger"
@@ -15,6 +20,9 @@ import (
 	"github.com/techxmind/logserver/storage"
 )
 
+// max concurrent storage writes of a SubmitMultiple request in sync delivery mode
+const syncWriteConcurrency = 16
+
 var (
 	_ = fmt.Sprint
 
@@ -32,83 +40,461 @@ func NewService() pb.LogServiceServer {
 		logger.Fatal("storage init failed", "err", err)
 	}
 
+	enricher, err := eventlog.NewChain(cfg.Enrich)
+
+	if err != nil {
+		logger.Fatal("enricher init failed", "err", err)
+	}
+
+	router, err := eventlog.NewRouter(cfg.TopicRouter)
+
+	if err != nil {
+		logger.Fatal("topic router init failed", "err", err)
+	}
+
 	_service = &logserviceService{
-		storage: storage,
-		config:  cfg,
+		state: &state{
+			storage:    storage,
+			config:     cfg,
+			enricher:   enricher,
+			router:     router,
+			anonymizer: eventlog.NewAnonymizer(cfg.Privacy),
+			dedup:      newDedup(cfg.Dedup),
+		},
+	}
+
+	if file := config.File(); file != "" {
+		_service.stopWatch = config.Watch(file, 5*time.Second, _service.reload)
 	}
 
 	return _service
 }
 
 type logserviceService struct {
-	storage storage.Storager
-	config  *config.Config
+	// guards state and closed, it's held only to get or replace state
+	mu    sync.RWMutex
+	state *state
+	// stops watching config file, nil if it's not watched
+	stopWatch func()
+	closed    bool
+}
+
+// state is the components built from a configuration, it's replaced as a whole on reload.
+// Requests use the state that they start with, its storage is closed after they finish.
+type state struct {
+	storage    storage.Storager
+	config     *config.Config
+	enricher   *eventlog.Chain
+	router     *eventlog.Router
+	anonymizer *eventlog.Anonymizer
+	// nil if dedup is disabled
+	dedup *dedup.Set
+	// in-flight requests
+	requests sync.WaitGroup
+}
+
+// acquire returns current state for a request, the request must call state.release when it finishes
+func (s *logserviceService) acquire() *state {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// added before the state is replaced, so that reload always waits for it
+	s.state.requests.Add(1)
+
+	return s.state
 }
 
-func (s logserviceService) SubmitSingle(ctx context.Context, in *pb.EventLog) (*pb.Response, error) {
+func (s *state) release() {
+	s.requests.Done()
+}
+
+// currentConfig returns configuration of current state
+func (s *logserviceService) currentConfig() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.state.config
+}
+
+func (s *logserviceService) SubmitSingle(ctx context.Context, in *pb.EventLog) (*pb.Response, error) {
 	var resp pb.Response
 
+	st := s.acquire()
+	defer st.release()
+
+	submitted := st.submitted(nil, []*pb.EventLog{in})[0]
+
 	if err := validate(in); err != nil {
+		st.writeDeadLetter(ctx, deadletter.ReasonInvalid, in, submitted, err)
 		return nil, err
 	}
 
-	eventlog.FillSingle(ctx, in)
+	st.enricher.FillSingle(ctx, in)
+	st.anonymizer.ApplySingle(in)
 
-	s.writeEventLog(in)
+	if err := checkEventTime(in, st.config.EventTime); err != nil {
+		st.writeDeadLetter(ctx, deadletter.ReasonInvalid, in, submitted, err)
+		return nil, err
+	}
+
+	if st.isDuplicate(in) {
+		return &resp, nil
+	}
+
+	if err := st.writeEventLog(ctx, in, submitted); err != nil {
+		return nil, err
+	}
 
 	return &resp, nil
 }
 
-func (s logserviceService) SubmitMultiple(ctx context.Context, in *pb.EventLogs) (*pb.Response, error) {
+func (s *logserviceService) SubmitMultiple(ctx context.Context, in *pb.EventLogs) (*pb.Response, error) {
 	var resp pb.Response
 
+	st := s.acquire()
+	defer st.release()
+
 	if len(in.Events) == 0 {
 		return nil, errors.New("No event")
 	}
 
-	eventlog.Fill(ctx, in.Common, in.Events)
+	// send time in body takes precedence over X-Send-Time header
+	if in.SendTime > 0 {
+		ctx = context.WithValue(ctx, "x-send-time", strconv.FormatInt(in.SendTime, 10))
+	}
+
+	submitted := st.submitted(in.Common, in.Events)
 
-	for _, event := range in.Events {
-		if err := validate(event); err != nil {
+	st.enricher.Fill(ctx, in.Common, in.Events)
+	st.anonymizer.Apply(in.Common, in.Events)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		writeErr error
+		sem      = make(chan struct{}, syncWriteConcurrency)
+	)
+
+	for i, event := range in.Events {
+		err := validate(event)
+		if err == nil {
+			err = checkEventTime(event, st.config.EventTime)
+		}
+		if err != nil {
+			st.writeDeadLetter(ctx, deadletter.ReasonInvalid, event, submitted[i], err)
+			resp.Rejected++
+			resp.RejectedEvents = append(resp.RejectedEvents, rejectedEvent(i, event, err))
 			continue
 		}
 
-		s.writeEventLog(event)
+		resp.Accepted++
+
+		if st.isDuplicate(event) {
+			continue
+		}
+
+		if !st.hasSyncDelivery() {
+			st.writeEventLog(ctx, event, submitted[i])
+			continue
+		}
+
+		// wait for the storage acks concurrently
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(event *pb.EventLog, submitted []byte) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := st.writeEventLog(ctx, event, submitted); err != nil {
+				mu.Lock()
+				writeErr = err
+				mu.Unlock()
+			}
+		}(event, submitted[i])
+	}
+
+	wg.Wait()
+
+	if writeErr != nil {
+		return nil, writeErr
 	}
 
 	return &resp, nil
 }
 
-func (s logserviceService) writeEventLog(event *pb.EventLog) {
-	msg := storage.NewMessage(
-		eventlog.GetTopic(event, s.config.TopicRouter),
-		event.EventId,
-		event,
-	)
+func rejectedEvent(index int, event *pb.EventLog, err error) *pb.RejectedEvent {
+	code := errors.ErrUnknown
+	if lerr, ok := errors.Cause(err).(errors.LError); ok {
+		code = lerr
+	}
 
-	//val, err := json.MarshalIndent(in, "", "  ")
+	return &pb.RejectedEvent{
+		Index:   int32(index),
+		EventId: event.EventId,
+		Code:    int32(code.ErrorCode()),
+		Msg:     err.Error(),
+	}
+}
+
+// writeEventLog writes event to the destinations that it's routed to.
+// In sync delivery mode, it waits for the storage ack and returns retryable error if the storage fails.
+// Delivery is at-least-once, the event that times out may still be delivered after the client retries it.
+// submitted is the encoded event as it's submitted, it's written to dead letter topic if no topic routes the event.
+func (s *state) writeEventLog(ctx context.Context, event *pb.EventLog, submitted []byte) error {
+	var err error
+
+	dests := s.router.Route(event)
 
 	// Ignore it when topic is empty
-	if msg.Topic != "" {
-		if s.config.Storage != nil && s.config.Storage.DataType == "json" {
-			msg.Value = storage.JSONMarshaler(msg.Value)
+	if len(dests) == 0 {
+		s.writeDeadLetter(ctx, deadletter.ReasonUnroutable, event, submitted, nil)
+		metrics.CountEvent("", event.AppType, event.Event)
+		return nil
+	}
+
+	for _, dest := range dests {
+		if werr := s.writeDestination(ctx, event, dest); werr != nil {
+			err = werr
+		}
+		metrics.CountEvent(dest.Topic, event.AppType, event.Event)
+	}
+
+	if err != nil {
+		// the client retries it
+		if s.dedup != nil {
+			s.dedup.Forget(event.EventId)
+		}
+		if errors.Cause(err) == context.DeadlineExceeded {
+			return errors.Wrap(errors.ErrDeliveryTimeout, err.Error())
+		}
+		return errors.Wrap(errors.ErrDeliveryFailed, err.Error())
+	}
+
+	return nil
+}
+
+// writeDestination writes message of the destination, it returns error only in sync delivery mode.
+// Message headers are taken from the whole event since the destination may carry a projection.
+func (s *state) writeDestination(ctx context.Context, event *pb.EventLog, dest *eventlog.Destination) error {
+	var (
+		err      error
+		delivery *config.Delivery
+		dataType = dest.DataType
+	)
+
+	msg := storage.NewMessage(dest.Topic, dest.Key, dest.Event)
+
+	if s.config.Storage != nil {
+		delivery = s.config.Storage.Delivery
+		if dataType == "" {
+			dataType = s.config.Storage.DataType
+		}
+	}
+	if dataType == "json" {
+		msg.Value = storage.JSONMarshaler(msg.Value)
+	}
+	msg.Headers = eventlog.Headers(event, dataType)
+
+	if delivery.IsSync(msg.Topic) {
+		wctx, cancel := context.WithTimeout(ctx, delivery.GetTimeout())
+		err = storage.WriteSync(wctx, s.storage, msg)
+		cancel()
+	} else {
+		err = s.storage.Write(msg)
+	}
+	if err != nil {
+		metrics.CounterAdd("record_write_err", 1)
+		logger.Error("write storage", "topic", msg.Topic, "err", err)
+	}
+
+	if !delivery.IsSync(msg.Topic) {
+		return nil
+	}
+
+	return err
+}
+
+// hasDeadLetter reports whether dead letter topic is configured
+func (s *state) hasDeadLetter() bool {
+	return s.config.Storage != nil && s.config.Storage.DeadLetter.GetTopic() != ""
+}
+
+// submitted returns the encoded events as they're submitted, before they're enriched.
+// Common properties are merged into the events, so that they're complete to reprocess.
+// Privacy policies are applied as the events written to storage, dead letters never keep what they drop or hash.
+// Items are nil if dead letter is disabled.
+func (s *state) submitted(common *pb.EventLogCommon, events []*pb.EventLog) [][]byte {
+	submitted := make([][]byte, len(events))
+	if !s.hasDeadLetter() {
+		return submitted
+	}
+
+	for i, event := range events {
+		// copy, the event itself is enriched later
+		var raw pb.EventLog
+		data, err := event.Marshal()
+		if err == nil {
+			err = raw.Unmarshal(data)
+		}
+		if err == nil {
+			if common != nil {
+				eventlog.MergeCommon(common, []*pb.EventLog{&raw})
+			}
+			s.anonymizer.ApplySingle(&raw)
+			data, err = raw.Marshal()
 		}
-		if err := s.storage.Write(msg); err != nil {
-			metrics.CounterAdd("record_write_err", 1)
-			logger.Error("write storage", "err", err)
+		if err != nil {
+			logger.Error("marshal dead letter", "err", err)
+			continue
 		}
+		submitted[i] = data
 	}
 
-	metrics.CountEvent(msg.Topic, event.AppType, event.Event)
+	return submitted
 }
 
-func (s logserviceService) close() {
-	if s.storage != nil {
-		s.storage.Close()
+// writeDeadLetter writes rejected event to dead letter topic if it's configured.
+// data is the encoded event as it's submitted and anonymized, event is used for the key and source ip only.
+func (s *state) writeDeadLetter(ctx context.Context, reason string, event *pb.EventLog, data []byte, err error) {
+	if !s.hasDeadLetter() || data == nil {
+		return
+	}
+
+	letter := deadletter.New(reason, data, err)
+	if lerr, ok := errors.Cause(err).(errors.LError); ok {
+		letter.Code = lerr.ErrorCode()
+	}
+	// source ip is anonymized as the event
+	if ip := contextString(ctx, "remote-ip"); ip != "" {
+		letter.SourceIP = s.anonymizer.IP(event.AppType, ip)
+	}
+
+	msg := storage.NewMessage(s.config.Storage.DeadLetter.Topic, event.EventId, letter)
+	msg.Headers = map[string]string{
+		storage.HeaderContentType: storage.ContentTypeJSON,
+	}
+	if werr := s.storage.Write(msg); werr != nil {
+		metrics.CounterAdd("dead_letter_write_err", 1)
+		logger.Error("write dead letter", "err", werr)
+		return
+	}
+
+	metrics.CounterAdd("dead_letter_"+reason, 1)
+}
+
+// isDuplicate reports whether event_id is submitted in the dedup window, duplicates are counted and dropped
+func (s *state) isDuplicate(event *pb.EventLog) bool {
+	if s.dedup == nil || !s.dedup.Seen(event.EventId) {
+		return false
+	}
+
+	metrics.CounterAdd("event_duplicated", 1)
+
+	return true
+}
+
+func newDedup(cfg *config.DedupConfig) *dedup.Set {
+	if cfg == nil {
+		return nil
+	}
+
+	return dedup.New(cfg.GetWindow(), cfg.GetMaxKeys())
+}
+
+// hasSyncDelivery reports whether some events may wait for the storage ack
+func (s *state) hasSyncDelivery() bool {
+	if s.config.Storage == nil || s.config.Storage.Delivery == nil {
+		return false
+	}
+	d := s.config.Storage.Delivery
+
+	return d.Mode == "sync" || len(d.Topics) > 0
+}
+
+// reload replaces topic router, enricher, anonymizer, dedup and storage with the new configuration.
+// Remembered event ids are kept if dedup configuration doesn't change.
+// New storage is built before the old one is closed, spool directory is shared by them,
+// and previous configuration is kept if any of them fails.
+// In-flight requests are finished with the old storage before it's closed, new requests don't wait for them.
+func (s *logserviceService) reload(cfg *config.Config) {
+	// state is only replaced by reload, that is called in the watcher goroutine
+	s.mu.RLock()
+	closed, prev := s.closed, s.state
+	s.mu.RUnlock()
+	if closed {
+		return
+	}
+
+	if cfg.HTTPAddr != prev.config.HTTPAddr || cfg.GRPCAddr != prev.config.GRPCAddr || cfg.DebugAddr != prev.config.DebugAddr {
+		logger.Warn("listen address changes require restart")
+	}
+
+	enricher, err := eventlog.NewChain(cfg.Enrich)
+	if err != nil {
+		logger.Error("reload enricher, keep previous configuration", "err", err)
+		return
+	}
+	router, err := eventlog.NewRouter(cfg.TopicRouter)
+	if err != nil {
+		logger.Error("reload topic router, keep previous configuration", "err", err)
+		return
+	}
+	storage, err := StorageGet(cfg.Storage)
+	if err != nil {
+		logger.Error("reload storage, keep previous configuration", "err", err)
+		return
+	}
+
+	next := &state{
+		storage:    storage,
+		config:     cfg,
+		enricher:   enricher,
+		router:     router,
+		anonymizer: eventlog.NewAnonymizer(cfg.Privacy),
+		dedup:      prev.dedup,
+	}
+	if cfg.Dedup == nil || prev.config.Dedup == nil || *cfg.Dedup != *prev.config.Dedup {
+		next.dedup = newDedup(cfg.Dedup)
+	}
+	cfg.Version, cfg.VersionDate = prev.config.Version, prev.config.VersionDate
+
+	s.mu.Lock()
+	// reload may be triggered while service is closing
+	if s.closed {
+		s.mu.Unlock()
+		storage.Close()
+		return
+	}
+	s.state = next
+	s.mu.Unlock()
+
+	// old storage is flushed after its requests finish, without blocking new requests
+	prev.requests.Wait()
+	if err := prev.storage.Close(); err != nil {
+		logger.Error("close old storage", "err", err)
+	}
+
+	metrics.CounterAdd("config_reload", 1)
+}
+
+func (s *logserviceService) close() {
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	st := s.state
+	s.mu.Unlock()
+
+	st.requests.Wait()
+	if st.storage != nil {
+		st.storage.Close()
 	}
 }
 
-func (s logserviceService) Ping(ctx context.Context, in *pb.Empty) (*pb.Response, error) {
+func (s *logserviceService) Ping(ctx context.Context, in *pb.Empty) (*pb.Response, error) {
 	var resp pb.Response
 	return &resp, nil
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/techxmind/logserver/config"
 	pb "github.com/techxmind/logserver/interface-defs"
 	"github.com/techxmind/logserver/logger"
 	"github.com/techxmind/logserver/metrics"
@@ -32,11 +33,34 @@ func WrapEndpoints(in svc.Endpoints) svc.Endpoints {
 	// How to apply a middleware to a single endpoint.
 	// in.ExampleEndpoint = authMiddleware(in.ExampleEndpoint)
 
+	// rate limit applies after authentication
+	in.WrapAllLabeledExcept(rateLimitMiddleware(rateLimitConfig), "Ping")
+	in.WrapAllExcept(authMiddleware(authConfig), "Ping")
+	svc.RawBodyRequired = func() bool {
+		return authConfig().Signed()
+	}
+
 	in.WrapAllLabeledExcept(metrics.RequestMetrics)
 
 	return in
 }
 
+func authConfig() *config.AuthConfig {
+	if _service == nil {
+		return nil
+	}
+
+	return _service.currentConfig().Auth
+}
+
+func rateLimitConfig() *config.RateLimitConfig {
+	if _service == nil {
+		return nil
+	}
+
+	return _service.currentConfig().RateLimit
+}
+
 func WrapService(in pb.LogServiceServer) pb.LogServiceServer {
 	return in
 }
@@ -44,5 +68,5 @@ func WrapService(in pb.LogServiceServer) pb.LogServiceServer {
 func WrapDebugService(mu *http.ServeMux) {
 	mu.Handle("/log_level", logger.HttpHandler())
 	mu.Handle("/metrics", metrics.HttpHandler())
-	mu.Handle("/version", _service.config)
+	mu.Handle("/version", _service.currentConfig())
 }
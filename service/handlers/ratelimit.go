@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/errors"
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/metrics"
+)
+
+// rateLimitMiddleware is LabeledMiddleware, limits events of the endpoint, see config.RateLimitConfig.
+// getConfig returns nil if rate limit is disabled.
+func rateLimitMiddleware(getConfig func() *config.RateLimitConfig) func(string, endpoint.Endpoint) endpoint.Endpoint {
+	limiter := &rateLimiter{now: time.Now}
+
+	return func(name string, next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if cfg := getConfig(); cfg != nil {
+				if route := cfg.GetRoute(name); route != nil {
+					ip := contextString(ctx, "remote-ip")
+					if err := limiter.allow(cfg, name, route, ip, countAppTypes(request)); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+type rateLimiter struct {
+	mu sync.Mutex
+	// buckets are reset when configuration changes
+	cfg     *config.RateLimitConfig
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type reservation struct {
+	bucket *bucket
+	tokens float64
+	limit  string
+}
+
+// allow takes tokens from all matched buckets, or none of them if any bucket is short of tokens
+func (l *rateLimiter) allow(cfg *config.RateLimitConfig, name string, route *config.RouteLimit, ip string, appTypes map[string]int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg != cfg {
+		l.cfg = cfg
+		l.buckets = make(map[string]*bucket)
+	}
+
+	var (
+		now          = l.now()
+		total        int
+		reservations = make([]reservation, 0, len(appTypes)+2)
+	)
+
+	for appType, n := range appTypes {
+		total += n
+		if limit := route.GetAppType(appType); limit != nil {
+			reservations = append(reservations, reservation{l.bucket(name+"|app_type|"+appType, limit, now), float64(n), "app_type"})
+		}
+	}
+	if route.Global != nil {
+		reservations = append(reservations, reservation{l.bucket(name+"|global", route.Global, now), float64(total), "global"})
+	}
+	if route.IP != nil && ip != "" {
+		reservations = append(reservations, reservation{l.bucket(name+"|ip|"+ip, route.IP, now), float64(total), "ip"})
+	}
+
+	var (
+		wait    time.Duration
+		limited string
+	)
+	for _, r := range reservations {
+		if d := r.bucket.wait(now, r.tokens); d > wait {
+			wait, limited = d, r.limit
+		}
+	}
+
+	if wait > 0 {
+		for appType, n := range appTypes {
+			metrics.CountRateLimited(name, limited, appType, n)
+		}
+		return errors.RetryAfter(errors.Wrapf(errors.ErrRateLimited, "%s limit", limited), wait)
+	}
+
+	for _, r := range reservations {
+		r.bucket.tokens -= r.tokens
+	}
+
+	return nil
+}
+
+func (l *rateLimiter) bucket(key string, limit *config.Limit, now time.Time) *bucket {
+	if b, ok := l.buckets[key]; ok {
+		return b
+	}
+
+	b := &bucket{
+		rate:   limit.Rate,
+		burst:  limit.GetBurst(),
+		tokens: limit.GetBurst(),
+		last:   now,
+	}
+
+	if len(l.buckets) >= l.cfg.GetMaxBuckets() {
+		// drop idle buckets, they are full again
+		for k, v := range l.buckets {
+			if v.refill(now); v.tokens >= v.burst {
+				delete(l.buckets, k)
+			}
+		}
+		// still too many active keys, the new key is not tracked
+		if len(l.buckets) >= l.cfg.GetMaxBuckets() {
+			return b
+		}
+	}
+	l.buckets[key] = b
+
+	return b
+}
+
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns the time to wait until n tokens are available, 0 means available now.
+// Requests larger than burst are allowed when the bucket is full, tokens become negative.
+func (b *bucket) wait(now time.Time, n float64) time.Duration {
+	b.refill(now)
+
+	need := math.Min(n, b.burst)
+	if b.tokens >= need {
+		return 0
+	}
+
+	return time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+}
+
+// countAppTypes returns number of events of each app type in request
+func countAppTypes(request interface{}) map[string]int {
+	switch req := request.(type) {
+	case *pb.EventLog:
+		return map[string]int{req.AppType: 1}
+	case *pb.EventLogs:
+		counts := make(map[string]int, 1)
+		for _, event := range req.Events {
+			appType := event.AppType
+			// events inherit app_type of common if empty
+			if appType == "" && req.Common != nil {
+				appType = req.Common.AppType
+			}
+			counts[appType]++
+		}
+		return counts
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/techxmind/logserver/logger"
 	"github.com/techxmind/logserver/storage"
 	"github.com/techxmind/logserver/storage/kafka"
+	"github.com/techxmind/logserver/storage/spool"
 )
 
 var (
@@ -38,11 +39,19 @@ func newStorage(cfg *config.StorageConfig) (storage.Storager, error) {
 				return nil, errors.New("Kafka storage configuration is missing")
 			}
 
-			if s, err := kafka.New(cfg.Kafka); err != nil {
+			s, err := kafka.New(cfg.Kafka)
+			if err != nil {
 				return nil, err
-			} else {
-				group.Add(s)
 			}
+			if cfg.Spool != nil && cfg.Spool.Dir != "" {
+				spooled, err := spool.New(s, cfg.Spool)
+				if err != nil {
+					s.Close()
+					return nil, err
+				}
+				s = spooled
+			}
+			group.Add(s)
 		default:
 			return nil, errors.Errorf("Unknow storage %s", storageType)
 		}
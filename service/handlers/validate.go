@@ -2,9 +2,18 @@ package handlers
 
 import (
 	"regexp"
+	"time"
 
+	"github.com/techxmind/logserver/config"
 	"github.com/techxmind/logserver/errors"
 	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/metrics"
+)
+
+// Event time flags
+const (
+	EventTimeFuture  = "future"
+	EventTimeExpired = "expired"
 )
 
 var (
@@ -45,3 +54,32 @@ func checkRequired(log *pb.EventLog) error {
 
 	return nil
 }
+
+// checkEventTime flags or rejects implausible event time, it must be called after event log is filled
+func checkEventTime(log *pb.EventLog, cfg *config.EventTimeConfig) error {
+	if cfg == nil || log.CorrectedEventTime == 0 {
+		return nil
+	}
+
+	var (
+		flag string
+		diff = time.Duration(log.CorrectedEventTime-log.LoggedTime) * time.Millisecond
+	)
+	switch {
+	case diff > cfg.GetMaxFuture():
+		flag = EventTimeFuture
+	case -diff > cfg.GetMaxAge():
+		flag = EventTimeExpired
+	default:
+		return nil
+	}
+
+	metrics.CounterAdd("event_time_"+flag, 1)
+
+	if cfg.IsReject() {
+		return errors.Wrapf(errors.ErrFieldInvalid, "'EventTime' is %s", flag)
+	}
+	log.EventTimeFlag = flag
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package svc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/metrics"
+)
+
+// readBody reads the request body, decompressing it according to the Content-Encoding header.
+// The decompressed size is limited by config http.max_body_size.
+func readBody(r *http.Request) ([]byte, error) {
+	maxSize := config.DefaultConfig.HTTP.GetMaxBodySize()
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	raw := &countReader{r: r.Body}
+
+	var body io.Reader
+	switch encoding {
+	case "", "identity":
+		body = raw
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil, badEncoding(encoding, err)
+		}
+		defer gr.Close()
+		body = gr
+	case "deflate":
+		dr, err := newDeflateReader(raw)
+		if err != nil {
+			return nil, badEncoding(encoding, err)
+		}
+		defer dr.Close()
+		body = dr
+	case "zstd":
+		zr, err := zstd.NewReader(raw, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, badEncoding(encoding, err)
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		metrics.AddBadRequest("encoding", int32(http.StatusUnsupportedMediaType))
+		return nil, httpError{errors.Errorf("unsupported content encoding %s", encoding),
+			http.StatusUnsupportedMediaType,
+			nil,
+		}
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		if encoding == "" || encoding == "identity" {
+			return nil, errors.Wrapf(err, "cannot read body of http request")
+		}
+		return nil, badEncoding(encoding, err)
+	}
+	if int64(len(buf)) > maxSize {
+		metrics.AddBadRequest("encoding", int32(http.StatusRequestEntityTooLarge))
+		return nil, httpError{errors.Errorf("request body exceeds %d bytes", maxSize),
+			http.StatusRequestEntityTooLarge,
+			nil,
+		}
+	}
+
+	if body != raw && raw.n > 0 {
+		metrics.ObserveCompressionRatio(encoding, float64(len(buf))/float64(raw.n))
+	}
+
+	return buf, nil
+}
+
+// RawBodyRequired reports whether raw request bodies are kept for signature verification,
+// it's set by handlers with the current auth config. Bodies are always kept if it's nil.
+var RawBodyRequired func() bool
+
+// bodyToContext keeps the raw request body in context as request-body, for signature verification.
+// The body is restored so that decoders can read it again.
+func bodyToContext(ctx context.Context, r *http.Request) context.Context {
+	if r.Body == nil || r.Body == http.NoBody {
+		return ctx
+	}
+	if RawBodyRequired != nil && !RawBodyRequired() {
+		return ctx
+	}
+
+	maxSize := config.DefaultConfig.HTTP.GetMaxBodySize()
+	raw, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), r.Body), r.Body}
+	if err != nil || int64(len(raw)) > maxSize {
+		return ctx
+	}
+
+	return context.WithValue(ctx, "request-body", raw)
+}
+
+func badEncoding(encoding string, err error) error {
+	metrics.AddBadRequest("encoding", int32(http.StatusBadRequest))
+	return httpError{errors.Wrapf(err, "cannot decompress %s request body", encoding),
+		http.StatusBadRequest,
+		nil,
+	}
+}
+
+// newDeflateReader returns reader of zlib format data, as RFC 7230 defines,
+// or raw deflate data that some clients send.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// countReader counts bytes read from the underlying reader
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,121 @@
+package svc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/metrics"
+)
+
+var _corsDefaultHeaders = []string{"Content-Type", "Content-Encoding"}
+
+// corsHandler answers preflight requests and sets Access-Control-* headers
+// of the requests from allowed origins.
+type corsHandler struct {
+	next             http.Handler
+	origins          []string
+	allowedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// withCORS wraps h with CORS handler, returns h if cfg is nil.
+func withCORS(cfg *config.CORSConfig, h http.Handler) http.Handler {
+	if cfg == nil {
+		return h
+	}
+
+	c := &corsHandler{
+		next:             h,
+		allowedHeaders:   strings.Join(append(_corsDefaultHeaders, cfg.AllowedHeaders...), ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		c.origins = append(c.origins, strings.ToLower(origin))
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+
+	return c
+}
+
+func (c *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if origin == "" {
+		c.next.ServeHTTP(w, r)
+		return
+	}
+
+	allowedOrigin := c.allowedOrigin(origin)
+	allowed := allowedOrigin != ""
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	if preflight {
+		if !allowed {
+			metrics.CounterAdd("cors_preflight_rejected", 1)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		metrics.CounterAdd("cors_preflight", 1)
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Origin", allowedOrigin)
+		header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		header.Set("Access-Control-Allow-Headers", c.allowedHeaders)
+		if c.allowCredentials && allowedOrigin != "*" {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if c.maxAge != "" {
+			header.Set("Access-Control-Max-Age", c.maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if allowed {
+		header.Set("Access-Control-Allow-Origin", allowedOrigin)
+		if c.allowCredentials && allowedOrigin != "*" {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	c.next.ServeHTTP(w, r)
+}
+
+// allowedOrigin returns value of Access-Control-Allow-Origin, empty if origin is not allowed.
+// Origins that only match * get literal *, that browsers never send credentials to.
+func (c *corsHandler) allowedOrigin(origin string) string {
+	var (
+		lower = strings.ToLower(origin)
+		any   bool
+	)
+
+	for _, allowed := range c.origins {
+		if allowed == "*" {
+			any = true
+			continue
+		}
+		if allowed == lower {
+			return origin
+		}
+		// https://*.example.com matches https://www.example.com
+		if i := strings.Index(allowed, "://*."); i > 0 {
+			scheme, domain := allowed[:i+3], allowed[i+4:]
+			if strings.HasPrefix(lower, scheme) && strings.HasSuffix(lower, domain) && len(lower) > len(scheme)+len(domain) {
+				return origin
+			}
+		}
+	}
+
+	if any {
+		return "*"
+	}
+
+	return ""
+}
@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// GRPCServerOptions returns the options that gRPC server of the service requires,
+// request messages are kept in the wire format for signature verification.
+func GRPCServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.StatsHandler(rawMessageHandler{}),
+	}
+}
+
+type rawMessageKey struct{}
+
+// rawMessage holds the serialized request message of a unary RPC
+type rawMessage struct {
+	data []byte
+}
+
+// rawMessageHandler captures serialized request messages, that are the same bytes sent by clients.
+// The message is received before the handler is called, so it's available in metadataToContext.
+type rawMessageHandler struct{}
+
+func (rawMessageHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rawMessageKey{}, &rawMessage{})
+}
+
+func (rawMessageHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	in, ok := s.(*stats.InPayload)
+	if !ok {
+		return
+	}
+	if m, ok := ctx.Value(rawMessageKey{}).(*rawMessage); ok {
+		m.data = in.Data
+	}
+}
+
+func (rawMessageHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (rawMessageHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+// rawMessageToContext keeps the serialized request message in context as request-body
+func rawMessageToContext(ctx context.Context) context.Context {
+	if m, ok := ctx.Value(rawMessageKey{}).(*rawMessage); ok && m.data != nil {
+		return context.WithValue(ctx, "request-body", m.data)
+	}
+
+	return ctx
+}
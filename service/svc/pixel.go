@@ -0,0 +1,130 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+
+	pb "github.com/techxmind/logserver/interface-defs"
+	"github.com/techxmind/logserver/metrics"
+)
+
+const extendInfoPrefix = "extend_info."
+
+// 1x1 transparent gif
+var _pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// event log fields indexed by json name, e.g. event_id
+var _pixelFields = func() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	typ := reflect.TypeOf(pb.EventLog{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || field.Type.Kind() == reflect.Map {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}()
+
+// DecodeHTTPPixelRequest is a transport/http.DecodeRequestFunc that
+// decodes a submitsingle request from the query parameters of an image pixel request.
+// Parameter names are the json names of EventLog fields, extend_info.{key} sets ExtendInfo[key].
+func DecodeHTTPPixelRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var req pb.EventLog
+
+	if err := decodeQuery(r.URL.Query(), &req); err != nil {
+		metrics.AddBadRequest("p:query", int32(http.StatusBadRequest))
+		return nil, httpError{errors.Wrap(err, "cannot parse query parameters"),
+			http.StatusBadRequest,
+			nil,
+		}
+	}
+
+	return &req, nil
+}
+
+func decodeQuery(query url.Values, req *pb.EventLog) error {
+	val := reflect.ValueOf(req).Elem()
+
+	for name, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+
+		if strings.HasPrefix(name, extendInfoPrefix) {
+			if req.ExtendInfo == nil {
+				req.ExtendInfo = make(map[string]string)
+			}
+			req.ExtendInfo[name[len(extendInfoPrefix):]] = value
+			continue
+		}
+
+		field, ok := _pixelFields[name]
+		if !ok || value == "" {
+			continue
+		}
+
+		fv := val.FieldByIndex(field.Index)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(value)
+		case reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+			if err != nil {
+				// enum accepts name, e.g. NETWORK_WIFI
+				if enum := enumName(field); enum != "" {
+					if v, ok := proto.EnumValueMap(enum)[value]; ok {
+						fv.SetInt(int64(v))
+						continue
+					}
+				}
+				return errors.Errorf("%s: invalid value %s", name, value)
+			}
+			fv.SetInt(n)
+		}
+	}
+
+	return nil
+}
+
+func enumName(field reflect.StructField) string {
+	for _, s := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(s, "enum=") {
+			return s[len("enum="):]
+		}
+	}
+	return ""
+}
+
+// queryKeyToContext takes api key from query parameter api_key, as image pixel can't set headers
+func queryKeyToContext(ctx context.Context, r *http.Request) context.Context {
+	if key := r.URL.Query().Get("api_key"); key != "" && ctx.Value("x-api-key") == nil {
+		ctx = context.WithValue(ctx, "x-api-key", key)
+	}
+
+	return ctx
+}
+
+// EncodeHTTPPixelResponse is a transport/http.EncodeResponseFunc that writes
+// a 1x1 gif to the response writer.
+func EncodeHTTPPixelResponse(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	_, err := w.Write(_pixel)
+
+	return err
+}
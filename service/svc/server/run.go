@@ -91,7 +91,7 @@ func Run(cfg *config.Config) {
 		}
 
 		srv := svc.MakeGRPCServer(endpoints)
-		s := grpc.NewServer()
+		s := grpc.NewServer(svc.GRPCServerOptions()...)
 		pb.RegisterLogServiceServer(s, srv)
 
 		errc <- s.Serve(ln)
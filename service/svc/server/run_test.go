@@ -2,17 +2,39 @@ package server
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"image/gif"
+	"io"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/techxmind/go-utils/stringutil"
 	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/deadletter"
+	"github.com/techxmind/logserver/errors"
 	pb "github.com/techxmind/logserver/interface-defs"
 	"github.com/techxmind/logserver/service/handlers"
 	"github.com/techxmind/logserver/service/svc"
@@ -67,6 +89,12 @@ func TestHttpJSONRequest(t *testing.T) {
 	ast.Equal("test-event1", msg.Topic)
 	v, _ := msg.Value.Marshal()
 	t.Log(string(v))
+
+	ast.Equal(storage.ContentTypeJSON, msg.Headers[storage.HeaderContentType])
+	ast.Equal("myapp", msg.Headers[storage.HeaderAppType])
+	ast.Equal("PV", msg.Headers[storage.HeaderEvent])
+	ast.NotEmpty(msg.Headers[storage.HeaderSchemaVersion])
+	ast.NotEmpty(msg.Headers[storage.HeaderLoggedTime])
 }
 
 func TestHttpProtobufRequest(t *testing.T) {
@@ -115,7 +143,7 @@ func TestHttpMulJSONRequest(t *testing.T) {
 
 	handler.ServeHTTP(writer, request)
 
-	ast.Equal("{}", writer.Body.String())
+	ast.Equal(`{"accepted":3}`, writer.Body.String())
 
 	msg := <-_testStorage.Successes()
 	ast.Equal("test-event2", msg.Topic)
@@ -125,6 +153,811 @@ func TestHttpMulJSONRequest(t *testing.T) {
 	ast.Equal("test-event2", msg.Topic)
 }
 
+func TestHttpMulRejectedEvents(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLogs := testEventLogs(3)
+	eventLogs.Events[1].Event = ""
+	eventLogs.Events[2].Event = "P-V"
+	postData, _ := json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+	request.Header.Add("Content-Length", strconv.Itoa(len(postData)))
+
+	handler.ServeHTTP(writer, request)
+
+	var resp pb.Response
+	ast.NoError(jsonpb.UnmarshalString(writer.Body.String(), &resp))
+	ast.Equal(int32(1), resp.Accepted)
+	ast.Equal(int32(2), resp.Rejected)
+	if ast.Len(resp.RejectedEvents, 2) {
+		ast.Equal(int32(1), resp.RejectedEvents[0].Index)
+		ast.Equal(eventLogs.Events[1].EventId, resp.RejectedEvents[0].EventId)
+		ast.Equal(int32(errors.ErrFieldRequired), resp.RejectedEvents[0].Code)
+		ast.Equal(int32(2), resp.RejectedEvents[1].Index)
+		ast.Equal(int32(errors.ErrFieldInvalid), resp.RejectedEvents[1].Code)
+	}
+
+	msg := <-_testStorage.Successes()
+	ast.Equal(eventLogs.Events[0].EventId, msg.Key)
+}
+
+func TestGRPCMulRejectedEvents(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	server := svc.MakeGRPCServer(endpoints)
+
+	eventLogs := testEventLogs(2)
+	eventLogs.Events[0].EventTime = 0
+
+	resp, err := server.SubmitMultiple(context.Background(), eventLogs)
+	if ast.NoError(err) {
+		ast.Equal(int32(1), resp.Accepted)
+		ast.Equal(int32(1), resp.Rejected)
+		if ast.Len(resp.RejectedEvents, 1) {
+			ast.Equal(int32(0), resp.RejectedEvents[0].Index)
+			ast.Equal(eventLogs.Events[0].EventId, resp.RejectedEvents[0].EventId)
+			ast.Equal(int32(errors.ErrFieldRequired), resp.RejectedEvents[0].Code)
+		}
+	}
+
+	msg := <-_testStorage.Successes()
+	ast.Equal(eventLogs.Events[1].EventId, msg.Key)
+}
+
+func TestHttpCompressedRequest(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLogs := testEventLogs(3)
+	jsonData, _ := json.Marshal(eventLogs)
+	pbData, _ := eventLogs.Marshal()
+
+	for _, c := range []struct {
+		encoding    string
+		contentType string
+		data        []byte
+	}{
+		{"gzip", "application/json", jsonData},
+		{"deflate", "application/json", jsonData},
+		{"deflate", "application/protobuf", pbData},
+		{"zstd", "application/protobuf", pbData},
+	} {
+		postData := testCompress(c.encoding, c.data)
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+		request.Header.Add("Content-Type", c.contentType)
+		request.Header.Add("Content-Encoding", c.encoding)
+		request.Header.Add("Content-Length", strconv.Itoa(len(postData)))
+
+		handler.ServeHTTP(writer, request)
+
+		ast.Equal(`{"accepted":3}`, writer.Body.String(), c.encoding)
+		for i := 0; i < 3; i++ {
+			msg := <-_testStorage.Successes()
+			ast.Equal(eventLogs.Events[i].EventId, msg.Key)
+		}
+	}
+}
+
+func TestHttpCompressedRequestError(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	postData, _ := json.Marshal(testEventLogs(3))
+
+	config.DefaultConfig.HTTP = &config.HTTPConfig{MaxBodySize: int64(len(postData) - 1)}
+	defer func() { config.DefaultConfig.HTTP = nil }()
+
+	for _, c := range []struct {
+		encoding string
+		data     []byte
+		code     int
+	}{
+		{"gzip", testCompress("gzip", postData), http.StatusRequestEntityTooLarge},
+		{"zstd", testCompress("zstd", postData), http.StatusRequestEntityTooLarge},
+		{"gzip", postData, http.StatusBadRequest},
+		{"br", postData, http.StatusUnsupportedMediaType},
+	} {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/mul", bytes.NewReader(c.data))
+		request.Header.Add("Content-Encoding", c.encoding)
+
+		handler.ServeHTTP(writer, request)
+
+		ast.Equal(c.code, writer.Code, c.encoding)
+	}
+}
+
+func TestHttpPixelRequest(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventTime := time.Now().UnixNano() / int64(1e6)
+	query := url.Values{}
+	query.Set("event_id", "pixel-id")
+	query.Set("event_time", strconv.FormatInt(eventTime, 10))
+	query.Set("event", "PV")
+	query.Set("app_type", "myapp")
+	query.Set("screen_width", "1024")
+	query.Set("network", "NETWORK_WIFI")
+	query.Set("carrier", "2")
+	query.Set("extend_info.foo", "bar")
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/p?"+query.Encode(), nil)
+	request.Header.Add("X-Forwarded-For", "124.78.41.83")
+	request.Header.Add("User-Agent", "test-ua")
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(http.StatusOK, writer.Code)
+	ast.Equal("image/gif", writer.Header().Get("Content-Type"))
+	img, err := gif.Decode(writer.Body)
+	if ast.NoError(err) {
+		ast.Equal(1, img.Bounds().Dx())
+		ast.Equal(1, img.Bounds().Dy())
+	}
+
+	msg := <-_testStorage.Successes()
+	ast.Equal("test-event1", msg.Topic)
+	ast.Equal("pixel-id", msg.Key)
+	v, _ := msg.Value.Marshal()
+	var event pb.EventLog
+	ast.NoError(json.Unmarshal(v, &event))
+	ast.Equal(eventTime, event.EventTime)
+	ast.Equal(int32(1024), event.ScreenWidth)
+	ast.Equal(pb.EventLog_NETWORK_WIFI, event.Network)
+	ast.Equal(pb.EventLog_CARRIER_CU, event.Carrier)
+	ast.Equal("bar", event.ExtendInfo["foo"])
+	ast.Equal("test-ua", event.UserAgent)
+	ast.Equal("124.78.41.83", event.Ip)
+
+	// invalid event is rejected
+	writer = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/p?event_id=x&event=PV&event_time=abc", nil)
+	handler.ServeHTTP(writer, request)
+	ast.Equal(http.StatusBadRequest, writer.Code)
+}
+
+func TestHttpTextPlainRequest(t *testing.T) {
+	ast := assert.New(t)
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLogs := testEventLogs(1)
+	postData, _ := json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+	request.Header.Add("Content-Type", "text/plain;charset=UTF-8")
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(`{"accepted":1}`, writer.Body.String())
+	msg := <-_testStorage.Successes()
+	ast.Equal(eventLogs.Events[0].EventId, msg.Key)
+}
+
+func TestHttpCORS(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.HTTP = &config.HTTPConfig{
+		CORS: &config.CORSConfig{
+			AllowedOrigins:   []string{"https://www.techxmind.com", "https://*.example.com"},
+			AllowedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           600,
+		},
+	}
+	defer func() { config.DefaultConfig.HTTP = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("OPTIONS", "/mul", nil)
+		request.Header.Add("Origin", origin)
+		request.Header.Add("Access-Control-Request-Method", "POST")
+		request.Header.Add("Access-Control-Request-Headers", "content-type")
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	for _, origin := range []string{"https://www.techxmind.com", "https://m.example.com", "https://a.b.example.com"} {
+		writer := preflight(origin)
+		ast.Equal(http.StatusNoContent, writer.Code, origin)
+		ast.Equal(origin, writer.Header().Get("Access-Control-Allow-Origin"))
+		ast.Equal("true", writer.Header().Get("Access-Control-Allow-Credentials"))
+		ast.Equal("600", writer.Header().Get("Access-Control-Max-Age"))
+		ast.Contains(writer.Header().Get("Access-Control-Allow-Headers"), "X-Request-Id")
+	}
+
+	for _, origin := range []string{"http://www.techxmind.com", "https://example.com", "https://evilexample.com"} {
+		writer := preflight(origin)
+		ast.Equal(http.StatusForbidden, writer.Code, origin)
+		ast.Empty(writer.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	eventLogs := testEventLogs(1)
+	postData, _ := json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+	request.Header.Add("Origin", "https://m.example.com")
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(`{"accepted":1}`, writer.Body.String())
+	ast.Equal("https://m.example.com", writer.Header().Get("Access-Control-Allow-Origin"))
+	<-_testStorage.Successes()
+
+	// origins that only match * get literal * without credentials
+	config.DefaultConfig.HTTP.CORS.AllowedOrigins = []string{"*", "https://www.techxmind.com"}
+	handler = svc.MakeHTTPHandler(endpoints)
+
+	writer = preflight("https://evil.com")
+	ast.Equal(http.StatusNoContent, writer.Code)
+	ast.Equal("*", writer.Header().Get("Access-Control-Allow-Origin"))
+	ast.Empty(writer.Header().Get("Access-Control-Allow-Credentials"))
+
+	writer = preflight("https://www.techxmind.com")
+	ast.Equal("https://www.techxmind.com", writer.Header().Get("Access-Control-Allow-Origin"))
+	ast.Equal("true", writer.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestAuth(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.Auth = &config.AuthConfig{
+		Apps: []*config.AppAuth{
+			{Key: "key1", AppTypes: []string{"myapp", "myapp1"}},
+			{Key: "key2", Secret: "secret2", AppTypes: []string{"myapp1"}},
+		},
+	}
+	ast.NoError(config.DefaultConfig.Auth.Validate())
+	defer func() { config.DefaultConfig.Auth = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+	grpcServer := svc.MakeGRPCServer(endpoints)
+	// raw body is kept only if any app signs requests
+	ast.True(svc.RawBodyRequired())
+	config.DefaultConfig.Auth.Apps[1].Secret = ""
+	ast.False(svc.RawBodyRequired())
+	config.DefaultConfig.Auth.Apps[1].Secret = "secret2"
+
+	sign := func(secret string, ts int64, body []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(strconv.FormatInt(ts, 10) + "\n"))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	eventLogs := testEventLogs(2)
+	postData, _ := json.Marshal(eventLogs)
+	now := time.Now().Unix()
+
+	for _, c := range []struct {
+		name    string
+		key     string
+		ts      int64
+		sign    string
+		code    int
+		success bool
+	}{
+		{"api key", "key1", 0, "", http.StatusOK, true},
+		{"no key", "", 0, "", http.StatusUnauthorized, false},
+		{"invalid key", "key3", 0, "", http.StatusUnauthorized, false},
+		{"hmac", "key2", now, sign("secret2", now, postData), http.StatusOK, true},
+		{"no signature", "key2", now, "", http.StatusUnauthorized, false},
+		{"wrong signature", "key2", now, sign("secret1", now, postData), http.StatusUnauthorized, false},
+		{"replay", "key2", now - 600, sign("secret2", now-600, postData), http.StatusUnauthorized, false},
+	} {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+		request.Header.Add("X-Api-Key", c.key)
+		if c.ts > 0 {
+			request.Header.Add("X-Timestamp", strconv.FormatInt(c.ts, 10))
+			request.Header.Add("X-Signature", c.sign)
+		}
+
+		handler.ServeHTTP(writer, request)
+
+		ast.Equal(c.code, writer.Code, c.name)
+		if c.success {
+			<-_testStorage.Successes()
+			<-_testStorage.Successes()
+		}
+	}
+
+	// app_type is not allowed for key2
+	eventLogs.Events[1].AppType = "myapp"
+	postData, _ = json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+	request.Header.Add("X-Api-Key", "key2")
+	request.Header.Add("X-Timestamp", strconv.FormatInt(now, 10))
+	request.Header.Add("X-Signature", sign("secret2", now, postData))
+	handler.ServeHTTP(writer, request)
+	ast.Equal(http.StatusForbidden, writer.Code)
+
+	// gRPC signs the request message as it's sent, bytes of a decoded request marshaled again may differ
+	eventLogs = testEventLogs(1)
+	eventLogs.Events[0].ExtendInfo = map[string]string{"k1": "v1", "k2": "v2", "k3": "v3", "k4": "v4"}
+	pbData, _ := eventLogs.Marshal()
+	// unknown field 100 of newer clients
+	pbData = append(pbData, 0xa0, 0x06, 0x01)
+	conn := testGRPCConn(t, grpcServer)
+	invoke := func(pairs ...string) error {
+		ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(pairs...))
+		return conn.Invoke(ctx, "/LogService/SubmitMultiple", pbData, &pb.Response{}, grpc.ForceCodec(rawCodec{}))
+	}
+	ast.NoError(invoke(
+		"x-api-key", "key2",
+		"x-timestamp", strconv.FormatInt(now, 10),
+		"x-signature", sign("secret2", now, pbData),
+	))
+	<-_testStorage.Successes()
+
+	ast.Equal(codes.Unauthenticated, status.Code(invoke("x-api-key", "key2")))
+	ast.Equal(codes.Unauthenticated, status.Code(invoke(
+		"x-api-key", "key2",
+		"x-timestamp", strconv.FormatInt(now, 10),
+		"x-signature", sign("secret1", now, pbData),
+	)))
+
+	// request message is unavailable if it's not received by gRPC server
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-api-key", "key2",
+		"x-timestamp", strconv.FormatInt(now, 10),
+		"x-signature", sign("secret2", now, pbData),
+	))
+	_, err := grpcServer.SubmitMultiple(ctx, eventLogs)
+	ast.Equal(codes.Unauthenticated, status.Code(err))
+
+	// image pixel uses api key in query
+	writer = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/p?event_id=x&event=PV&app_type=myapp&event_time=1&api_key=key1", nil)
+	handler.ServeHTTP(writer, request)
+	ast.Equal(http.StatusOK, writer.Code)
+	<-_testStorage.Successes()
+}
+
+func TestRateLimit(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.RateLimit = &config.RateLimitConfig{
+		Routes: map[string]*config.RouteLimit{
+			"SubmitMultiple": {
+				AppType:  &config.Limit{Rate: 0.1, Burst: 3},
+				AppTypes: map[string]*config.Limit{"myapp": {Rate: 100}},
+			},
+			"default": {
+				IP: &config.Limit{Rate: 0.1, Burst: 1},
+			},
+		},
+	}
+	defer func() { config.DefaultConfig.RateLimit = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+	grpcServer := svc.MakeGRPCServer(endpoints)
+
+	post := func(path string, data interface{}) *httptest.ResponseRecorder {
+		postData, _ := json.Marshal(data)
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", path, bytes.NewReader(postData))
+		request.Header.Add("X-Forwarded-For", "124.78.41.83")
+		handler.ServeHTTP(writer, request)
+		return writer
+	}
+
+	// app_type myapp1 takes 3 tokens of burst
+	writer := post("/mul", testEventLogs(3))
+	ast.Equal(http.StatusOK, writer.Code)
+	for i := 0; i < 3; i++ {
+		<-_testStorage.Successes()
+	}
+
+	writer = post("/mul", testEventLogs(1))
+	ast.Equal(http.StatusTooManyRequests, writer.Code)
+	ast.Equal("10", writer.Header().Get("Retry-After"))
+
+	_, err := grpcServer.SubmitMultiple(context.Background(), testEventLogs(1))
+	ast.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// app_type myapp has its own limit
+	eventLogs := testEventLogs(2)
+	eventLogs.Common.AppType = "myapp"
+	writer = post("/mul", eventLogs)
+	ast.Equal(http.StatusOK, writer.Code)
+	<-_testStorage.Successes()
+	<-_testStorage.Successes()
+
+	// SubmitSingle is limited by client ip
+	writer = post("/s", testEventLog())
+	ast.Equal(http.StatusOK, writer.Code)
+	<-_testStorage.Successes()
+	writer = post("/s", testEventLog())
+	ast.Equal(http.StatusTooManyRequests, writer.Code)
+
+	// gRPC requests without peer have no client ip
+	_, err = grpcServer.SubmitSingle(context.Background(), testEventLog())
+	ast.NoError(err)
+	<-_testStorage.Successes()
+
+	// client ip of gRPC requests is the peer address, it can't be overridden by metadata
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("remote-ip", "124.78.41.84"))
+	_, err = grpcServer.SubmitSingle(ctx, testEventLog())
+	ast.NoError(err)
+	<-_testStorage.Successes()
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("remote-ip", "124.78.41.85"))
+	_, err = grpcServer.SubmitSingle(ctx, testEventLog())
+	ast.Equal(codes.ResourceExhausted, status.Code(err))
+}
+
+func TestDeadLetter(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.Storage.DeadLetter = &config.DeadLetterConfig{Topic: "test-dead-letter"}
+	defer func() { config.DefaultConfig.Storage.DeadLetter = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLogs := testEventLogs(2)
+	eventLogs.Events[0].EventTime = 0
+	postData, _ := json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+	request.Header.Add("X-Forwarded-For", "124.78.41.83")
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(http.StatusOK, writer.Code)
+
+	msg := <-_testStorage.Successes()
+	ast.Equal("test-dead-letter", msg.Topic)
+	ast.Equal(eventLogs.Events[0].EventId, msg.Key)
+	v, _ := msg.Value.Marshal()
+	var letter deadletter.Letter
+	ast.NoError(json.Unmarshal(v, &letter))
+	ast.Equal(deadletter.ReasonInvalid, letter.Reason)
+	ast.Equal(errors.ErrFieldRequired.ErrorCode(), letter.Code)
+	ast.Equal("124.78.41.83", letter.SourceIP)
+	// data is the event as it's submitted with common properties, it's not enriched
+	var event pb.EventLog
+	ast.NoError(event.Unmarshal(letter.Data))
+	ast.Equal(eventLogs.Events[0].EventId, event.EventId)
+	ast.Equal(eventLogs.Common.Udid, event.Udid)
+	ast.Empty(event.Ip)
+	ast.Zero(event.LoggedTime)
+
+	msg = <-_testStorage.Successes()
+	ast.Equal(eventLogs.Events[1].EventId, msg.Key)
+}
+
+func TestDeadLetterPrivacy(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.Storage.DeadLetter = &config.DeadLetterConfig{Topic: "test-dead-letter"}
+	config.DefaultConfig.Privacy = &config.PrivacyConfig{
+		Salt: "test-salt",
+		Policies: map[string]*config.PrivacyPolicy{
+			"default": {
+				Fields: map[string]string{
+					"imei": "drop", "mac": "drop", "idfa": "hash", "oaid": "hash", "android_id": "hash",
+					"lat": "truncate", "lon": "drop",
+				},
+			},
+		},
+	}
+	ast.NoError(config.DefaultConfig.Privacy.Validate())
+	defer func() {
+		config.DefaultConfig.Storage.DeadLetter = nil
+		config.DefaultConfig.Privacy = nil
+	}()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	raw := &pb.EventLog{
+		Imei: "860000000000001", Mac: "02:00:00:00:00:01", Idfa: "idfa-raw", Oaid: "oaid-raw",
+		AndroidId: "android-raw", Lat: "31.230416", Lon: "121.473701",
+	}
+	single := testEventLog()
+	single.EventTime = 0
+	multiple := testEventLogs(1)
+	multiple.Events[0].EventTime = 0
+	multiple.Common.AndroidId = raw.AndroidId
+	for _, event := range []*pb.EventLog{single, multiple.Events[0]} {
+		event.Imei, event.Mac, event.Idfa, event.Oaid = raw.Imei, raw.Mac, raw.Idfa, raw.Oaid
+		event.Lat, event.Lon = raw.Lat, raw.Lon
+	}
+	single.AndroidId = raw.AndroidId
+
+	for path, data := range map[string]interface{}{"/s": single, "/mul": multiple} {
+		postData, _ := json.Marshal(data)
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", path, bytes.NewReader(postData))
+		handler.ServeHTTP(writer, request)
+
+		msg := <-_testStorage.Successes()
+		ast.Equal("test-dead-letter", msg.Topic, path)
+		v, _ := msg.Value.Marshal()
+		var letter deadletter.Letter
+		ast.NoError(json.Unmarshal(v, &letter))
+
+		// raw identifiers are never kept in dead letters
+		for _, value := range []string{raw.Imei, raw.Mac, raw.Idfa, raw.Oaid, raw.AndroidId, raw.Lat, raw.Lon} {
+			ast.NotContains(string(letter.Data), value, path)
+		}
+		var event pb.EventLog
+		ast.NoError(event.Unmarshal(letter.Data))
+		ast.Empty(event.Imei, path)
+		ast.Empty(event.Lon, path)
+		ast.Len(event.Idfa, 64, path)
+		ast.Len(event.AndroidId, 64, path)
+		ast.Equal("31.23", event.Lat, path)
+	}
+}
+
+func TestPrivacy(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.Privacy = &config.PrivacyConfig{
+		Salt: "test-salt",
+		Policies: map[string]*config.PrivacyPolicy{
+			"myapp": {
+				Fields: map[string]string{"ip": "truncate", "idfa": "hash", "lat": "truncate", "lon": "drop"},
+			},
+		},
+	}
+	ast.NoError(config.DefaultConfig.Privacy.Validate())
+	defer func() { config.DefaultConfig.Privacy = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLog := testEventLog()
+	eventLog.Lat, eventLog.Lon = "31.230416", "121.473701"
+	postData, _ := json.Marshal(eventLog)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/s", bytes.NewReader(postData))
+	request.Header.Add("X-Forwarded-For", "124.78.41.83")
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(http.StatusOK, writer.Code)
+
+	msg := <-_testStorage.Successes()
+	v, _ := msg.Value.Marshal()
+	var event pb.EventLog
+	ast.NoError(json.Unmarshal(v, &event))
+	ast.Equal("124.78.41.0", event.Ip)
+	ast.Len(event.Idfa, 64)
+	ast.NotEqual(eventLog.Idfa, event.Idfa)
+	ast.Equal("31.23", event.Lat)
+	ast.Equal("", event.Lon)
+	ast.Equal(eventLog.Udid, event.Udid)
+	// ip location is filled before ip is truncated
+	ast.NotEmpty(event.IpCity)
+}
+
+func TestDedup(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.Dedup = &config.DedupConfig{}
+	defer func() { config.DefaultConfig.Dedup = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLogs := testEventLogs(2)
+	eventLogs.Events[1].EventId = eventLogs.Events[0].EventId
+	postData, _ := json.Marshal(eventLogs)
+
+	// retried batch
+	for i := 0; i < 2; i++ {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+		handler.ServeHTTP(writer, request)
+		ast.Equal(`{"accepted":2}`, writer.Body.String())
+	}
+
+	msg := <-_testStorage.Successes()
+	ast.Equal(eventLogs.Events[0].EventId, msg.Key)
+	select {
+	case msg = <-_testStorage.Successes():
+		t.Errorf("duplicated event is written: %s", msg.Key)
+	default:
+	}
+}
+
+func TestEventTime(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.EventTime = &config.EventTimeConfig{Action: config.EventTimeReject}
+	defer func() { config.DefaultConfig.EventTime = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	// client clock is 2 hours ahead, the event happened 1 minute ago
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	eventLogs := testEventLogs(2)
+	eventLogs.SendTime = now + 7200000
+	eventLogs.Events[0].EventTime = now + 7200000 - 60000
+	eventLogs.Events[1].EventTime = now - 40*86400000
+	postData, _ := json.Marshal(eventLogs)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/mul", bytes.NewReader(postData))
+
+	handler.ServeHTTP(writer, request)
+
+	var resp pb.Response
+	ast.NoError(jsonpb.UnmarshalString(writer.Body.String(), &resp))
+	ast.Equal(int32(1), resp.Accepted)
+	if ast.Len(resp.RejectedEvents, 1) {
+		ast.Equal(int32(1), resp.RejectedEvents[0].Index)
+		ast.Equal(int32(errors.ErrFieldInvalid), resp.RejectedEvents[0].Code)
+	}
+
+	msg := <-_testStorage.Successes()
+	v, _ := msg.Value.Marshal()
+	var event pb.EventLog
+	ast.NoError(json.Unmarshal(v, &event))
+	ast.Equal(eventLogs.Events[0].EventTime, event.EventTime)
+	ast.InDelta(-7200000, event.ClockSkew, 1000)
+	ast.InDelta(now-60000, event.CorrectedEventTime, 1000)
+
+	// flag
+	config.DefaultConfig.EventTime.Action = config.EventTimeFlag
+	service = handlers.NewService()
+	handler = svc.MakeHTTPHandler(NewEndpoints(service))
+
+	eventLog := testEventLog()
+	eventLog.EventTime = now + 7200000
+	postData, _ = json.Marshal(eventLog)
+	writer = httptest.NewRecorder()
+	request = httptest.NewRequest("POST", "/s", bytes.NewReader(postData))
+	request.Header.Add("X-Send-Time", strconv.FormatInt(now, 10))
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(http.StatusOK, writer.Code)
+	msg = <-_testStorage.Successes()
+	v, _ = msg.Value.Marshal()
+	event = pb.EventLog{}
+	ast.NoError(json.Unmarshal(v, &event))
+	ast.Equal("future", event.EventTimeFlag)
+}
+
+func TestFanOut(t *testing.T) {
+	ast := assert.New(t)
+
+	config.DefaultConfig.TopicRouter.Rules = []*config.RouteRule{
+		{
+			Destinations: []*config.Destination{{Topic: "test-firehose", DataType: "protobuf"}},
+			Continue:     true,
+		},
+		{
+			Match: []*config.RouteCondition{{Field: "event", Value: "pay"}},
+			Destinations: []*config.Destination{
+				{Topic: "test-finance", Fields: []string{"event_id", "mid"}, Key: "mid"},
+			},
+		},
+	}
+	defer func() { config.DefaultConfig.TopicRouter.Rules = nil }()
+
+	service := handlers.NewService()
+	endpoints := NewEndpoints(service)
+	handler := svc.MakeHTTPHandler(endpoints)
+
+	eventLog := testEventLog()
+	eventLog.Event = "pay"
+	postData, _ := json.Marshal(eventLog)
+	writer := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/s", bytes.NewReader(postData))
+
+	handler.ServeHTTP(writer, request)
+
+	ast.Equal(http.StatusOK, writer.Code)
+
+	msg := <-_testStorage.Successes()
+	ast.Equal("test-firehose", msg.Topic)
+	ast.Equal(eventLog.EventId, msg.Key)
+	v, _ := msg.Value.Marshal()
+	var event pb.EventLog
+	ast.NoError(event.Unmarshal(v))
+	ast.Equal(eventLog.Udid, event.Udid)
+
+	msg = <-_testStorage.Successes()
+	ast.Equal("test-finance", msg.Topic)
+	ast.Equal(eventLog.Mid, msg.Key)
+	v, _ = msg.Value.Marshal()
+	ast.JSONEq(fmt.Sprintf(`{"event_id":%q,"mid":%q}`, eventLog.EventId, eventLog.Mid), string(v))
+}
+
+func testCompress(encoding string, data []byte) []byte {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	}
+	w.Write(data)
+	w.Close()
+
+	return buf.Bytes()
+}
+
+// rawCodec sends bytes as they're, responses are decoded as protobuf
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	return v.(interface{ Unmarshal([]byte) error }).Unmarshal(data)
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// testGRPCConn serves the gRPC server in memory and returns a client connection
+func testGRPCConn(t *testing.T, server pb.LogServiceServer) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(svc.GRPCServerOptions()...)
+	pb.RegisterLogServiceServer(s, server)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
 func testEventLogs(n int) *pb.EventLogs {
 	logs := &pb.EventLogs{
 		Common: testEventLogCommon(),
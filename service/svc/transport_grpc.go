@@ -10,9 +10,15 @@ package svc
 
 import (
 	"context"
+	"net"
 	"net/http"
 
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 
@@ -62,7 +68,7 @@ type grpcServer struct {
 func (s *grpcServer) SubmitSingle(ctx context.Context, req *pb.EventLog) (*pb.Response, error) {
 	_, rep, err := s.submitsingle.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(ctx, err)
 	}
 	return rep.(*pb.Response), nil
 }
@@ -70,7 +76,7 @@ func (s *grpcServer) SubmitSingle(ctx context.Context, req *pb.EventLog) (*pb.Re
 func (s *grpcServer) SubmitMultiple(ctx context.Context, req *pb.EventLogs) (*pb.Response, error) {
 	_, rep, err := s.submitmultiple.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(ctx, err)
 	}
 	return rep.(*pb.Response), nil
 }
@@ -78,7 +84,7 @@ func (s *grpcServer) SubmitMultiple(ctx context.Context, req *pb.EventLogs) (*pb
 func (s *grpcServer) Ping(ctx context.Context, req *pb.Empty) (*pb.Response, error) {
 	_, rep, err := s.ping.ServeGRPC(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, grpcError(ctx, err)
 	}
 	return rep.(*pb.Response), nil
 }
@@ -131,6 +137,39 @@ func EncodeGRPCPingResponse(_ context.Context, response interface{}) (interface{
 
 // Helpers
 
+// GRPCCoder is implemented by errors that map to gRPC status code
+type GRPCCoder interface {
+	GRPCCode() codes.Code
+}
+
+// Headerer is implemented by errors that carry response headers
+type Headerer interface {
+	Headers() http.Header
+}
+
+// grpcError converts error to gRPC status error.
+// Headers of the error, e.g. Retry-After, are sent as trailer metadata.
+func grpcError(ctx context.Context, err error) error {
+	headerer, ok := err.(Headerer)
+	if !ok {
+		headerer, ok = errors.Cause(err).(Headerer)
+	}
+	if ok && len(headerer.Headers()) > 0 {
+		md := metadata.MD{}
+		for k, v := range headerer.Headers() {
+			md.Append(k, v...)
+		}
+		grpc.SetTrailer(ctx, md)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if coder, ok := errors.Cause(err).(GRPCCoder); ok {
+		return status.Error(coder.GRPCCode(), err.Error())
+	}
+	return err
+}
+
 func metadataToContext(ctx context.Context, md metadata.MD) context.Context {
 	for k, v := range md {
 		if v != nil {
@@ -142,5 +181,24 @@ func metadataToContext(ctx context.Context, md metadata.MD) context.Context {
 		}
 	}
 
-	return ctx
+	// set after metadata, so that clients can't override them
+	ctx = context.WithValue(ctx, "remote-ip", peerIP(ctx))
+
+	return rawMessageToContext(ctx)
+}
+
+// peerIP returns ip address of the connection of gRPC request
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+
+	return ""
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/techxmind/go-utils/access"
 
 	// This service
+	"github.com/techxmind/logserver/config"
 	pb "github.com/techxmind/logserver/interface-defs"
 	"github.com/techxmind/logserver/logger"
 	"github.com/techxmind/logserver/metrics"
@@ -52,7 +53,7 @@ var (
 // on predefined paths.
 func MakeHTTPHandler(endpoints Endpoints, options ...httptransport.ServerOption) http.Handler {
 	serverOptions := []httptransport.ServerOption{
-		httptransport.ServerBefore(headersToContext),
+		httptransport.ServerBefore(headersToContext, bodyToContext),
 		httptransport.ServerErrorEncoder(errorEncoder),
 		httptransport.ServerAfter(httptransport.SetContentType(contentType)),
 	}
@@ -73,13 +74,21 @@ func MakeHTTPHandler(endpoints Endpoints, options ...httptransport.ServerOption)
 		serverOptions...,
 	))
 
+	// image pixel, e.g. <img src="/p?event_id=xx&event_time=xx&event=xx&extend_info.foo=bar">
+	m.Methods("GET").Path("/p").Handler(httptransport.NewServer(
+		endpoints.SubmitSingleEndpoint,
+		DecodeHTTPPixelRequest,
+		EncodeHTTPPixelResponse,
+		append(serverOptions[:len(serverOptions):len(serverOptions)], httptransport.ServerBefore(queryKeyToContext))...,
+	))
+
 	m.Methods("GET").Path("/ping").Handler(httptransport.NewServer(
 		endpoints.PingEndpoint,
 		DecodeHTTPPingZeroRequest,
 		EncodeHTTPGenericResponse,
 		serverOptions...,
 	))
-	return m
+	return withCORS(config.DefaultConfig.HTTP.GetCORS(), m)
 }
 
 // ErrorEncoder writes the error to the ResponseWriter, by default a content
@@ -105,10 +114,16 @@ func errorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 		for k := range headerer.Headers() {
 			w.Header().Set(k, headerer.Headers().Get(k))
 		}
+	} else if headerer, ok := errors.Cause(err).(httptransport.Headerer); ok {
+		for k := range headerer.Headers() {
+			w.Header().Set(k, headerer.Headers().Get(k))
+		}
 	}
 	code := http.StatusInternalServerError
 	if sc, ok := err.(httptransport.StatusCoder); ok {
 		code = sc.StatusCode()
+	} else if sc, ok := errors.Cause(err).(httptransport.StatusCoder); ok {
+		code = sc.StatusCode()
 	}
 	w.WriteHeader(code)
 	w.Write(body)
@@ -149,9 +164,9 @@ func (h httpError) Headers() http.Header {
 func DecodeHTTPSubmitSingleZeroRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	defer r.Body.Close()
 	var req pb.EventLog
-	buf, err := ioutil.ReadAll(r.Body)
+	buf, err := readBody(r)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot read body of http request")
+		return nil, err
 	}
 	if len(buf) > 0 {
 		if c := r.Header.Get("Content-Type"); strings.Contains(c, "protobuf") {
@@ -163,6 +178,7 @@ func DecodeHTTPSubmitSingleZeroRequest(_ context.Context, r *http.Request) (inte
 				}
 			}
 		} else {
+			// JSON body, including text/plain sent by navigator.sendBeacon.
 			// AllowUnknownFields stops the unmarshaler from failing if the JSON contains unknown fields.
 			unmarshaller := jsonpb.Unmarshaler{
 				AllowUnknownFields: true,
@@ -196,9 +212,9 @@ func DecodeHTTPSubmitSingleZeroRequest(_ context.Context, r *http.Request) (inte
 func DecodeHTTPSubmitMultipleZeroRequest(_ context.Context, r *http.Request) (interface{}, error) {
 	defer r.Body.Close()
 	var req pb.EventLogs
-	buf, err := ioutil.ReadAll(r.Body)
+	buf, err := readBody(r)
 	if err != nil {
-		return nil, errors.Wrapf(err, "cannot read body of http request")
+		return nil, err
 	}
 	if len(buf) > 0 {
 		if c := r.Header.Get("Content-Type"); strings.Contains(c, "protobuf") {
@@ -210,6 +226,7 @@ func DecodeHTTPSubmitMultipleZeroRequest(_ context.Context, r *http.Request) (in
 				}
 			}
 		} else {
+			// JSON body, including text/plain sent by navigator.sendBeacon.
 			// AllowUnknownFields stops the unmarshaler from failing if the JSON contains unknown fields.
 			unmarshaller := jsonpb.Unmarshaler{
 				AllowUnknownFields: true,
@@ -1,5 +1,9 @@
 package storage
 
+import (
+	"context"
+)
+
 type Group struct {
 	group []Storager
 }
@@ -31,6 +35,15 @@ func (g *Group) Write(msg *Message) (err error) {
 	return
 }
 
+func (g *Group) WriteSync(ctx context.Context, msg *Message) (err error) {
+	for _, storager := range g.group {
+		if ierr := WriteSync(ctx, storager, msg); ierr != nil {
+			err = ierr
+		}
+	}
+	return
+}
+
 func (g *Group) Close() (err error) {
 	for _, storager := range g.group {
 		if ierr := storager.Close(); ierr != nil {
@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/techxmind/logserver/config"
+)
+
+// NewPartitioner returns partitioner constructor by name, see config.KafkaConfig.Partitioner.
+// Messages without key are distributed round-robin.
+func NewPartitioner(name string) (sarama.PartitionerConstructor, error) {
+	var keyed sarama.PartitionerConstructor
+
+	switch name {
+	case "", config.PartitionerHash:
+		keyed = sarama.NewHashPartitioner
+	case config.PartitionerMurmur2:
+		keyed = newMurmur2Partitioner
+	case config.PartitionerManual:
+		keyed = newKeyNumberPartitioner
+	case config.PartitionerRandom:
+		keyed = sarama.NewRandomPartitioner
+	case config.PartitionerRoundRobin:
+		keyed = sarama.NewRoundRobinPartitioner
+	default:
+		return nil, errors.Errorf("unknown partitioner %s", name)
+	}
+
+	return func(topic string) sarama.Partitioner {
+		return &partitioner{
+			keyed:   keyed(topic),
+			keyless: sarama.NewRoundRobinPartitioner(topic),
+		}
+	}, nil
+}
+
+type partitioner struct {
+	keyed   sarama.Partitioner
+	keyless sarama.Partitioner
+}
+
+func (p *partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Key == nil {
+		return p.keyless.Partition(msg, numPartitions)
+	}
+
+	return p.keyed.Partition(msg, numPartitions)
+}
+
+func (p *partitioner) RequiresConsistency() bool {
+	return p.keyed.RequiresConsistency()
+}
+
+// MessageRequiresConsistency implements sarama.DynamicConsistencyPartitioner
+func (p *partitioner) MessageRequiresConsistency(msg *sarama.ProducerMessage) bool {
+	return msg.Key != nil && p.keyed.RequiresConsistency()
+}
+
+// murmur2Partitioner is the same as the default partitioner of java producer:
+// toPositive(murmur2(key)) % numPartitions
+type murmur2Partitioner struct{}
+
+func newMurmur2Partitioner(string) sarama.Partitioner {
+	return murmur2Partitioner{}
+}
+
+func (murmur2Partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	key, err := msg.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+
+	return int32(murmur2(key)&0x7fffffff) % numPartitions, nil
+}
+
+func (murmur2Partitioner) RequiresConsistency() bool {
+	return true
+}
+
+// keyNumberPartitioner takes key as the partition number
+type keyNumberPartitioner struct{}
+
+func newKeyNumberPartitioner(string) sarama.Partitioner {
+	return keyNumberPartitioner{}
+}
+
+func (keyNumberPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	key, err := msg.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+
+	partition, err := strconv.ParseInt(string(key), 10, 32)
+	if err != nil || partition < 0 || int32(partition) >= numPartitions {
+		return -1, sarama.ErrInvalidPartition
+	}
+
+	return int32(partition), nil
+}
+
+func (keyNumberPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// murmur2 is the murmur2 hash of java client, org.apache.kafka.common.utils.Utils.murmur2
+func murmur2(data []byte) uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return h
+}
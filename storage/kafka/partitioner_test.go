@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMurmur2(t *testing.T) {
+	// values of java client org.apache.kafka.common.utils.UtilsTest
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+
+	ast := assert.New(t)
+	for key, expect := range tests {
+		ast.Equal(expect, int32(murmur2([]byte(key))), key)
+	}
+}
+
+func TestPartitioner(t *testing.T) {
+	ast := assert.New(t)
+
+	_, err := NewPartitioner("unknown")
+	ast.Error(err)
+
+	newPartitioner, err := NewPartitioner("murmur2")
+	ast.NoError(err)
+	p := newPartitioner("test")
+
+	msg := &sarama.ProducerMessage{Key: sarama.StringEncoder("foobar")}
+	partition, err := p.Partition(msg, 10)
+	ast.NoError(err)
+	// toPositive(-790332482) % 10
+	ast.Equal(int32((-790332482&0x7fffffff)%10), partition)
+	ast.True(p.(sarama.DynamicConsistencyPartitioner).MessageRequiresConsistency(msg))
+
+	// messages without key are distributed round-robin
+	keyless := &sarama.ProducerMessage{}
+	for i := int32(0); i < 3; i++ {
+		partition, err = p.Partition(keyless, 3)
+		ast.NoError(err)
+		ast.Equal(i, partition)
+	}
+	ast.False(p.(sarama.DynamicConsistencyPartitioner).MessageRequiresConsistency(keyless))
+
+	newPartitioner, err = NewPartitioner("manual")
+	ast.NoError(err)
+	p = newPartitioner("test")
+	partition, err = p.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder("2")}, 3)
+	ast.NoError(err)
+	ast.Equal(int32(2), partition)
+	_, err = p.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder("3")}, 3)
+	ast.Error(err)
+}
@@ -1,8 +1,11 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/Shopify/sarama"
 	"github.com/pkg/errors"
@@ -20,6 +23,16 @@ var (
 type kafkaStorage struct {
 	quit     chan bool
 	producer sarama.AsyncProducer
+
+	mu      sync.RWMutex
+	onError func(*storage.Message, error)
+}
+
+// envelope is attached to sarama.ProducerMessage.Metadata
+type envelope struct {
+	msg *storage.Message
+	// receives the delivery result of synchronous write
+	result chan error
 }
 
 func New(cfg *config.KafkaConfig) (storage.Storager, error) {
@@ -36,7 +49,22 @@ func New(cfg *config.KafkaConfig) (storage.Storager, error) {
 		return nil, errors.New("Kafka storage address configuration is missing")
 	}
 
-	producer, err := sarama.NewAsyncProducer(addrs, cfg.ProducerConfig)
+	var err error
+
+	producerConfig := sarama.NewConfig()
+	if cfg.ProducerConfig != nil {
+		*producerConfig = *cfg.ProducerConfig
+	}
+	if err = cfg.Config.Apply(producerConfig); err != nil {
+		return nil, errors.Wrap(err, "New kafkaStorage")
+	}
+	// required by synchronous write
+	producerConfig.Producer.Return.Successes = true
+	if producerConfig.Producer.Partitioner, err = NewPartitioner(cfg.Partitioner); err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewAsyncProducer(addrs, producerConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "New kafkaStorage")
 	}
@@ -59,9 +87,14 @@ func (s *kafkaStorage) daemon() {
 MainLoop:
 	for {
 		select {
+		case msg := <-s.producer.Successes():
+			if env, ok := msg.Metadata.(*envelope); ok && env.result != nil {
+				env.result <- nil
+			}
 		case err := <-s.producer.Errors():
 			metrics.CounterAdd("kafka_error", 1)
-			logger.Errorf("Producer", "err", err)
+			logger.Error("Producer", "err", err)
+			s.handleError(err)
 		case <-s.quit:
 			s.quit <- true
 			break MainLoop
@@ -70,19 +103,73 @@ MainLoop:
 }
 
 func (s *kafkaStorage) Write(msg *storage.Message) error {
-	bs, err := msg.Value.Marshal()
+	pmsg, err := s.producerMessage(msg, nil)
 	if err != nil {
 		return err
 	}
+
+	s.producer.Input() <- pmsg
+
+	return nil
+}
+
+// WriteSync implements storage.SyncWriter, it waits for the broker ack.
+// The message may still be delivered after ctx is done.
+func (s *kafkaStorage) WriteSync(ctx context.Context, msg *storage.Message) error {
+	result := make(chan error, 1)
+	pmsg, err := s.producerMessage(msg, result)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.producer.Input() <- pmsg:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *kafkaStorage) producerMessage(msg *storage.Message, result chan error) (*sarama.ProducerMessage, error) {
+	bs, err := msg.Value.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
 	pmsg := &sarama.ProducerMessage{
 		Topic: msg.Topic,
-		Key:   sarama.StringEncoder(msg.Key),
 		Value: sarama.ByteEncoder(bs),
+		Metadata: &envelope{
+			msg:    msg,
+			result: result,
+		},
+	}
+	// messages without key are distributed round-robin
+	if msg.Key != "" {
+		pmsg.Key = sarama.StringEncoder(msg.Key)
+	}
+	if len(msg.Headers) > 0 {
+		names := make([]string, 0, len(msg.Headers))
+		for name := range msg.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pmsg.Headers = make([]sarama.RecordHeader, 0, len(names))
+		for _, name := range names {
+			pmsg.Headers = append(pmsg.Headers, sarama.RecordHeader{
+				Key:   []byte(name),
+				Value: []byte(msg.Headers[name]),
+			})
+		}
 	}
 
-	s.producer.Input() <- pmsg
-
-	return nil
+	return pmsg, nil
 }
 
 func (s *kafkaStorage) Close() (err error) {
@@ -92,7 +179,55 @@ func (s *kafkaStorage) Close() (err error) {
 
 	if s.producer != nil {
 		err = s.producer.Close()
+		// messages failed while flushing
+		if errs, ok := err.(sarama.ProducerErrors); ok {
+			for _, perr := range errs {
+				s.handleError(perr)
+			}
+		}
 	}
 
 	return
 }
+
+// NotifyError implements storage.ErrorNotifier
+func (s *kafkaStorage) NotifyError(fn func(*storage.Message, error)) {
+	s.mu.Lock()
+	s.onError = fn
+	s.mu.Unlock()
+}
+
+// handleError returns the error to synchronous writer,
+// or notifies the error handler in asynchronous mode
+func (s *kafkaStorage) handleError(err *sarama.ProducerError) {
+	if err.Msg == nil {
+		return
+	}
+
+	env, _ := err.Msg.Metadata.(*envelope)
+	if env != nil && env.result != nil {
+		env.result <- err.Err
+		return
+	}
+
+	s.mu.RLock()
+	fn := s.onError
+	s.mu.RUnlock()
+
+	if fn == nil {
+		return
+	}
+
+	msg := &storage.Message{
+		Topic: err.Msg.Topic,
+	}
+	if env != nil {
+		*msg = *env.msg
+	}
+	// use the marshaled value, avoid marshaling again
+	if value, verr := err.Msg.Value.Encode(); verr == nil {
+		msg.Value = storage.BytesMarshaler(value)
+	}
+
+	fn(msg, err.Err)
+}
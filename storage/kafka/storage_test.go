@@ -1,10 +1,13 @@
 package kafka
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/Shopify/sarama/mocks"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/techxmind/logserver/storage"
@@ -19,28 +22,105 @@ func TestAll(t *testing.T) {
 
 	s := newInstance(mp)
 
-	mp.ExpectInputAndSucceed()
+	// successes are consumed by storage, check the value that producer received
+	mp.ExpectInputWithCheckerFunctionAndSucceed(func(value []byte) error {
+		if string(value) != "value" {
+			return errors.Errorf("unexpected value %s", value)
+		}
+		return nil
+	})
 	mp.ExpectInputAndFail(sarama.ErrOutOfBrokers)
 
-	s.Write(&storage.Message{
+	err := s.WriteSync(context.Background(), &storage.Message{
 		Topic: "test",
 		Key:   "key",
 		Value: storage.StringMarshaler("value"),
 	})
+	ast.NoError(err)
 
-	s.Write(&storage.Message{
+	err = s.WriteSync(context.Background(), &storage.Message{
 		Topic: "test",
 		Key:   "key",
 		Value: storage.StringMarshaler("value"),
 	})
+	ast.Equal(sarama.ErrOutOfBrokers, err)
+
+	s.Close()
+}
+
+func TestWriteSyncTimeout(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+	mp := mocks.NewAsyncProducer(t, cfg)
+
+	s := newInstance(mp)
+	mp.ExpectInputAndSucceed()
+
+	// successes of the mock producer are not consumed until daemon is running
+	s.quit <- true
+	<-s.quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.WriteSync(ctx, &storage.Message{
+		Topic: "test",
+		Key:   "key",
+		Value: storage.StringMarshaler("value"),
+	})
+	ast.Equal(context.DeadlineExceeded, err)
+
+	go s.daemon()
+	s.Close()
+}
+
+func TestNotifyError(t *testing.T) {
+	ast := assert.New(t)
+
+	cfg := mocks.NewTestConfig()
+	mp := mocks.NewAsyncProducer(t, cfg)
+
+	s := newInstance(mp)
+	failed := make(chan *storage.Message, 1)
+	s.NotifyError(func(msg *storage.Message, err error) {
+		failed <- msg
+	})
 
-	msg1 := <-mp.Successes()
+	mp.ExpectInputAndFail(sarama.ErrOutOfBrokers)
 
-	ast.Equal("test", msg1.Topic)
-	key, _ := msg1.Key.Encode()
-	ast.Equal("key", string(key))
-	value, _ := msg1.Value.Encode()
+	s.Write(&storage.Message{
+		Topic: "test",
+		Key:   "key",
+		Value: storage.StringMarshaler("value"),
+	})
+
+	msg := <-failed
+	ast.Equal("test", msg.Topic)
+	ast.Equal("key", msg.Key)
+	value, _ := msg.Value.Marshal()
 	ast.Equal("value", string(value))
 
 	s.Close()
 }
+
+func TestProducerMessage(t *testing.T) {
+	ast := assert.New(t)
+
+	s := &kafkaStorage{}
+	pmsg, err := s.producerMessage(&storage.Message{
+		Topic: "test",
+		Value: storage.StringMarshaler("value"),
+		Headers: map[string]string{
+			storage.HeaderEvent:   "pv",
+			storage.HeaderAppType: "myapp",
+		},
+	}, nil)
+	ast.NoError(err)
+	// no key
+	ast.Nil(pmsg.Key)
+	ast.Equal([]sarama.RecordHeader{
+		{Key: []byte("app-type"), Value: []byte("myapp")},
+		{Key: []byte("event"), Value: []byte("pv")},
+	}, pmsg.Headers)
+}
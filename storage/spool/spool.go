@@ -0,0 +1,685 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/atomic"
+
+	"github.com/techxmind/logserver/config"
+	"github.com/techxmind/logserver/logger"
+	"github.com/techxmind/logserver/metrics"
+	"github.com/techxmind/logserver/storage"
+)
+
+const (
+	segmentSuffix = ".spool"
+	// record header: payload length(4 bytes) + crc32 of payload(4 bytes)
+	headerSize = 8
+	// the highest bit of payload length is set if payload contains message headers
+	headersFlag = 1 << 31
+
+	defaultSegmentSize   = 64 << 20
+	defaultMaxSize       = 1 << 30
+	defaultMaxAge        = 7 * 24 * time.Hour
+	defaultRetryInterval = 10 * time.Second
+)
+
+type options struct {
+	dir           string
+	segmentSize   int64
+	maxSize       int64
+	maxAge        time.Duration
+	retryInterval time.Duration
+	checkInterval time.Duration
+}
+
+type segment struct {
+	seq     int64 // creation time in nanoseconds
+	path    string
+	size    int64 // bytes written
+	records int64
+}
+
+func (seg *segment) created() time.Time {
+	return time.Unix(0, seg.seq)
+}
+
+// backlog is the segment files of a spool directory.
+// It's shared by the spools of the same directory, e.g. old and new storage while reloading configuration.
+type backlog struct {
+	dir  string
+	refs int
+
+	mu       sync.Mutex
+	segments []*segment // head(oldest) first
+	writer   *os.File   // writer of tail segment, nil if tail segment is not writable
+	reader   *os.File   // reader of head segment
+	readPos  int64
+	size     int64 // bytes of backlog
+	records  int64 // messages of backlog
+
+	// only one spool replays the backlog at a time
+	replayMu sync.Mutex
+}
+
+var (
+	_backlogsMu sync.Mutex
+	// dir => backlog opened by spools
+	_backlogs = make(map[string]*backlog)
+)
+
+// spool is a storage.Storager wrapper.
+// Messages failed to be written to the inner storage are appended to segment files,
+// and replayed in order once the inner storage recovers.
+// Replay is at-least-once, messages of a partially replayed segment may be replayed again after restart.
+type spool struct {
+	inner storage.Storager
+	opts  options
+	*backlog
+
+	lastFailure atomic.Int64
+	quit        chan struct{}
+	done        chan struct{}
+}
+
+// New returns a storage.Storager that spools messages to local disk when inner storage fails.
+// Asynchronous delivery failures are collected if inner storage implements storage.ErrorNotifier.
+func New(inner storage.Storager, cfg *config.SpoolConfig) (storage.Storager, error) {
+	if cfg == nil || cfg.Dir == "" {
+		return nil, errors.New("Spool directory configuration is missing")
+	}
+
+	opts := options{
+		dir:           cfg.Dir,
+		segmentSize:   cfg.SegmentSize,
+		maxSize:       cfg.MaxSize,
+		maxAge:        time.Duration(cfg.MaxAge) * time.Second,
+		retryInterval: time.Duration(cfg.RetryInterval) * time.Second,
+		checkInterval: time.Second,
+	}
+
+	return newSpool(inner, opts)
+}
+
+func newSpool(inner storage.Storager, opts options) (*spool, error) {
+	if opts.segmentSize <= 0 {
+		opts.segmentSize = defaultSegmentSize
+	}
+	if opts.maxSize <= 0 {
+		opts.maxSize = defaultMaxSize
+	}
+	if opts.maxAge <= 0 {
+		opts.maxAge = defaultMaxAge
+	}
+	if opts.retryInterval <= 0 {
+		opts.retryInterval = defaultRetryInterval
+	}
+
+	if err := os.MkdirAll(opts.dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "New spool")
+	}
+
+	s := &spool{
+		inner: inner,
+		opts:  opts,
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	if err := s.open(); err != nil {
+		return nil, errors.Wrap(err, "New spool")
+	}
+
+	if notifier, ok := inner.(storage.ErrorNotifier); ok {
+		notifier.NotifyError(s.onError)
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// open shares backlog of the directory if it's opened by another spool,
+// otherwise it loads the segment files left by previous process
+func (s *spool) open() error {
+	dir, err := filepath.Abs(s.opts.dir)
+	if err != nil {
+		return err
+	}
+
+	_backlogsMu.Lock()
+	defer _backlogsMu.Unlock()
+
+	if b, ok := _backlogs[dir]; ok {
+		b.refs++
+		s.backlog = b
+		return nil
+	}
+
+	s.backlog = &backlog{dir: dir, refs: 1}
+	if err := s.load(); err != nil {
+		return err
+	}
+	_backlogs[dir] = s.backlog
+
+	return nil
+}
+
+// release closes the files of backlog if no other spool shares it
+func (s *spool) release() {
+	_backlogsMu.Lock()
+	defer _backlogsMu.Unlock()
+
+	if s.refs--; s.refs > 0 {
+		return
+	}
+	delete(_backlogs, s.dir)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+	}
+}
+
+// load scans segment files left by previous process
+func (s *spool) load() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimSuffix(file.Name(), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seg := &segment{
+			seq:  seq,
+			path: filepath.Join(s.dir, file.Name()),
+		}
+		if err := scanSegment(seg); err != nil {
+			logger.Error("Spool scan segment", "file", seg.path, "err", err)
+		}
+		if seg.records == 0 {
+			os.Remove(seg.path)
+			continue
+		}
+		s.segments = append(s.segments, seg)
+		s.size += seg.size
+		s.records += seg.records
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+
+	s.updateMetrics()
+
+	if s.records > 0 {
+		logger.Info("Spool backlog found", "dir", s.dir, "records", s.records, "bytes", s.size)
+	}
+
+	return nil
+}
+
+// scanSegment counts valid records, truncated or corrupted tail is ignored
+func scanSegment(seg *segment) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for {
+		n, err := readRecord(f, seg.size, nil)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		seg.size += n
+		seg.records++
+	}
+}
+
+func (s *spool) Write(msg *storage.Message) error {
+	if !s.hasBacklog() && !s.failedRecently() {
+		err := s.inner.Write(msg)
+		if err == nil {
+			return nil
+		}
+		logger.Error("Spool inner storage write", "err", err)
+		s.markFailure()
+	}
+
+	return s.append(msg)
+}
+
+// WriteSync implements storage.SyncWriter.
+// Message that is appended to spool is regarded as delivered since it's durable on local disk.
+// It's not spooled if ctx is done, because the in-flight message may still be delivered,
+// the error is returned and the caller retries it.
+func (s *spool) WriteSync(ctx context.Context, msg *storage.Message) error {
+	if !s.hasBacklog() && !s.failedRecently() {
+		err := storage.WriteSync(ctx, s.inner, msg)
+		if err == nil {
+			return nil
+		}
+		logger.Error("Spool inner storage write", "err", err)
+		s.markFailure()
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return s.append(msg)
+}
+
+func (s *spool) Close() error {
+	close(s.quit)
+	<-s.done
+
+	// inner storage may report failures while flushing
+	err := s.inner.Close()
+
+	s.release()
+
+	return err
+}
+
+func (s *spool) onError(msg *storage.Message, err error) {
+	s.markFailure()
+	if aerr := s.append(msg); aerr != nil {
+		metrics.CounterAdd("spool_lost", 1)
+		logger.Error("Spool append", "err", aerr, "cause", err)
+	}
+}
+
+func (s *spool) markFailure() {
+	s.lastFailure.Store(time.Now().UnixNano())
+}
+
+func (s *spool) failedRecently() bool {
+	return time.Since(time.Unix(0, s.lastFailure.Load())) < s.opts.retryInterval
+}
+
+func (s *spool) hasBacklog() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.records > 0
+}
+
+func (s *spool) append(msg *storage.Message) error {
+	if msg.Value == nil {
+		return errors.New("message value is nil")
+	}
+	value, err := msg.Value.Marshal()
+	if err != nil {
+		return err
+	}
+	record := encodeRecord(msg.Topic, msg.Key, msg.Headers, value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tail *segment
+	if len(s.segments) > 0 && s.writer != nil {
+		tail = s.segments[len(s.segments)-1]
+	}
+	if tail == nil || tail.size+int64(len(record)) > s.opts.segmentSize {
+		if tail, err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.writer.Write(record); err != nil {
+		// discard the partially written record
+		s.writer.Truncate(tail.size)
+		return err
+	}
+
+	tail.size += int64(len(record))
+	tail.records++
+	s.size += int64(len(record))
+	s.records++
+
+	metrics.CounterAdd("spool_append", 1)
+
+	s.enforceMaxSize()
+	s.updateMetrics()
+
+	return nil
+}
+
+// rotate creates new tail segment, must be called with lock held
+func (s *spool) rotate() (*segment, error) {
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+
+	seq := time.Now().UnixNano()
+	if n := len(s.segments); n > 0 && seq <= s.segments[n-1].seq {
+		seq = s.segments[n-1].seq + 1
+	}
+	seg := &segment{
+		seq:  seq,
+		path: filepath.Join(s.dir, fmt.Sprintf("%019d%s", seq, segmentSuffix)),
+	}
+
+	f, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s.writer = f
+	s.segments = append(s.segments, seg)
+
+	return seg, nil
+}
+
+// enforceMaxSize drops oldest segments, must be called with lock held
+func (s *spool) enforceMaxSize() {
+	for s.size > s.opts.maxSize && len(s.segments) > 1 {
+		s.dropHead("max_size")
+	}
+}
+
+// expire drops segments older than max age
+func (s *spool) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.segments) > 0 && time.Since(s.segments[0].created()) > s.opts.maxAge {
+		s.dropHead("max_age")
+	}
+	s.updateMetrics()
+}
+
+// dropHead removes head segment with its unreplayed records, must be called with lock held
+func (s *spool) dropHead(reason string) {
+	head := s.segments[0]
+	records, size := head.records, head.size-s.readPos
+
+	logger.Warn("Spool drop segment", "file", head.path, "reason", reason, "records", records)
+	metrics.CounterAdd("spool_dropped", float64(records))
+
+	s.removeHead()
+	s.size -= size
+	s.records -= records
+}
+
+// removeHead deletes head segment file, must be called with lock held
+func (s *spool) removeHead() {
+	head := s.segments[0]
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+	}
+	s.readPos = 0
+	if len(s.segments) == 1 && s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+	s.segments = s.segments[1:]
+	if err := os.Remove(head.path); err != nil {
+		logger.Error("Spool remove segment", "file", head.path, "err", err)
+	}
+}
+
+func (s *spool) updateMetrics() {
+	metrics.GaugeSet("spool_backlog_bytes", float64(s.size))
+	metrics.GaugeSet("spool_backlog_records", float64(s.records))
+}
+
+func (s *spool) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.expire()
+			if !s.failedRecently() {
+				s.replay()
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// replay writes backlog to inner storage until it's empty or inner storage fails
+func (s *spool) replay() {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+
+		msg, seg, n, err := s.peek()
+		if err != nil {
+			logger.Error("Spool read", "err", err)
+			return
+		}
+		if msg == nil {
+			return
+		}
+
+		if err := s.inner.Write(msg); err != nil {
+			logger.Error("Spool replay", "err", err)
+			s.markFailure()
+			return
+		}
+
+		s.commit(seg, n)
+		metrics.CounterAdd("spool_replay", 1)
+
+		// asynchronous failure reported, wait for next retry
+		if s.failedRecently() {
+			return
+		}
+	}
+}
+
+// peek reads next record of backlog without consuming it, returns nil message if backlog is empty
+func (s *spool) peek() (*storage.Message, *segment, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.segments) > 0 {
+		head := s.segments[0]
+		isTail := len(s.segments) == 1 && s.writer != nil
+
+		if s.readPos >= head.size {
+			// all records have been replayed
+			s.removeHead()
+			if isTail {
+				return nil, nil, 0, nil
+			}
+			continue
+		}
+
+		if s.reader == nil {
+			f, err := os.Open(head.path)
+			if err != nil {
+				s.dropHead("unreadable")
+				return nil, nil, 0, err
+			}
+			s.reader = f
+		}
+
+		var msg storage.Message
+		n, err := readRecord(s.reader, s.readPos, &msg)
+		if err != nil || n == 0 {
+			// corrupted segment, drop the rest of it
+			s.dropHead("corrupted")
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			continue
+		}
+
+		return &msg, head, n, nil
+	}
+
+	return nil, nil, 0, nil
+}
+
+// commit consumes record of n bytes returned by peek
+func (s *spool) commit(seg *segment, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// segment has been dropped while replaying
+	if len(s.segments) == 0 || s.segments[0] != seg {
+		return
+	}
+	head := s.segments[0]
+	s.readPos += n
+	head.records--
+	s.size -= n
+	s.records--
+	s.updateMetrics()
+}
+
+// encodeRecord encodes message as: topic, key, [headers count, (name, value)...], value.
+// headers are encoded only if there's any, so that records written by previous versions are still readable.
+func encodeRecord(topic, key string, headers map[string]string, value []byte) []byte {
+	payload := make([]byte, 0, 2*binary.MaxVarintLen64+len(topic)+len(key)+len(value))
+	payload = appendBytes(payload, []byte(topic))
+	payload = appendBytes(payload, []byte(key))
+	if len(headers) > 0 {
+		payload = appendUvarint(payload, uint64(len(headers)))
+		for name, v := range headers {
+			payload = appendBytes(payload, []byte(name))
+			payload = appendBytes(payload, []byte(v))
+		}
+	}
+	payload = append(payload, value...)
+
+	length := uint32(len(payload))
+	if len(headers) > 0 {
+		length |= headersFlag
+	}
+
+	record := make([]byte, headerSize, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], length)
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+
+	return append(record, payload...)
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(dst, buf[:n]...)
+}
+
+func appendBytes(dst, src []byte) []byte {
+	dst = appendUvarint(dst, uint64(len(src)))
+	return append(dst, src...)
+}
+
+func readBytes(src []byte) ([]byte, []byte, error) {
+	l, n := binary.Uvarint(src)
+	if n <= 0 || uint64(len(src)-n) < l {
+		return nil, nil, errors.New("invalid record")
+	}
+	return src[n : n+int(l)], src[n+int(l):], nil
+}
+
+// readRecord reads record at offset, returns bytes of the record, 0 means end of file.
+// msg can be nil if only the size is needed.
+func readRecord(f *os.File, offset int64, msg *storage.Message) (int64, error) {
+	var header [headerSize]byte
+	if n, _ := f.ReadAt(header[:], offset); n < headerSize {
+		return 0, nil
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	payload := make([]byte, length&^headersFlag)
+	if n, _ := f.ReadAt(payload, offset+headerSize); n < len(payload) {
+		return 0, nil
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, errors.Errorf("checksum mismatch at %d", offset)
+	}
+
+	if msg != nil {
+		topic, rest, err := readBytes(payload)
+		if err != nil {
+			return 0, err
+		}
+		key, rest, err := readBytes(rest)
+		if err != nil {
+			return 0, err
+		}
+		msg.Topic = string(topic)
+		msg.Key = string(key)
+		msg.Headers = nil
+		if length&headersFlag != 0 {
+			if msg.Headers, rest, err = readHeaders(rest); err != nil {
+				return 0, err
+			}
+		}
+		msg.Value = storage.BytesMarshaler(rest)
+	}
+
+	return int64(headerSize + len(payload)), nil
+}
+
+func readHeaders(src []byte) (map[string]string, []byte, error) {
+	count, n := binary.Uvarint(src)
+	if n <= 0 || count > uint64(len(src)) {
+		return nil, nil, errors.New("invalid record")
+	}
+	src = src[n:]
+
+	var (
+		headers     = make(map[string]string, count)
+		name, value []byte
+		err         error
+	)
+	for i := uint64(0); i < count; i++ {
+		if name, src, err = readBytes(src); err != nil {
+			return nil, nil, err
+		}
+		if value, src, err = readBytes(src); err != nil {
+			return nil, nil, err
+		}
+		headers[string(name)] = string(value)
+	}
+
+	return headers, src, nil
+}
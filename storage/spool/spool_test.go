@@ -0,0 +1,278 @@
+package spool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/techxmind/logserver/storage"
+)
+
+type testStorage struct {
+	mu      sync.Mutex
+	fail    bool
+	values  []string
+	onError func(*storage.Message, error)
+}
+
+func (s *testStorage) Write(msg *storage.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	v, _ := msg.Value.Marshal()
+	s.values = append(s.values, msg.Topic+":"+msg.Key+":"+string(v))
+	return nil
+}
+
+func (s *testStorage) Close() error {
+	return nil
+}
+
+func (s *testStorage) NotifyError(fn func(*storage.Message, error)) {
+	s.onError = fn
+}
+
+func (s *testStorage) setFail(fail bool) {
+	s.mu.Lock()
+	s.fail = fail
+	s.mu.Unlock()
+}
+
+func (s *testStorage) written() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.values...)
+}
+
+// slowStorage never acks synchronous writes in time
+type slowStorage struct {
+	testStorage
+}
+
+func (s *slowStorage) WriteSync(ctx context.Context, msg *storage.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func testOptions(t *testing.T) options {
+	dir, err := ioutil.TempDir("", "logserver-spool")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return options{
+		dir:           dir,
+		segmentSize:   100,
+		retryInterval: 20 * time.Millisecond,
+		checkInterval: 5 * time.Millisecond,
+	}
+}
+
+func testMessage(i int) *storage.Message {
+	return storage.NewMessage("topic", fmt.Sprintf("key%d", i), storage.StringMarshaler(fmt.Sprintf("value%d", i)))
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSpoolReplay(t *testing.T) {
+	ast := assert.New(t)
+	inner := &testStorage{}
+	s, err := newSpool(inner, testOptions(t))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMessage(0)))
+
+	inner.setFail(true)
+	for i := 1; i < 10; i++ {
+		require.NoError(t, s.Write(testMessage(i)))
+	}
+	// asynchronous failure
+	inner.onError(testMessage(10), errors.New("timeout"))
+
+	ast.True(s.hasBacklog())
+	ast.Equal([]string{"topic:key0:value0"}, inner.written())
+
+	s.mu.Lock()
+	ast.True(len(s.segments) > 1, "segments are rotated by size")
+	s.mu.Unlock()
+
+	inner.setFail(false)
+	waitFor(t, func() bool { return !s.hasBacklog() })
+
+	expect := make([]string, 0)
+	for i := 0; i <= 10; i++ {
+		expect = append(expect, fmt.Sprintf("topic:key%d:value%d", i, i))
+	}
+	ast.Equal(expect, inner.written())
+
+	// segment files are removed after replay
+	files, _ := ioutil.ReadDir(s.opts.dir)
+	ast.Equal(0, len(files))
+
+	// write directly when backlog is empty
+	time.Sleep(s.opts.retryInterval)
+	require.NoError(t, s.Write(testMessage(11)))
+	ast.Equal(12, len(inner.written()))
+}
+
+func TestSpoolReload(t *testing.T) {
+	ast := assert.New(t)
+	opts := testOptions(t)
+	inner := &testStorage{fail: true}
+	s, err := newSpool(inner, opts)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Write(testMessage(i)))
+	}
+	require.NoError(t, s.Close())
+
+	inner2 := &testStorage{}
+	s2, err := newSpool(inner2, opts)
+	require.NoError(t, err)
+	defer s2.Close()
+
+	ast.EqualValues(5, s2.records)
+	waitFor(t, func() bool { return !s2.hasBacklog() })
+	ast.Equal("topic:key0:value0", inner2.written()[0])
+	ast.Equal("topic:key4:value4", inner2.written()[4])
+}
+
+func TestSpoolShared(t *testing.T) {
+	ast := assert.New(t)
+	opts := testOptions(t)
+	inner := &testStorage{fail: true}
+	s, err := newSpool(inner, opts)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Write(testMessage(i)))
+	}
+
+	// new spool of the same directory shares the backlog before the old one is closed
+	inner2 := &testStorage{}
+	s2, err := newSpool(inner2, opts)
+	require.NoError(t, err)
+	defer s2.Close()
+	ast.True(s.backlog == s2.backlog)
+
+	// failures of old storage while closing are replayed by the new one
+	inner.onError(testMessage(5), errors.New("timeout"))
+	require.NoError(t, s.Close())
+
+	waitFor(t, func() bool { return !s2.hasBacklog() })
+	written := inner2.written()
+	require.Len(t, written, 6)
+	ast.Equal("topic:key0:value0", written[0])
+	ast.Equal("topic:key5:value5", written[5])
+	ast.Empty(inner.written())
+}
+
+func TestSpoolWriteSyncTimeout(t *testing.T) {
+	ast := assert.New(t)
+	s, err := newSpool(&slowStorage{}, testOptions(t))
+	require.NoError(t, err)
+	defer s.Close()
+
+	// timed out message may still be delivered, it's not spooled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ast.Equal(context.DeadlineExceeded, s.WriteSync(ctx, testMessage(0)))
+	ast.False(s.hasBacklog())
+
+	// spooled while inner storage is failing
+	ast.NoError(s.WriteSync(context.Background(), testMessage(1)))
+	ast.True(s.hasBacklog())
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	ast := assert.New(t)
+	opts := testOptions(t)
+	opts.segmentSize = 50
+	opts.maxSize = 100
+	opts.retryInterval = time.Hour
+	inner := &testStorage{fail: true}
+	s, err := newSpool(inner, opts)
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, s.Write(testMessage(i)))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ast.True(s.size <= opts.maxSize)
+	ast.True(s.records < 20)
+}
+
+func TestSpoolCorrupted(t *testing.T) {
+	ast := assert.New(t)
+	opts := testOptions(t)
+	inner := &testStorage{fail: true}
+	s, err := newSpool(inner, opts)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(testMessage(0)))
+	path := s.segments[0].path
+	require.NoError(t, s.Close())
+
+	// truncated record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	f.Write(encodeRecord("topic", "key", nil, []byte("value"))[:10])
+	f.Close()
+
+	s2, err := newSpool(&testStorage{fail: true}, opts)
+	require.NoError(t, err)
+	defer s2.Close()
+	ast.EqualValues(1, s2.records)
+}
+
+func TestRecordHeaders(t *testing.T) {
+	ast := assert.New(t)
+
+	f, err := ioutil.TempFile("", "logserver-spool")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	headers := map[string]string{"app-type": "myapp", "event": "pv"}
+	legacy := encodeRecord("topic", "key1", nil, []byte("value1"))
+	f.Write(legacy)
+	f.Write(encodeRecord("topic", "key2", headers, []byte("value2")))
+
+	var msg storage.Message
+	n, err := readRecord(f, 0, &msg)
+	ast.NoError(err)
+	ast.EqualValues(len(legacy), n)
+	ast.Equal("key1", msg.Key)
+	ast.Nil(msg.Headers)
+	v, _ := msg.Value.Marshal()
+	ast.Equal("value1", string(v))
+
+	_, err = readRecord(f, n, &msg)
+	ast.NoError(err)
+	ast.Equal("key2", msg.Key)
+	ast.Equal(headers, msg.Headers)
+	v, _ = msg.Value.Marshal()
+	ast.Equal("value2", string(v))
+}
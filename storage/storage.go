@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 )
@@ -10,6 +11,27 @@ type Storager interface {
 	Close() error
 }
 
+// SyncWriter is implemented by storages that can wait for the delivery result
+type SyncWriter interface {
+	// WriteSync writes message and waits until it's delivered or ctx is done
+	WriteSync(context.Context, *Message) error
+}
+
+// WriteSync writes message synchronously if s implements SyncWriter,
+// otherwise it falls back to Write
+func WriteSync(ctx context.Context, s Storager, msg *Message) error {
+	if w, ok := s.(SyncWriter); ok {
+		return w.WriteSync(ctx, msg)
+	}
+	return s.Write(msg)
+}
+
+// ErrorNotifier is implemented by asynchronous storages,
+// fn is called with the message that failed to be delivered
+type ErrorNotifier interface {
+	NotifyError(fn func(*Message, error))
+}
+
 type Marshaler interface {
 	Marshal() ([]byte, error)
 }
@@ -20,6 +42,12 @@ func (s StringMarshaler) Marshal() ([]byte, error) {
 	return []byte(s), nil
 }
 
+type BytesMarshaler []byte
+
+func (b BytesMarshaler) Marshal() ([]byte, error) {
+	return b, nil
+}
+
 type jsonMarshaler struct {
 	v interface{}
 }
@@ -34,10 +62,31 @@ func (m *jsonMarshaler) Marshal() ([]byte, error) {
 	return json.Marshal(m.v)
 }
 
+// Message headers, kafka storage writes them as record headers
+const (
+	// application/json | application/x-protobuf
+	HeaderContentType = "content-type"
+	// version of event log schema, see eventlog.SchemaVersion
+	HeaderSchemaVersion = "schema-version"
+	HeaderAppType       = "app-type"
+	HeaderEvent         = "event"
+	// hostname of the server that receives the event
+	HeaderIngestHost = "ingest-host"
+	// unix timestamp in milliseconds
+	HeaderLoggedTime = "logged-time"
+)
+
+// Content types
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
 type Message struct {
-	Topic string
-	Key   string
-	Value Marshaler
+	Topic   string
+	Key     string
+	Value   Marshaler
+	Headers map[string]string
 }
 
 func NewMessage(topic, key string, value Marshaler) *Message {
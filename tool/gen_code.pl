@@ -101,7 +101,7 @@ sub tpl_event_log_fill {
 
         if ($type eq 'string') {
             push @content, <<EOT;
-		if log.$field == "" && common != nil && common.$field != "" {
+		if log.$field == "" && common.$field != "" {
 			log.$field = common.$field
 		}
 EOT
@@ -109,7 +109,7 @@ EOT
 
         if ($type =~ /^int(\d+)?$/ || $proto_type eq 'varint') {
             push @content, <<EOT;
-		if log.$field == 0 && common != nil && common.$field != 0 {
+		if log.$field == 0 && common.$field != 0 {
 			log.$field = common.$field
 		}
 EOT
@@ -152,7 +152,7 @@ sub tpl_event_log_getter {
             push @content, qq/\t\t\tif e.$field == nil {\n\t\t\t\treturn "", nil\n\t\t\t}/;
             push @content, qq/\t\t\treturn e.$field\[$self.key\], nil/;
             push @content, qq/\t\t}/;
-            push @content, qq/\t\tif e.$field == nil {\n\t\t\t\treturn "{}", nil\n\t\t\t}/;
+            push @content, qq/\t\tif e.$field == nil {\n\t\t\treturn "{}", nil\n\t\t}/;
             push @content, qq/\t\tif bs, err := json.Marshal(e.$field); err == nil {/;
             push @content, qq/\t\t\treturn string(bs), nil/;
             push @content, qq/\t\t}/;